@@ -10,23 +10,103 @@ import (
 
 // Book represents a GitBook project configuration
 type Book struct {
-	Root    string
-	Config  *Config
-	Summary *Summary
+	Root       string // directory holding the content: ConfigRoot, or its root setting
+	ConfigRoot string // directory holding the configuration file
+	Config     *Config
+	Summary    *Summary
+	Readme     string        // README of the book, under Root
+	Glossary   string        // glossary of the book, under Root; the file may not exist
+	Ignore     *Ignore       // paths under Root left out of the book, see LoadIgnore
+	Warnings   []ConfigIssue // problems found in the configuration file, see ValidateConfig, and the environment
 }
 
 // Config represents book.json configuration
 type Config struct {
+	Schema        string                 `json:"$schema,omitempty"` // JSON Schema of the file, for editors
 	Title         string                 `json:"title,omitempty"`
 	Author        string                 `json:"author,omitempty"`
+	Subtitle      string                 `json:"subtitle,omitempty"`
 	Description   string                 `json:"description,omitempty"`
 	Language      string                 `json:"language,omitempty"`
 	Gitbook       string                 `json:"gitbook,omitempty"`
 	Root          string                 `json:"root,omitempty"`
 	Structure     *Structure             `json:"structure,omitempty"`
+	Summary       *SummaryConfig         `json:"summary,omitempty"`
+	Gitignore     bool                   `json:"gitignore,omitempty"` // also leave out the paths matched by .gitignore files
 	Plugins       []string               `json:"plugins,omitempty"`
 	PluginsConfig map[string]interface{} `json:"pluginsConfig,omitempty"`
 	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Numbering     bool                   `json:"numbering,omitempty"` // prefix chapters with hierarchical numbers (1.2.3)
+	SiteURL       string                 `json:"siteUrl,omitempty"`   // public URL of the published site, enables sitemap.xml and canonical links
+	EditLink      *EditLink              `json:"editLink,omitempty"`
+	Ebook         *Ebook                 `json:"ebook,omitempty"`
+	PDF           *PDF                   `json:"pdf,omitempty"`
+	LaTeX         *LaTeX                 `json:"latex,omitempty"`
+}
+
+// Ebook configures ebook generation
+type Ebook struct {
+	// Converter selects the backend (native, calibre, pandoc or command);
+	// empty picks the first available one supporting the requested format
+	Converter string `json:"converter,omitempty"`
+	// Command is the command line template of the "command" converter, e.g.
+	// "mytool {input} -o {output}". Placeholders: {input}, {output}, {format},
+	// {title}, {author}, {language}
+	Command string `json:"command,omitempty"`
+	// Cover is the cover image, relative to the book root (default: cover.jpg if present)
+	Cover string `json:"cover,omitempty"`
+	// CoverStyle configures the cover generated for books without a cover image
+	CoverStyle *CoverStyle `json:"coverStyle,omitempty"`
+}
+
+// CoverStyle configures generated covers. Colors are CSS hex colors (#rgb or #rrggbb).
+type CoverStyle struct {
+	Background      string `json:"background,omitempty"`      // background color
+	BackgroundImage string `json:"backgroundImage,omitempty"` // image scaled to fill the cover, relative to the book root
+	Color           string `json:"color,omitempty"`           // text color
+	Font            string `json:"font,omitempty"`            // TrueType/OpenType font tried before the bundled one, e.g. for CJK titles
+}
+
+// PDF holds the page layout options of PDF output, as the legacy "pdf" section of book.json
+type PDF struct {
+	PaperSize      string  `json:"paperSize,omitempty"` // a4, a5, letter, legal, ...
+	Margin         *Margin `json:"margin,omitempty"`
+	FontFamily     string  `json:"fontFamily,omitempty"`
+	FontSize       int     `json:"fontSize,omitempty"`    // in points
+	PageNumbers    *bool   `json:"pageNumbers,omitempty"` // add page numbers to the footer
+	HeaderTemplate string  `json:"headerTemplate,omitempty"`
+	FooterTemplate string  `json:"footerTemplate,omitempty"`
+	// ChapterMark is how chapters are separated: pagebreak, rule, both or none
+	ChapterMark string `json:"chapterMark,omitempty"`
+	// PageBreaksBefore is an XPath expression; a page break is inserted before matching elements
+	PageBreaksBefore string `json:"pageBreaksBefore,omitempty"`
+}
+
+// LaTeX configures the LaTeX project export
+type LaTeX struct {
+	// Preamble is a text/template file, relative to the book root, replacing the
+	// built-in preamble. It receives .Title, .Subtitle, .Author, .Date, .Language,
+	// .DocumentClass, .ClassOptions and .CJK.
+	Preamble      string `json:"preamble,omitempty"`
+	DocumentClass string `json:"documentClass,omitempty"` // default: book
+	ClassOptions  string `json:"classOptions,omitempty"`  // default: 11pt plus the pdf paper size
+}
+
+// Margin holds page margins in points
+type Margin struct {
+	Top    float64 `json:"top,omitempty"`
+	Bottom float64 `json:"bottom,omitempty"`
+	Left   float64 `json:"left,omitempty"`
+	Right  float64 `json:"right,omitempty"`
+}
+
+// EditLink configures the "Edit this page" and "View source" links.
+// URL templates may reference {path} (source path relative to the repository root)
+// and {branch}, e.g. "https://github.com/owner/repo/edit/{branch}/{path}".
+type EditLink struct {
+	URL       string `json:"url,omitempty"`
+	SourceURL string `json:"sourceUrl,omitempty"` // defaults to URL with /edit/ replaced by /blob/
+	Branch    string `json:"branch,omitempty"`    // defaults to the checked out branch, or "main"
 }
 
 // Structure defines custom file structure
@@ -37,9 +117,18 @@ type Structure struct {
 	Languages string `json:"languages,omitempty"`
 }
 
+// SummaryConfig configures how the table of contents is obtained
+type SummaryConfig struct {
+	// Auto derives the summary from the directory tree (see GenerateSummary)
+	// instead of reading SUMMARY.md
+	Auto bool `json:"auto,omitempty"`
+}
+
 // Summary represents the SUMMARY.md structure
 type Summary struct {
+	Title    string // heading of the file, "Summary" when empty
 	Chapters []Chapter
+	Trailer  []string // comment lines after the last chapter
 }
 
 // Chapter represents a chapter in the summary
@@ -47,31 +136,77 @@ type Chapter struct {
 	Title    string
 	Path     string
 	Articles []Chapter
+	Part     string   // title of the part ("## Part") opened by this chapter
+	Comments []string // comment lines just before the chapter
 }
 
 // LoadBook loads a book from a directory
-func LoadBook(root string) (*Book, error) {
+func LoadBook(root string, opts ...LoadOption) (*Book, error) {
+	var options loadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return nil, err
 	}
 
 	book := &Book{
-		Root: absRoot,
+		Root:       absRoot,
+		ConfigRoot: absRoot,
 	}
 
-	// Load book.json
-	configPath := filepath.Join(absRoot, "book.json")
-	config, err := LoadConfig(configPath)
-	if err != nil && !os.IsNotExist(err) {
+	// Load book.json, book.yaml or book.toml
+	configPath, err := FindConfig(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	if configPath != "" {
+		config, err := LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		book.Config = config
+		if data, err := os.ReadFile(configPath); err == nil {
+			book.Warnings, _ = ValidateConfig(data, configPath)
+		}
+	}
+	config, warnings, err := applyOverrides(configPath, book.Config, options.overrides)
+	if err != nil {
 		return nil, err
 	}
 	book.Config = config
+	book.Warnings = append(book.Warnings, warnings...)
+
+	// Content may live in a subdirectory
+	if book.Root, err = book.Config.ContentRoot(absRoot); err != nil {
+		return nil, err
+	}
+	structure := book.Config.WithDefaults().Structure
+	if book.Readme, err = ResolvePath(book.Root, structure.Readme); err != nil {
+		return nil, fmt.Errorf("invalid structure.readme: %w", err)
+	}
+	if book.Glossary, err = ResolvePath(book.Root, structure.Glossary); err != nil {
+		return nil, fmt.Errorf("invalid structure.glossary: %w", err)
+	}
+
+	if err := book.ReadIgnore(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore files: %w", err)
+	}
+
+	if book.Config.AutoSummary() {
+		summary, err := GenerateSummary(book.Root, book.Ignore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate summary: %w", err)
+		}
+		book.Summary = summary
+		return book, nil
+	}
 
 	// Load SUMMARY.md
-	summaryPath := filepath.Join(absRoot, "SUMMARY.md")
-	if book.Config != nil && book.Config.Structure != nil && book.Config.Structure.Summary != "" {
-		summaryPath = filepath.Join(absRoot, book.Config.Structure.Summary)
+	summaryPath, err := ResolvePath(book.Root, structure.Summary)
+	if err != nil {
+		return nil, fmt.Errorf("invalid structure.summary: %w", err)
 	}
 	summary, err := LoadSummary(summaryPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -82,24 +217,178 @@ func LoadBook(root string) (*Book, error) {
 	return book, nil
 }
 
-// LoadConfig loads book.json
+// ReadIgnore (re)reads the .bookignore file of the book, and its .gitignore
+// files if the configuration enables them
+func (b *Book) ReadIgnore() error {
+	ignore, err := LoadIgnore(b.ConfigRoot, b.Root, b.Config != nil && b.Config.Gitignore)
+	if err != nil {
+		return err
+	}
+	b.Ignore = ignore
+	return nil
+}
+
+// ContentRoot returns the directory holding the content of a book whose
+// configuration file is in dir: the root setting, resolved against dir, or
+// dir itself
+func (c *Config) ContentRoot(dir string) (string, error) {
+	if c == nil || c.Root == "" {
+		return dir, nil
+	}
+	root, err := ResolvePath(dir, c.Root)
+	if err != nil {
+		return "", fmt.Errorf("invalid root: %w", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid root: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid root: %s is not a directory", c.Root)
+	}
+	return root, nil
+}
+
+// ResolvePath joins a relative path to dir, refusing paths that lead out of
+// dir, including through symbolic links
+func ResolvePath(dir, rel string) (string, error) {
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%s must be relative", rel)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if !Within(dir, path) {
+		return "", fmt.Errorf("%s is outside %s", rel, dir)
+	}
+	return path, nil
+}
+
+// Within reports whether path is dir or lies under it, once symbolic links
+// are resolved. For paths that don't exist yet, the links of their existing
+// ancestors are resolved, so a new file can't be created through a link
+// leading out of dir.
+func Within(dir, path string) bool {
+	if !within(dir, path) {
+		return false
+	}
+	resolvedDir, resolved := resolveExisting(dir, 0), resolveExisting(path, 0)
+	return resolvedDir != "" && resolved != "" && within(resolvedDir, resolved)
+}
+
+// resolveExisting resolves the symbolic links of the longest existing part of
+// path, including dangling links, and keeps the rest as written. It returns ""
+// for link cycles.
+func resolveExisting(path string, depth int) string {
+	if depth > 40 {
+		return ""
+	}
+	path = filepath.Clean(path)
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return ""
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			return resolveExisting(filepath.Join(target, rest), depth+1)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest)
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// AutoSummary reports whether the summary is derived from the directory tree
+func (c *Config) AutoSummary() bool {
+	return c != nil && c.Summary != nil && c.Summary.Auto
+}
+
+// WithDefaults returns a copy of the configuration with the defaults the
+// builders use filled in
+func (c *Config) WithDefaults() *Config {
+	out := Config{}
+	if c != nil {
+		out = *c
+	}
+	if out.Title == "" {
+		out.Title = "GitBook"
+	}
+	structure := Structure{}
+	if out.Structure != nil {
+		structure = *out.Structure
+	}
+	for _, f := range []struct {
+		value *string
+		name  string
+	}{
+		{&structure.Readme, "README.md"},
+		{&structure.Summary, "SUMMARY.md"},
+		{&structure.Glossary, "GLOSSARY.md"},
+		{&structure.Languages, "LANGS.md"},
+	} {
+		if *f.value == "" {
+			*f.value = f.name
+		}
+	}
+	out.Structure = &structure
+	return &out
+}
+
+// LoadConfig loads a configuration file, in JSON, YAML or TOML depending on
+// its extension
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	name := filepath.Base(path)
+	content := data
+	if configFormat(path) != "json" {
+		values, err := ReadConfigValues(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s", err)
+		}
+		if content, err = json.Marshal(values); err != nil {
+			return nil, err
+		}
+	}
+
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse book.json: %w", err)
+	if err := json.Unmarshal(content, &config); err != nil {
+		// The schema locates the offending value
+		issues, verr := ValidateConfig(data, path)
+		if verr != nil {
+			return nil, fmt.Errorf("failed to parse %w", verr)
+		}
+		for _, issue := range issues {
+			if strings.HasPrefix(issue.Message, "expected ") {
+				return nil, fmt.Errorf("failed to parse %s", issue)
+			}
+		}
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
 	}
 
 	return &config, nil
 }
 
-// SaveConfig saves book.json
+// SaveConfig saves a configuration file, in JSON, YAML or TOML depending on
+// its extension
 func SaveConfig(path string, config *Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+	data, err := EncodeConfig(config, path)
 	if err != nil {
 		return err
 	}
@@ -107,6 +396,18 @@ func SaveConfig(path string, config *Config) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// EncodeConfig returns the content SaveConfig writes to path
+func EncodeConfig(config *Config, path string) ([]byte, error) {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if data, err = encodeConfig(data, path); err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	return data, nil
+}
+
 // LoadSummary loads SUMMARY.md
 func LoadSummary(path string) (*Summary, error) {
 	data, err := os.ReadFile(path)
@@ -136,12 +437,32 @@ func ParseSummary(content string) (*Summary, error) {
 	var stack []*[]Chapter
 	stack = append(stack, &summary.Chapters)
 
+	// Comments and part headings are kept for the next chapter so that the
+	// summary can be rendered back without losing them
+	var comments []string
+	part := ""
+	inComment := false
+
 	for _, line := range lines {
 		// Get indent level BEFORE trimming spaces
 		level := getIndentLevel(line)
 
 		// Trim spaces for parsing
 		trimmedLine := trimSpace(line)
+		if inComment || strings.HasPrefix(trimmedLine, "<!--") {
+			comments = append(comments, trimmedLine)
+			inComment = !strings.Contains(trimmedLine, "-->")
+			continue
+		}
+		if strings.HasPrefix(trimmedLine, "#") {
+			heading := trimSpace(strings.TrimLeft(trimmedLine, "#"))
+			if summary.Title == "" && len(summary.Chapters) == 0 && part == "" {
+				summary.Title = heading
+			} else {
+				part = heading
+			}
+			continue
+		}
 		if trimmedLine == "" || !strings.HasPrefix(trimmedLine, "*") {
 			continue
 		}
@@ -156,6 +477,12 @@ func ParseSummary(content string) (*Summary, error) {
 			Title:    title,
 			Path:     path,
 			Articles: []Chapter{},
+			Comments: comments,
+		}
+		comments = nil
+		if part != "" {
+			// Parts start at the top level
+			chapter.Part, part, level = part, "", 0
 		}
 
 		// Adjust stack to match current level
@@ -187,6 +514,10 @@ func ParseSummary(content string) (*Summary, error) {
 		parent := stack[level]
 		*parent = append(*parent, chapter)
 	}
+	summary.Trailer = comments
+	if part != "" {
+		summary.Trailer = append(summary.Trailer, "## "+part) // a part without chapters
+	}
 
 	return summary, nil
 }
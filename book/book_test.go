@@ -0,0 +1,96 @@
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithin(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "new.md"), filepath.Join(root, "dangling.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("docs", filepath.Join(root, "inner")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{".", true},
+		{"docs/page.md", true},
+		{"missing/dir/page.md", true},
+		{"inner/page.md", true},
+		{"../page.md", false},
+		{"docs/../../page.md", false},
+		{"link", false},
+		{"link/pwn.md", false},
+		{"link/sub/pwn.md", false},
+		{"dangling.md", false},
+	}
+	for _, tt := range tests {
+		if got := Within(root, filepath.Join(root, tt.path)); got != tt.want {
+			t.Errorf("Within(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBookOverrides(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "book.json"), []byte(`{"title": "File", "pdf": {"fontSize": 10}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITBOOK_TITLE", "Env")
+	t.Setenv("GITBOOK_PDF_FONTSIZE", "11")
+
+	b, err := LoadBook(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Config.Title != "Env" || b.Config.PDF.FontSize != 11 {
+		t.Errorf("without overrides got title %q, font size %d; want the environment", b.Config.Title, b.Config.PDF.FontSize)
+	}
+
+	// Overrides apply after the environment
+	b, err = LoadBook(root, WithOverrides([]string{"title=Set"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Config.Title != "Set" || b.Config.PDF.FontSize != 11 {
+		t.Errorf("with overrides got title %q, font size %d", b.Config.Title, b.Config.PDF.FontSize)
+	}
+
+	if _, err := LoadBook(root, WithOverrides([]string{"title"})); err == nil {
+		t.Error("expected an error for an override without a value")
+	}
+}
+
+func TestLoadBookWarnings(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "book.json"), []byte("{\n  \"title\": \"T\",\n  \"colour\": \"red\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITBOOK_NO_SUCH_KEY", "1")
+
+	b, err := LoadBook(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, w := range b.Warnings {
+		got = append(got, w.String())
+	}
+	if len(got) != 2 || got[0] != `book.json:3:3: colour: unknown key "colour"` ||
+		got[1] != "GITBOOK_NO_SUCH_KEY: does not name a configuration key; ignored" {
+		t.Errorf("warnings = %q", got)
+	}
+}
@@ -0,0 +1,611 @@
+package book
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFiles lists the configuration file names a book root may hold, in
+// order of precedence
+var ConfigFiles = []string{"book.json", "book.yaml", "book.yml", "book.toml"}
+
+// FindConfig returns the configuration file of a book root, or "" if there is
+// none. Books may only have one. The book.toml of an mdBook project, which
+// keeps its settings in a [book] table, is not a GitBook configuration.
+func FindConfig(root string) (string, error) {
+	var found []string
+	for _, name := range ConfigFiles {
+		file := filepath.Join(root, name)
+		if _, err := os.Stat(file); err == nil && !IsMdbookConfig(file) {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return filepath.Join(root, found[0]), nil
+	}
+	return "", fmt.Errorf("%s has several configuration files (%s); keep only one", root, strings.Join(found, ", "))
+}
+
+// IsMdbookConfig reports whether a file is the book.toml of an mdBook project
+func IsMdbookConfig(file string) bool {
+	if filepath.Base(file) != "book.toml" {
+		return false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	n, err := parseTOMLNode(data)
+	return err == nil && n.member("book") != nil && n.member("book").kind() == "object"
+}
+
+// ConfigPath returns the configuration file of a book root, book.json if
+// there is none yet
+func ConfigPath(root string) (string, error) {
+	path, err := FindConfig(root)
+	if path == "" && err == nil {
+		path = filepath.Join(root, "book.json")
+	}
+	return path, err
+}
+
+// IsConfigFile reports whether a file name is one of ConfigFiles
+func IsConfigFile(name string) bool {
+	for _, f := range ConfigFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// configFormat returns json, yaml or toml from the extension of a file
+func configFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	return "json"
+}
+
+// ReadConfigValues reads a configuration file as generic JSON values
+func ReadConfigValues(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseConfigNode(data, path)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := n.interfaceValue().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: the configuration must be an object", filepath.Base(path))
+	}
+	return values, nil
+}
+
+// EditConfig sets a dotted key in the content of a configuration file, as
+// SetConfigValue does, and returns the new content. Other keys are kept in
+// their order, and so are the comments of YAML files; TOML files are encoded
+// again with sorted keys and without comments.
+func EditConfig(data []byte, file, key, raw string) ([]byte, error) {
+	if configFormat(file) == "json" && len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}\n")
+	}
+	n, err := parseConfigNode(data, file)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := n.interfaceValue().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: the configuration must be an object", filepath.Base(file))
+	}
+	if err := SetConfigValue(values, key, raw); err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(key, ".")
+	var value interface{} = values
+	for _, part := range parts {
+		value = value.(map[string]interface{})[part]
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	valueNode, err := parseJSONNode(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	switch configFormat(file) {
+	case "yaml":
+		data, err = editYAML(data, parts, valueNode)
+	case "toml":
+		data, err = editTOML(data, parts, valueNode)
+	default:
+		if err = setMember(n, parts, valueNode); err == nil {
+			data, err = encodeJSONNode(n)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+	return data, nil
+}
+
+// setMember sets a dotted key of an object node, creating intermediate
+// objects
+func setMember(n *configNode, parts []string, value *configNode) error {
+	for _, part := range parts[:len(parts)-1] {
+		child := n.member(part)
+		if child == nil {
+			child = &configNode{value: []configMember{}}
+			n.value = append(n.value.([]configMember), configMember{key: part, value: child})
+		} else if child.kind() != "object" {
+			return fmt.Errorf("%s is not an object", part)
+		}
+		n = child
+	}
+	last := parts[len(parts)-1]
+	members := n.value.([]configMember)
+	for i := len(members) - 1; i >= 0; i-- {
+		if members[i].key == last {
+			members[i].value = value
+			return nil
+		}
+	}
+	n.value = append(members, configMember{key: last, value: value})
+	return nil
+}
+
+// encodeJSONNode writes a node as indented JSON, keeping the key order
+func encodeJSONNode(n *configNode) ([]byte, error) {
+	var compact, out bytes.Buffer
+	if err := writeJSONNode(&compact, n); err != nil {
+		return nil, err
+	}
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func writeJSONNode(buf *bytes.Buffer, n *configNode) error {
+	switch v := n.value.(type) {
+	case []configMember:
+		buf.WriteByte('{')
+		for i, m := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(m.key)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, m.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []*configNode:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(string(v))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+// editYAML sets a dotted key of a YAML document through its node tree, which
+// keeps comments and the style of the other values
+func editYAML(data []byte, parts []string, value *configNode) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	m := doc.Content[0]
+	for i, part := range parts {
+		if m.Kind == yaml.AliasNode {
+			m = m.Alias
+		}
+		if m.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not an object", strings.Join(parts[:i], "."))
+		}
+		var child *yaml.Node
+		for j := len(m.Content) - 2; j >= 0; j -= 2 {
+			if m.Content[j].Value == part {
+				child = m.Content[j+1]
+				if i == len(parts)-1 {
+					v := yamlNode(value)
+					v.HeadComment, v.LineComment, v.FootComment = child.HeadComment, child.LineComment, child.FootComment
+					m.Content[j+1] = v
+					return encodeYAMLNode(&doc)
+				}
+				break
+			}
+		}
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if i == len(parts)-1 {
+				child = yamlNode(value)
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, child)
+		}
+		m = child
+	}
+	return encodeYAMLNode(&doc)
+}
+
+func encodeYAMLNode(n *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// position is a 1-based line and column, in characters
+type position struct {
+	line, col int
+}
+
+// configNode is a configuration value with its position in the source file,
+// whatever its format
+type configNode struct {
+	pos   position
+	value interface{} // nil, bool, json.Number, string, []configMember or []*configNode
+}
+
+// configMember is a key of an object, in source order
+type configMember struct {
+	key   string
+	pos   position // position of the key
+	value *configNode
+}
+
+func (n *configNode) kind() string {
+	switch v := n.value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case string:
+		return "string"
+	case []configMember:
+		return "object"
+	default:
+		return "array"
+	}
+}
+
+func (n *configNode) is(t string) bool {
+	k := n.kind()
+	return k == t || t == "number" && k == "integer"
+}
+
+// member returns the value of a key of an object node, or nil
+func (n *configNode) member(key string) *configNode {
+	members, _ := n.value.([]configMember)
+	for i := len(members) - 1; i >= 0; i-- {
+		if members[i].key == key {
+			return members[i].value
+		}
+	}
+	return nil
+}
+
+// interfaceValue converts the node to the generic values of encoding/json
+func (n *configNode) interfaceValue() interface{} {
+	switch v := n.value.(type) {
+	case []configMember:
+		obj := make(map[string]interface{}, len(v))
+		for _, m := range v {
+			obj[m.key] = m.value.interfaceValue()
+		}
+		return obj
+	case []*configNode:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item.interfaceValue()
+		}
+		return list
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return float64(i)
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// parseConfigNode parses a configuration file in the format of its extension
+func parseConfigNode(data []byte, file string) (*configNode, error) {
+	var n *configNode
+	var err error
+	switch configFormat(file) {
+	case "yaml":
+		n, err = parseYAMLNode(data)
+	case "toml":
+		n, err = parseTOMLNode(data)
+	default:
+		n, err = parseJSONNode(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+	return n, nil
+}
+
+// lineIndex converts byte offsets to positions
+type lineIndex struct {
+	data   []byte
+	starts []int // offset of each line
+}
+
+func newLineIndex(data []byte) *lineIndex {
+	idx := &lineIndex{data: data, starts: []int{0}}
+	for i, c := range data {
+		if c == '\n' {
+			idx.starts = append(idx.starts, i+1)
+		}
+	}
+	return idx
+}
+
+func (idx *lineIndex) position(offset int) position {
+	offset = min(offset, len(idx.data))
+	line := 0
+	for line+1 < len(idx.starts) && idx.starts[line+1] <= offset {
+		line++
+	}
+	return position{line: line + 1, col: utf8.RuneCount(idx.data[idx.starts[line]:offset]) + 1}
+}
+
+// parseJSONNode parses JSON keeping the position of every value and key
+func parseJSONNode(data []byte) (*configNode, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	lines := newLineIndex(data)
+	n, err := decodeJSONNode(d, data, lines)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("line %d: %w", lines.position(int(syntaxErr.Offset)).line, err)
+		}
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return n, nil
+}
+
+func decodeJSONNode(d *json.Decoder, data []byte, lines *lineIndex) (*configNode, error) {
+	offset := valueStart(data, int(d.InputOffset()))
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &configNode{pos: lines.position(offset)}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		n.value = tok
+		return n, nil
+	}
+
+	switch delim {
+	case '{':
+		members := []configMember{}
+		for d.More() {
+			keyOffset := valueStart(data, int(d.InputOffset()))
+			keyTok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			value, err := decodeJSONNode(d, data, lines)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, configMember{key: key, pos: lines.position(keyOffset), value: value})
+		}
+		n.value = members
+	case '[':
+		items := []*configNode{}
+		for d.More() {
+			item, err := decodeJSONNode(d, data, lines)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		n.value = items
+	}
+	if _, err := d.Token(); err != nil { // closing delimiter
+		return nil, err
+	}
+	return n, nil
+}
+
+// valueStart skips the whitespace and separators before the next token
+func valueStart(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// parseYAMLNode parses YAML keeping the position of every value and key
+func parseYAMLNode(data []byte) (*configNode, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return &configNode{pos: position{1, 1}, value: []configMember{}}, nil // empty file
+	}
+	return convertYAMLNode(doc.Content[0])
+}
+
+func convertYAMLNode(y *yaml.Node) (*configNode, error) {
+	n := &configNode{pos: position{y.Line, y.Column}}
+	switch y.Kind {
+	case yaml.AliasNode:
+		return convertYAMLNode(y.Alias)
+	case yaml.MappingNode:
+		members := []configMember{}
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			key, value := y.Content[i], y.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be scalars", key.Line)
+			}
+			v, err := convertYAMLNode(value)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, configMember{key: key.Value, pos: position{key.Line, key.Column}, value: v})
+		}
+		n.value = members
+	case yaml.SequenceNode:
+		items := []*configNode{}
+		for _, item := range y.Content {
+			v, err := convertYAMLNode(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		n.value = items
+	case yaml.ScalarNode:
+		switch y.ShortTag() {
+		case "!!null":
+			n.value = nil
+		case "!!bool":
+			var b bool
+			if err := y.Decode(&b); err != nil {
+				return nil, fmt.Errorf("line %d: %w", y.Line, err)
+			}
+			n.value = b
+		case "!!int":
+			var i int64
+			if err := y.Decode(&i); err != nil {
+				return nil, fmt.Errorf("line %d: %w", y.Line, err)
+			}
+			n.value = json.Number(strconv.FormatInt(i, 10))
+		case "!!float":
+			var f float64
+			if err := y.Decode(&f); err != nil {
+				return nil, fmt.Errorf("line %d: %w", y.Line, err)
+			}
+			s := strconv.FormatFloat(f, 'g', -1, 64)
+			if strings.ContainsAny(s, "IN") {
+				return nil, fmt.Errorf("line %d: %s is not a finite number", y.Line, y.Value)
+			}
+			if !strings.ContainsAny(s, ".e") {
+				s += ".0"
+			}
+			n.value = json.Number(s)
+		default:
+			n.value = y.Value
+		}
+	}
+	return n, nil
+}
+
+// encodeConfig writes JSON in the format of file
+func encodeConfig(data []byte, file string) ([]byte, error) {
+	format := configFormat(file)
+	if format == "json" {
+		return data, nil
+	}
+	n, err := parseJSONNode(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == "toml" {
+		return encodeTOML(n)
+	}
+
+	return encodeYAMLNode(yamlNode(n))
+}
+
+// yamlNode converts a node to YAML, keeping the key order
+func yamlNode(n *configNode) *yaml.Node {
+	switch v := n.value.(type) {
+	case []configMember:
+		y := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, m := range v {
+			y.Content = append(y.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: m.key}, yamlNode(m.value))
+		}
+		return y
+	case []*configNode:
+		y := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			y.Content = append(y.Content, yamlNode(item))
+		}
+		return y
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case json.Number:
+		tag := "!!int"
+		if n.kind() == "number" {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,139 @@
+package book
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditConfig(t *testing.T) {
+	tests := []struct {
+		name, file, data, key, raw, want string
+	}{
+		{
+			name: "json keeps unknown keys and order",
+			file: "book.json",
+			data: `{"title": "A", "extra": {"b": 1}, "author": "Z"}`,
+			key:  "title", raw: "B",
+			want: "{\n  \"title\": \"B\",\n  \"extra\": {\n    \"b\": 1\n  },\n  \"author\": \"Z\"\n}\n",
+		},
+		{
+			name: "json new file",
+			file: "book.json",
+			key:  "pdf.fontSize", raw: "12",
+			want: "{\n  \"pdf\": {\n    \"fontSize\": 12\n  }\n}\n",
+		},
+		{
+			name: "yaml keeps comments",
+			file: "book.yaml",
+			data: "# My book\ntitle: T # the title\nreleased: 2024-01-02\npdf:\n  # font\n  fontSize: 10\n",
+			key:  "pdf.fontSize", raw: "12",
+			want: "# My book\ntitle: T # the title\nreleased: 2024-01-02\npdf:\n  # font\n  fontSize: 12\n",
+		},
+		{
+			name: "yaml new nested key",
+			file: "book.yaml",
+			data: "title: T\n",
+			key:  "variables.version", raw: `"1.0"`,
+			want: "title: T\nvariables:\n  version: \"1.0\"\n",
+		},
+		{
+			name: "toml keeps dates",
+			file: "book.toml",
+			data: "title = \"T\" # the title\nreleased = 2024-01-02\n",
+			key:  "title", raw: "New",
+			want: "released = 2024-01-02\ntitle = 'New'\n",
+		},
+		{
+			name: "toml new key in its table",
+			file: "book.toml",
+			data: "title = \"T\"\n\n[pdf]\nfontSize = 10\n\n[pdf.margin]\ntop = 1\n",
+			key:  "pdf.paperSize", raw: "a4",
+			want: "title = 'T'\n\n[pdf]\nfontSize = 10\npaperSize = 'a4'\n\n[pdf.margin]\ntop = 1\n",
+		},
+		{
+			name: "toml new table",
+			file: "book.toml",
+			data: "title = \"T\"\n\n[pdf]\nfontSize = 10\n",
+			key:  "variables.version", raw: "2",
+			want: "title = 'T'\n\n[pdf]\nfontSize = 10\n\n[variables]\nversion = 2\n",
+		},
+		{
+			name: "toml dotted keys and inline tables",
+			file: "book.toml",
+			data: "pdf.fontSize = 10\nvariables = { v = 1 }\n",
+			key:  "pdf.paperSize", raw: "a4",
+			want: "[pdf]\nfontSize = 10\npaperSize = 'a4'\n\n[variables]\nv = 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EditConfig([]byte(tt.data), tt.file, tt.key, tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, file, data, key, raw string
+	}{
+		{"wrong type", "book.json", `{}`, "pdf.fontSize", `"big"`},
+		{"not an object", "book.toml", "pdf = 1\n", "pdf.fontSize", "12"},
+		{"invalid toml", "book.toml", "pdf = \n", "pdf.fontSize", "12"},
+	}
+	for _, tt := range tests {
+		if _, err := EditConfig([]byte(tt.data), tt.file, tt.key, tt.raw); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestParseTOMLNode(t *testing.T) {
+	data := "title = \"T\"\nreleased = 2024-01-02\n\n[pdf]\nmargin.top = 1.5\n\n[[plugins]]\nname = \"a\"\n\n[[plugins]]\nname = 1\n"
+	n, err := parseTOMLNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Members keep the order of the document
+	var keys []string
+	for _, m := range n.value.([]configMember) {
+		keys = append(keys, m.key)
+	}
+	if got := strings.Join(keys, " "); got != "title released pdf plugins" {
+		t.Errorf("keys = %s", got)
+	}
+	if got := n.member("released").value; got != "2024-01-02" {
+		t.Errorf("released = %#v, want the date as a string", got)
+	}
+
+	tests := []struct {
+		name string
+		node *configNode
+		want position
+	}{
+		{"title", n.member("title"), position{1, 9}},
+		{"pdf.margin.top", n.member("pdf").member("margin").member("top"), position{5, 14}},
+		{"plugins[1].name", n.member("plugins").value.([]*configNode)[1].member("name"), position{11, 8}},
+	}
+	for _, tt := range tests {
+		if tt.node == nil {
+			t.Errorf("%s is missing", tt.name)
+		} else if tt.node.pos != tt.want {
+			t.Errorf("%s at %v, want %v", tt.name, tt.node.pos, tt.want)
+		}
+	}
+	plugins := n.member("plugins").value.([]*configNode)
+	if m := plugins[1].value.([]configMember)[0]; m.pos != (position{11, 1}) {
+		t.Errorf("key of plugins[1].name at %v", m.pos)
+	}
+
+	if _, err := parseTOMLNode([]byte("a = 1\nb =\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("error = %v, want one on line 2", err)
+	}
+}
@@ -0,0 +1,100 @@
+package book
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter represents the YAML front matter of a markdown page
+type FrontMatter map[string]interface{}
+
+// ParseFrontMatter splits a markdown page into its front matter and body.
+// Front matter is a YAML block delimited by "---" lines at the very top of the file;
+// pages without one return an empty FrontMatter and the content unchanged.
+func ParseFrontMatter(content []byte) (FrontMatter, []byte, error) {
+	fm := FrontMatter{}
+
+	rest, ok := trimDelimiter(content)
+	if !ok {
+		return fm, content, nil
+	}
+
+	// Find the closing delimiter
+	end := -1
+	offset := 0
+	for offset < len(rest) {
+		lineEnd := bytes.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if lineEnd >= 0 {
+			line = rest[offset : offset+lineEnd]
+		}
+		if string(bytes.TrimRight(line, " \t\r")) == "---" {
+			end = offset
+			break
+		}
+		if lineEnd < 0 {
+			break
+		}
+		offset += lineEnd + 1
+	}
+	if end < 0 {
+		return fm, content, nil
+	}
+
+	if err := yaml.Unmarshal(rest[:end], &fm); err != nil {
+		return nil, content, fmt.Errorf("failed to parse front matter: %w", err)
+	}
+	if fm == nil {
+		fm = FrontMatter{}
+	}
+
+	body := rest[end:]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	return fm, body, nil
+}
+
+// String returns the front matter value for key as a string, or "" if unset
+func (fm FrontMatter) String(key string) string {
+	v, ok := fm[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// Number returns the front matter value for key as a number, reporting
+// whether it is set and numeric
+func (fm FrontMatter) Number(key string) (float64, bool) {
+	switch v := fm[key].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// trimDelimiter strips the opening "---" line, reporting whether one was present
+func trimDelimiter(content []byte) ([]byte, bool) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	for _, prefix := range []string{"---\n", "---\r\n"} {
+		if bytes.HasPrefix(content, []byte(prefix)) {
+			return content[len(prefix):], true
+		}
+	}
+	return nil, false
+}
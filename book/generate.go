@@ -0,0 +1,193 @@
+package book
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numberPrefix matches ordering prefixes of file names such as "01-" or "2_"
+var numberPrefix = regexp.MustCompile(`^(\d+)\s*[-_. ]+`)
+
+// generatedEntry is a chapter found while walking the tree, with its sort keys
+type generatedEntry struct {
+	chapter  Chapter
+	name     string
+	order    float64
+	hasOrder bool
+}
+
+// GenerateSummary builds a summary from the Markdown files under root.
+// Directories become chapters opened by their README.md or index.md, titles
+// come from the title front matter or the first heading, and entries are
+// ordered by their order front matter, then by numeric file name prefixes,
+// then by name. Paths matched by ignore are skipped.
+func GenerateSummary(root string, ignore *Ignore) (*Summary, error) {
+	index, chapters, err := generateChapters(root, "", ignore)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil {
+		chapters = append([]Chapter{index.chapter}, chapters...)
+	}
+	return &Summary{Chapters: chapters}, nil
+}
+
+// generateChapters returns the index page of a directory and the sorted
+// chapters of its other pages and subdirectories
+func generateChapters(dir, rel string, ignore *Ignore) (*generatedEntry, []Chapter, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var index *generatedEntry
+	var found []generatedEntry
+	for _, entry := range entries {
+		name := entry.Name()
+		entryRel := path.Join(rel, name)
+		if strings.HasPrefix(name, ".") || name == "_book" || name == "node_modules" ||
+			ignore.Match(entryRel, entry.IsDir()) {
+			continue
+		}
+
+		if entry.IsDir() {
+			sub, articles, err := generateChapters(filepath.Join(dir, name), entryRel, ignore)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch {
+			case sub != nil:
+				sub.chapter.Articles = articles
+				sub.name = name
+				if !sub.hasOrder {
+					sub.order, sub.hasOrder = prefixOrder(name)
+				}
+				found = append(found, *sub)
+			case len(articles) > 0:
+				e := generatedEntry{chapter: Chapter{Title: FileTitle(name), Articles: articles}, name: name}
+				e.order, e.hasOrder = prefixOrder(name)
+				found = append(found, e)
+			}
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(name))
+		if ext != ".md" && ext != ".markdown" {
+			continue
+		}
+		if rel == "" && (name == "SUMMARY.md" || name == "GLOSSARY.md" || name == "LANGS.md") {
+			continue
+		}
+
+		e := generatedEntry{chapter: Chapter{Path: entryRel, Articles: []Chapter{}}, name: name}
+		fm, title := pageInfo(filepath.Join(dir, name))
+		e.chapter.Title = title
+		if order, ok := fm.Number("order"); ok {
+			e.order, e.hasOrder = order, true
+		} else {
+			e.order, e.hasOrder = prefixOrder(name)
+		}
+
+		base := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+		if (base == "readme" || base == "index") && (index == nil || base == "readme") {
+			if e.chapter.Title == "" {
+				e.chapter.Title = FileTitle(path.Base(rel))
+				if rel == "" {
+					e.chapter.Title = "Introduction"
+				}
+			}
+			if index != nil {
+				found = append(found, *index) // README wins over index
+			}
+			index = &e
+			continue
+		}
+		if e.chapter.Title == "" {
+			e.chapter.Title = FileTitle(name)
+		}
+		found = append(found, e)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.hasOrder != b.hasOrder {
+			return a.hasOrder
+		}
+		if a.hasOrder && a.order != b.order {
+			return a.order < b.order
+		}
+		return a.name < b.name
+	})
+	chapters := make([]Chapter, len(found))
+	for i, e := range found {
+		chapters[i] = e.chapter
+	}
+	return index, chapters, nil
+}
+
+// prefixOrder returns the number a name starts with, as in "02-setup.md"
+func prefixOrder(name string) (float64, bool) {
+	m := numberPrefix.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	return float64(n), err == nil
+}
+
+var atxHeading = regexp.MustCompile(`^#{1,6}\s+(.+?)(?:\s+#+)?\s*$`)
+
+// PageTitle returns the title of a Markdown page from its front matter or its
+// first heading, or "" if it has neither
+func PageTitle(file string) string {
+	_, title := pageInfo(file)
+	return title
+}
+
+// pageInfo returns the front matter of a page and its title
+func pageInfo(file string) (FrontMatter, string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return FrontMatter{}, ""
+	}
+	fm, body, err := ParseFrontMatter(content)
+	if err != nil {
+		fm, body = FrontMatter{}, content
+	}
+	if title := fm.String("title"); title != "" {
+		return fm, title
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	fence := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fence = !fence
+			continue
+		}
+		if m := atxHeading.FindStringSubmatch(line); m != nil && !fence {
+			return fm, m[1]
+		}
+	}
+	return fm, ""
+}
+
+// FileTitle turns a file or directory name into a title: the extension and
+// numeric prefix are dropped and hyphens and underscores become spaces
+func FileTitle(name string) string {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	name = numberPrefix.ReplaceAllString(name, "")
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
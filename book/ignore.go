@@ -0,0 +1,230 @@
+package book
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Ignore matches paths against gitignore-style patterns, as found in .bookignore
+type Ignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool   // "!pattern" re-includes what earlier patterns excluded
+	dirOnly bool   // "pattern/" only matches directories
+	prefix  string // path from the directory of the pattern file to the root
+	base    string // directory of the pattern file below the root, the only paths it applies to
+}
+
+// LoadIgnore returns the ignore rules of a book whose configuration file is
+// in configRoot and content in root: with gitignore, those of the .gitignore
+// files from configRoot down to root and of those inside root, each applying
+// to its own directory, then those of the .bookignore file of root, which
+// take precedence. Missing files ignore nothing.
+func LoadIgnore(configRoot, root string, gitignore bool) (*Ignore, error) {
+	ig := &Ignore{}
+	if gitignore {
+		var parts []string
+		if rel, err := filepath.Rel(configRoot, root); err == nil && rel != "." && within(configRoot, root) {
+			parts = strings.Split(filepath.ToSlash(rel), "/")
+		}
+		for i := 0; i <= len(parts); i++ {
+			dir := filepath.Join(configRoot, filepath.FromSlash(strings.Join(parts[:i], "/")))
+			prefix := strings.Join(parts[i:], "/")
+			if prefix != "" {
+				prefix += "/"
+			}
+			if err := ig.addFile(filepath.Join(dir, ".gitignore"), prefix); err != nil {
+				return nil, err
+			}
+		}
+		if err := ig.addNested(root); err != nil {
+			return nil, err
+		}
+	}
+	if err := ig.addFile(filepath.Join(root, ".bookignore"), ""); err != nil {
+		return nil, err
+	}
+	return ig, nil
+}
+
+// addFile adds the patterns of a file, if it exists, to paths under prefix
+func (ig *Ignore) addFile(name, prefix string) error {
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ig.add(string(data), prefix)
+	return nil
+}
+
+// addNested adds the .gitignore files of the directories below root, parents
+// first. As with git, the files of ignored directories are not read, nor are
+// those of the directories the book always skips.
+func (ig *Ignore) addNested(root string) error {
+	return filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || p == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		name := d.Name()
+		if strings.HasPrefix(name, ".") || name == "_book" || name == "node_modules" || ig.Match(rel, true) {
+			return filepath.SkipDir
+		}
+		data, err := os.ReadFile(filepath.Join(p, ".gitignore"))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		n := len(ig.rules)
+		ig.add(string(data), "")
+		for i := n; i < len(ig.rules); i++ {
+			ig.rules[i].base = rel
+		}
+		return nil
+	})
+}
+
+// ParseIgnore parses gitignore patterns, one per line
+func ParseIgnore(content string) *Ignore {
+	ig := &Ignore{}
+	ig.Add(content)
+	return ig
+}
+
+// Add appends the patterns of content; later patterns take precedence
+func (ig *Ignore) Add(content string) {
+	ig.add(content, "")
+}
+
+func (ig *Ignore) add(content, prefix string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// Trailing spaces are ignored unless escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{prefix: prefix}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// Patterns with a slash other than a trailing one are relative to the
+		// root, others match a name at any depth
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		start := "^(?:.*/)?"
+		if anchored {
+			start = "^"
+		}
+		re, err := regexp.Compile(start + ignorePatternRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		ig.rules = append(ig.rules, rule)
+	}
+}
+
+// ignorePatternRegexp translates the glob syntax of a pattern
+func ignorePatternRegexp(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// Match reports whether a slash-separated path relative to the root is
+// ignored. Paths inside an ignored directory are ignored too, as with git.
+func (ig *Ignore) Match(rel string, isDir bool) bool {
+	if ig == nil || len(ig.rules) == 0 {
+		return false
+	}
+	rel = strings.Trim(path.Clean(filepath.ToSlash(rel)), "/")
+	if rel == "." || rel == "" {
+		return false
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if ig.match(dir, true) {
+			return true
+		}
+	}
+	return ig.match(rel, isDir)
+}
+
+// match applies the rules to one path, the last matching rule deciding
+func (ig *Ignore) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		p := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			p = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if rule.re.MatchString(rule.prefix + p) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
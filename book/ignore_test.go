@@ -0,0 +1,94 @@
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatch(t *testing.T) {
+	ig := ParseIgnore("# logs\n*.log\n!keep.log\nbuild/\n/root.txt\n**/cache/tmp\ndocs/**/draft.md\nspace\\ \n")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"sub/app.log", false, true},
+		{"keep.log", false, false},
+		{"sub/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"build/out.js", false, true},
+		{"root.txt", false, true},
+		{"sub/root.txt", false, false},
+		{"cache/tmp", false, true},
+		{"a/b/cache/tmp", true, true},
+		{"docs/draft.md", false, true},
+		{"docs/a/b/draft.md", false, true},
+		{"draft.md", false, false},
+		{"space ", false, true},
+		{".", true, false},
+	}
+	for _, tt := range tests {
+		if got := ig.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestLoadIgnore(t *testing.T) {
+	configRoot := t.TempDir()
+	root := filepath.Join(configRoot, "docs")
+	files := map[string]string{
+		".gitignore":          "/docs/private/\n*.tmp\n/top.md\n",
+		"docs/.gitignore":     "local.txt\n",
+		"docs/.bookignore":    "!a.tmp\n",
+		"docs/sub/.gitignore": "*.md\n!keep.md\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(configRoot, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path      string
+		isDir     bool
+		gitignore bool // result with .gitignore files
+		bookonly  bool // result with .bookignore alone
+	}{
+		{"private", true, true, false},
+		{"private/page.md", false, true, false},
+		{"a.tmp", false, false, false},
+		{"b.tmp", false, true, false},
+		{"top.md", false, false, false},
+		{"local.txt", false, true, false},
+		{"sub/local.txt", false, true, false},
+		{"sub/page.md", false, true, false},
+		{"sub/keep.md", false, false, false},
+		{"page.md", false, false, false},
+		{"other/page.md", false, false, false},
+	}
+	for _, gitignore := range []bool{true, false} {
+		ig, err := LoadIgnore(configRoot, root, gitignore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			want := tt.bookonly
+			if gitignore {
+				want = tt.gitignore
+			}
+			if got := ig.Match(tt.path, tt.isDir); got != want {
+				t.Errorf("gitignore=%v: Match(%q) = %v, want %v", gitignore, tt.path, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix prefixes the environment variables that override configuration
+// keys: GITBOOK_TITLE sets title, GITBOOK_PDF_FONTSIZE sets pdf.fontSize
+const EnvPrefix = "GITBOOK_"
+
+// LoadOption changes how LoadBook reads a book
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	overrides []string
+}
+
+// WithOverrides applies key=value settings over the configuration file and
+// the environment, as given to the --set flag
+func WithOverrides(overrides []string) LoadOption {
+	return func(o *loadOptions) {
+		o.overrides = append(o.overrides, overrides...)
+	}
+}
+
+// applyOverrides returns the configuration of a book with the environment and
+// overrides applied, in that order. Without any, config is returned as is.
+// Environment variables that name no key are ignored and reported.
+func applyOverrides(configPath string, config *Config, overrides []string) (*Config, []ConfigIssue, error) {
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, EnvPrefix) {
+			env = append(env, kv)
+		}
+	}
+	if len(env) == 0 && len(overrides) == 0 {
+		return config, nil, nil
+	}
+
+	values := map[string]interface{}{}
+	if configPath != "" {
+		var err error
+		if values, err = ReadConfigValues(configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var warnings []ConfigIssue
+	sort.Strings(env)
+	for _, kv := range env {
+		name, raw, _ := strings.Cut(kv, "=")
+		key := envKey(values, strings.TrimPrefix(name, EnvPrefix))
+		if key == "" {
+			warnings = append(warnings, ConfigIssue{File: name, Message: "does not name a configuration key; ignored"})
+			continue
+		}
+		if err := SetConfigValue(values, key, raw); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for _, o := range overrides {
+		key, raw, ok := strings.Cut(o, "=")
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("invalid --set %q: expected key=value", o)
+		}
+		if err := SetConfigValue(values, key, raw); err != nil {
+			return nil, nil, fmt.Errorf("--set %s: %w", key, err)
+		}
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	var out Config
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration overrides: %w", err)
+	}
+	return &out, warnings, nil
+}
+
+// envKey maps the name of an environment variable, without EnvPrefix, to a
+// dotted configuration key, or "" if it names none. Parts separated by _ match
+// schema keys regardless of case; the keys of free-form objects such as
+// variables match existing keys regardless of case, or are used verbatim.
+func envKey(values map[string]interface{}, name string) string {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		return ""
+	}
+	parts := strings.Split(name, "_")
+	var path []string
+	node := schema
+	var current interface{} = values
+	for i := 0; i < len(parts); i++ {
+		node = resolveSchemaRef(schema, node)
+		props, ok := node["properties"].(map[string]interface{})
+		if !ok {
+			if t, _ := node["type"].(string); t != "object" {
+				return ""
+			}
+			return strings.Join(append(path, freeFormKey(current, parts[i:])...), ".")
+		}
+		key := ""
+		for prop := range props {
+			if strings.EqualFold(prop, parts[i]) {
+				key = prop
+			}
+		}
+		if key == "" {
+			return ""
+		}
+		path = append(path, key)
+		node, _ = props[key].(map[string]interface{})
+		current = childValue(current, key)
+	}
+	if t, _ := resolveSchemaRef(schema, node)["type"].(string); t == "object" {
+		return "" // objects are set key by key
+	}
+	return strings.Join(path, ".")
+}
+
+// freeFormKey descends into existing objects while parts match their keys,
+// and joins the remaining parts with _ into the last key
+func freeFormKey(current interface{}, parts []string) []string {
+	var keys []string
+	for len(parts) > 1 {
+		key := matchKey(current, parts[0])
+		if _, ok := childValue(current, key).(map[string]interface{}); !ok {
+			break
+		}
+		keys = append(keys, key)
+		current = childValue(current, key)
+		parts = parts[1:]
+	}
+	return append(keys, matchKey(current, strings.Join(parts, "_")))
+}
+
+// matchKey returns the key of an object equal to name regardless of case, or
+// name itself
+func matchKey(current interface{}, name string) string {
+	obj, _ := current.(map[string]interface{})
+	if _, ok := obj[name]; ok {
+		return name
+	}
+	for key := range obj {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+func childValue(current interface{}, key string) interface{} {
+	obj, _ := current.(map[string]interface{})
+	return obj[key]
+}
+
+// SetConfigValue sets a dotted key in the generic values of a configuration,
+// creating intermediate objects. Strings need no quotes; values of other types
+// are parsed as JSON. The new value must pass validation against Schema.
+func SetConfigValue(values map[string]interface{}, key, raw string) error {
+	var value interface{} = raw
+	if SchemaType(key) != "string" {
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+	}
+
+	parts := strings.Split(key, ".")
+	obj := values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := obj[part].(map[string]interface{})
+		if !ok {
+			if _, exists := obj[part]; exists {
+				return fmt.Errorf("%s is not an object", part)
+			}
+			next = map[string]interface{}{}
+			obj[part] = next
+		}
+		obj = next
+	}
+	obj[parts[len(parts)-1]] = value
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	issues, err := ValidateConfig(data, "book.json")
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if issue.Path == key || strings.HasPrefix(issue.Path, key+".") || strings.HasPrefix(issue.Path, key+"[") {
+			return fmt.Errorf("invalid value for %s: %s", key, issue.Message)
+		}
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return nil
+}
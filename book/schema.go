@@ -0,0 +1,213 @@
+package book
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Schema is the JSON Schema of book.json
+//
+//go:embed book.schema.json
+var Schema []byte
+
+// ConfigIssue is a problem found while validating book.json against Schema
+type ConfigIssue struct {
+	File    string // configuration file, or environment variable without Line
+	Line    int    // 1-based
+	Column  int    // 1-based, in characters
+	Path    string
+	Message string
+}
+
+func (i ConfigIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	if i.Path != "" {
+		return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Path, i.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Line, i.Column, i.Message)
+}
+
+// ValidateConfig checks a configuration file against Schema: unknown keys,
+// wrong types, values outside enums and duplicate keys. The extension of file
+// selects JSON, YAML or TOML; malformed content is an error.
+func ValidateConfig(data []byte, file string) ([]ConfigIssue, error) {
+	root, err := parseConfigNode(data, file)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		return nil, fmt.Errorf("invalid built-in schema: %w", err)
+	}
+
+	v := &schemaValidator{file: filepath.Base(file), root: schema}
+	v.validate(root, schema, "")
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		a, b := v.issues[i], v.issues[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return v.issues, nil
+}
+
+// SchemaType returns the type the schema declares for a dotted key such as
+// "pdf.fontSize", or "" if the key is unknown or untyped
+func SchemaType(key string) string {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		return ""
+	}
+	node := schema
+	for _, part := range strings.Split(key, ".") {
+		node = resolveSchemaRef(schema, node)
+		props, _ := node["properties"].(map[string]interface{})
+		next, ok := props[part].(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		node = next
+	}
+	node = resolveSchemaRef(schema, node)
+	t, _ := node["type"].(string)
+	return t
+}
+
+// resolveSchemaRef follows a "#/$defs/name" reference
+func resolveSchemaRef(root, node map[string]interface{}) map[string]interface{} {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	defs, _ := root["$defs"].(map[string]interface{})
+	if def, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{}); ok {
+		return def
+	}
+	return node
+}
+
+type schemaValidator struct {
+	file   string
+	root   map[string]interface{}
+	issues []ConfigIssue
+}
+
+func (v *schemaValidator) report(pos position, path, format string, args ...interface{}) {
+	v.issues = append(v.issues, ConfigIssue{File: v.file, Line: pos.line, Column: pos.col, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(n *configNode, schema map[string]interface{}, path string) {
+	schema = resolveSchemaRef(v.root, schema)
+	if t, ok := schema["type"].(string); ok && !n.is(t) {
+		v.report(n.pos, path, "expected %s, got %s", article(t), article(n.kind()))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		var allowed []string
+		for _, e := range enum {
+			allowed = append(allowed, fmt.Sprint(e))
+			if s, ok := n.value.(string); ok && s == e {
+				found = true
+			}
+		}
+		if !found {
+			v.report(n.pos, path, "must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+	if min, ok := schema["minimum"].(float64); ok {
+		if num, ok := n.value.(json.Number); ok {
+			if f, err := num.Float64(); err == nil && f < min {
+				v.report(n.pos, path, "must be at least %v", min)
+			}
+		}
+	}
+
+	switch value := n.value.(type) {
+	case []configMember:
+		props, _ := schema["properties"].(map[string]interface{})
+		seen := make(map[string]bool)
+		for _, m := range value {
+			memberPath := m.key
+			if path != "" {
+				memberPath = path + "." + m.key
+			}
+			if seen[m.key] {
+				v.report(m.pos, memberPath, "duplicate key; the last value wins")
+			}
+			seen[m.key] = true
+
+			if prop, ok := props[m.key].(map[string]interface{}); ok {
+				v.validate(m.value, prop, memberPath)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					msg := fmt.Sprintf("unknown key %q", m.key)
+					if s := suggestKey(m.key, props); s != "" {
+						msg += fmt.Sprintf(" (did you mean %q?)", s)
+					}
+					v.report(m.pos, memberPath, "%s", msg)
+				}
+			case map[string]interface{}:
+				v.validate(m.value, extra, memberPath)
+			}
+		}
+	case []*configNode:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				v.validate(item, items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+// suggestKey returns the known key closest to a misspelled one
+func suggestKey(key string, props map[string]interface{}) string {
+	best, bestDist := "", 3
+	for name := range props {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist || d == bestDist && name < best {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func article(kind string) string {
+	switch kind {
+	case "object", "array", "integer":
+		return "an " + kind
+	case "null":
+		return kind
+	}
+	return "a " + kind
+}
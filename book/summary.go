@@ -0,0 +1,271 @@
+package book
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SaveSummary saves a summary as SUMMARY.md
+func SaveSummary(path string, summary *Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteSummary(f, summary); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteSummary writes the Markdown of a summary, see Summary.Render
+func WriteSummary(w io.Writer, summary *Summary) error {
+	if summary == nil {
+		summary = &Summary{}
+	}
+	_, err := io.WriteString(w, summary.Render())
+	return err
+}
+
+// Render serializes the summary in the format read by ParseSummary: "* " items
+// indented by two spaces per level, title-only chapters with an empty link,
+// parts as "## " headings and comments where they were read
+func (s *Summary) Render() string {
+	var sb strings.Builder
+	title := s.Title
+	if title == "" {
+		title = "Summary"
+	}
+	sb.WriteString("# " + title + "\n\n")
+	renderChapters(&sb, s.Chapters, 0)
+	for _, line := range s.Trailer {
+		if strings.HasPrefix(line, "#") {
+			sb.WriteString("\n" + line + "\n")
+		} else {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func renderChapters(sb *strings.Builder, chapters []Chapter, level int) {
+	indent := strings.Repeat("  ", level)
+	for _, ch := range chapters {
+		if ch.Part != "" && level == 0 {
+			if !strings.HasSuffix(sb.String(), "\n\n") {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("## " + ch.Part + "\n\n")
+		}
+		for _, line := range ch.Comments {
+			sb.WriteString(indent + line + "\n")
+		}
+		sb.WriteString(indent + "* [" + ch.Title + "](" + ch.Path + ")\n")
+		renderChapters(sb, ch.Articles, level+1)
+	}
+}
+
+// Index locates a chapter in a summary: its position in Summary.Chapters
+// followed by its position in the Articles of each level below, all zero-based
+type Index []int
+
+// String returns the index in the one-based dotted form of chapter numbers, e.g. "2.1"
+func (idx Index) String() string {
+	parts := make([]string, len(idx))
+	for i, n := range idx {
+		parts[i] = strconv.Itoa(n + 1)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ParseIndex parses a one-based dotted chapter number such as "2.1"
+func ParseIndex(s string) (Index, error) {
+	var idx Index
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid chapter number %q", s)
+		}
+		idx = append(idx, n-1)
+	}
+	return idx, nil
+}
+
+// Find returns the index of the first chapter with the given page path
+func (s *Summary) Find(path string) (Index, bool) {
+	return findChapter(s.Chapters, path, nil)
+}
+
+func findChapter(chapters []Chapter, path string, prefix Index) (Index, bool) {
+	for i, ch := range chapters {
+		idx := append(append(Index{}, prefix...), i)
+		if ch.Path != "" && ch.Path == path {
+			return idx, true
+		}
+		if found, ok := findChapter(ch.Articles, path, idx); ok {
+			return found, true
+		}
+	}
+	return nil, false
+}
+
+// Locate resolves a reference to a chapter, either a dotted chapter number
+// such as "2.1" or the path of its page
+func (s *Summary) Locate(ref string) (Index, error) {
+	if idx, err := ParseIndex(ref); err == nil {
+		if s.Chapter(idx) == nil {
+			return nil, fmt.Errorf("no chapter %s in the summary", ref)
+		}
+		return idx, nil
+	}
+	idx, ok := s.Find(ref)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the summary", ref)
+	}
+	return idx, nil
+}
+
+// Chapter returns the chapter at idx, or nil if there is none
+func (s *Summary) Chapter(idx Index) *Chapter {
+	list := s.list(idx)
+	if list == nil || len(idx) == 0 || idx[len(idx)-1] < 0 || idx[len(idx)-1] >= len(*list) {
+		return nil
+	}
+	return &(*list)[idx[len(idx)-1]]
+}
+
+// list returns the slice holding the chapter at idx: Summary.Chapters or the
+// Articles of its parent
+func (s *Summary) list(idx Index) *[]Chapter {
+	list := &s.Chapters
+	for i := 0; i < len(idx)-1; i++ {
+		if idx[i] < 0 || idx[i] >= len(*list) {
+			return nil
+		}
+		list = &(*list)[idx[i]].Articles
+	}
+	return list
+}
+
+// Insert inserts a chapter at position pos of the chapters under parent, an
+// empty parent being the top level; a negative pos appends
+func (s *Summary) Insert(parent Index, pos int, ch Chapter) error {
+	list := &s.Chapters
+	if len(parent) > 0 {
+		p := s.Chapter(parent)
+		if p == nil {
+			return fmt.Errorf("no chapter %s in the summary", parent)
+		}
+		list = &p.Articles
+	}
+	if pos < 0 || pos > len(*list) {
+		pos = len(*list)
+	}
+	*list = append(*list, Chapter{})
+	copy((*list)[pos+1:], (*list)[pos:])
+	(*list)[pos] = ch
+	return nil
+}
+
+// Remove removes the chapter at idx with its articles and returns it
+func (s *Summary) Remove(idx Index) (Chapter, error) {
+	ch := s.Chapter(idx)
+	if ch == nil {
+		return Chapter{}, fmt.Errorf("no chapter %s in the summary", idx)
+	}
+	removed := *ch
+	list := s.list(idx)
+	i := idx[len(idx)-1]
+	// The part heading stays where it was, opened by the next chapter or left
+	// empty at the end
+	if removed.Part != "" && len(idx) == 1 {
+		switch {
+		case i+1 == len(*list):
+			s.Trailer = append([]string{"## " + removed.Part}, s.Trailer...)
+			removed.Part = ""
+		case (*list)[i+1].Part == "":
+			(*list)[i+1].Part, removed.Part = removed.Part, ""
+		}
+	}
+	*list = append((*list)[:i], (*list)[i+1:]...)
+	return removed, nil
+}
+
+// Move moves the chapter at from, with its articles, to position pos of the
+// chapters under parent; positions refer to the summary before the move. Part
+// headings stay where they are: the chapter leaves its part behind, and a part
+// left without chapters at the end of the summary is kept empty.
+func (s *Summary) Move(from, parent Index, pos int) error {
+	if s.Chapter(from) == nil {
+		return fmt.Errorf("no chapter %s in the summary", from)
+	}
+	if len(parent) >= len(from) && equalIndex(parent[:len(from)], from) {
+		return fmt.Errorf("cannot move chapter %s under itself", from)
+	}
+	if len(parent) > 0 && s.Chapter(parent) == nil {
+		return fmt.Errorf("no chapter %s in the summary", parent)
+	}
+
+	// Removing the chapter shifts the positions after it in the same list
+	n := len(from) - 1
+	parent = append(Index{}, parent...)
+	if len(parent) > n && equalIndex(parent[:n], from[:n]) && parent[n] > from[n] {
+		parent[n]--
+	}
+	if equalIndex(parent, from[:n]) && pos > from[n] {
+		pos--
+	}
+
+	part := s.Chapter(from).Part
+	last := len(from) == 1 && from[0] == len(s.Chapters)-1
+	ch, err := s.Remove(from)
+	if err != nil {
+		return err
+	}
+	ch.Part = ""
+	if err := s.Insert(parent, pos, ch); err != nil {
+		return err
+	}
+	if len(parent) > 0 || part == "" {
+		return nil
+	}
+
+	// Moving the chapter back in front of its part, the one that took over the
+	// heading or the empty part at the end, leaves it opening the part
+	if pos < 0 || pos > len(s.Chapters)-1 {
+		pos = len(s.Chapters) - 1
+	}
+	switch {
+	case last && pos == len(s.Chapters)-1:
+		s.Trailer = s.Trailer[1:]
+		s.Chapters[pos].Part = part
+	case !last && pos+1 < len(s.Chapters) && s.Chapters[pos+1].Part == part:
+		s.Chapters[pos].Part, s.Chapters[pos+1].Part = part, ""
+	}
+	return nil
+}
+
+// Rename changes the title of the chapter at idx
+func (s *Summary) Rename(idx Index, title string) error {
+	ch := s.Chapter(idx)
+	if ch == nil {
+		return fmt.Errorf("no chapter %s in the summary", idx)
+	}
+	ch.Title = title
+	return nil
+}
+
+func equalIndex(a, b Index) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package book
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSummary = `# Summary
+
+* [Intro](README.md)
+
+## Basics
+
+* [One](one.md)
+  * [One A](one/a.md)
+* [Two](two.md)
+
+## Advanced
+
+<!-- hard -->
+* [Three](three.md)
+`
+
+func TestSummaryRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "canonical",
+			in:   testSummary,
+			want: testSummary,
+		},
+		{
+			name: "comments",
+			in:   "# Contents\n\n<!-- first -->\n* [A](a.md)\n  <!-- nested\n  over two lines -->\n  * [B](b.md)\n<!-- the end -->\n",
+			want: "# Contents\n\n<!-- first -->\n* [A](a.md)\n  <!-- nested\n  over two lines -->\n  * [B](b.md)\n<!-- the end -->\n",
+		},
+		{
+			name: "parts and loose formatting",
+			in:   "# Summary\n* [A](a.md)\n### Part One\n* [B](b.md)\n  * [C](c.md)\n* [Draft]()\n",
+			want: "# Summary\n\n* [A](a.md)\n\n## Part One\n\n* [B](b.md)\n  * [C](c.md)\n* [Draft]()\n",
+		},
+		{
+			name: "trailing empty part",
+			in:   "# Summary\n\n* [A](a.md)\n\n## Later\n",
+			want: "# Summary\n\n* [A](a.md)\n\n## Later\n",
+		},
+		{
+			name: "no title",
+			in:   "* [A](a.md)\n",
+			want: "# Summary\n\n* [A](a.md)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := parseTestSummary(t, tt.in).Render()
+			if first != tt.want {
+				t.Errorf("Render =\n%s\nwant\n%s", first, tt.want)
+			}
+			if second := parseTestSummary(t, first).Render(); second != first {
+				t.Errorf("rendering again changed the summary:\n%s\nwas\n%s", second, first)
+			}
+		})
+	}
+}
+
+func TestSummaryEdits(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(s *Summary) error
+		want string
+	}{
+		{
+			name: "insert at the top",
+			edit: func(s *Summary) error { return s.Insert(nil, 0, Chapter{Title: "Preface", Path: "preface.md"}) },
+			want: "# Summary\n\n* [Preface](preface.md)\n* [Intro](README.md)\n\n## Basics\n\n* [One](one.md)\n  * [One A](one/a.md)\n* [Two](two.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n",
+		},
+		{
+			name: "append an article",
+			edit: func(s *Summary) error { return s.Insert(Index{1}, -1, Chapter{Title: "One B", Path: "one/b.md"}) },
+			want: "# Summary\n\n* [Intro](README.md)\n\n## Basics\n\n* [One](one.md)\n  * [One A](one/a.md)\n  * [One B](one/b.md)\n* [Two](two.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n",
+		},
+		{
+			name: "remove a part opener",
+			edit: func(s *Summary) error { _, err := s.Remove(Index{1}); return err },
+			want: "# Summary\n\n* [Intro](README.md)\n\n## Basics\n\n* [Two](two.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n",
+		},
+		{
+			name: "remove the last chapter opening a part",
+			edit: func(s *Summary) error { _, err := s.Remove(Index{3}); return err },
+			want: "# Summary\n\n* [Intro](README.md)\n\n## Basics\n\n* [One](one.md)\n  * [One A](one/a.md)\n* [Two](two.md)\n\n## Advanced\n",
+		},
+		{
+			name: "rename an article",
+			edit: func(s *Summary) error { return s.Rename(Index{1, 0}, "First") },
+			want: "# Summary\n\n* [Intro](README.md)\n\n## Basics\n\n* [One](one.md)\n  * [First](one/a.md)\n* [Two](two.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n",
+		},
+		{
+			name: "move a part opener to the end",
+			edit: func(s *Summary) error { return s.Move(Index{1}, nil, -1) },
+			want: "# Summary\n\n* [Intro](README.md)\n\n## Basics\n\n* [Two](two.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n* [One](one.md)\n  * [One A](one/a.md)\n",
+		},
+		{
+			name: "move a part opener onto itself",
+			edit: func(s *Summary) error { return s.Move(Index{1}, nil, 1) },
+			want: testSummary,
+		},
+		{
+			name: "move the last chapter of a part up",
+			edit: func(s *Summary) error { return s.Move(Index{3}, nil, 1) },
+			want: "# Summary\n\n* [Intro](README.md)\n<!-- hard -->\n* [Three](three.md)\n\n## Basics\n\n* [One](one.md)\n  * [One A](one/a.md)\n* [Two](two.md)\n\n## Advanced\n",
+		},
+		{
+			name: "move the last chapter of a part under a chapter",
+			edit: func(s *Summary) error { return s.Move(Index{3}, Index{0}, 0) },
+			want: "# Summary\n\n* [Intro](README.md)\n  <!-- hard -->\n  * [Three](three.md)\n\n## Basics\n\n* [One](one.md)\n  * [One A](one/a.md)\n* [Two](two.md)\n\n## Advanced\n",
+		},
+		{
+			name: "move the last chapter onto itself",
+			edit: func(s *Summary) error { return s.Move(Index{3}, nil, -1) },
+			want: testSummary,
+		},
+		{
+			name: "move an article to the top level",
+			edit: func(s *Summary) error { return s.Move(Index{1, 0}, nil, 3) },
+			want: "# Summary\n\n* [Intro](README.md)\n\n## Basics\n\n* [One](one.md)\n* [Two](two.md)\n* [One A](one/a.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n",
+		},
+		{
+			name: "move a chapter down within its list",
+			edit: func(s *Summary) error { return s.Move(Index{0}, nil, 3) },
+			want: "# Summary\n\n## Basics\n\n* [One](one.md)\n  * [One A](one/a.md)\n* [Two](two.md)\n* [Intro](README.md)\n\n## Advanced\n\n<!-- hard -->\n* [Three](three.md)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseTestSummary(t, testSummary)
+			if err := tt.edit(s); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Render(); got != tt.want {
+				t.Errorf("Render =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryEditErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(s *Summary) error
+		want string
+	}{
+		{"insert under a missing chapter", func(s *Summary) error { return s.Insert(Index{9}, 0, Chapter{Title: "X"}) }, "no chapter 10"},
+		{"remove a missing chapter", func(s *Summary) error { _, err := s.Remove(Index{1, 5}); return err }, "no chapter 2.6"},
+		{"rename a missing chapter", func(s *Summary) error { return s.Rename(Index{4}, "X") }, "no chapter 5"},
+		{"move under itself", func(s *Summary) error { return s.Move(Index{1}, Index{1, 0}, 0) }, "under itself"},
+	}
+	for _, tt := range tests {
+		err := tt.edit(parseTestSummary(t, testSummary))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func parseTestSummary(t *testing.T, content string) *Summary {
+	t.Helper()
+	s, err := ParseSummary(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,285 @@
+package book
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// parseTOMLNode parses a TOML document. Dates and times have no JSON
+// equivalent and are read as strings.
+func parseTOMLNode(data []byte) (*configNode, error) {
+	var doc map[string]interface{}
+	if err := decodeTOML(data, &doc); err != nil {
+		return nil, err
+	}
+	n, err := tomlNode(doc, nil, tomlPositions(data))
+	if err != nil {
+		return nil, err
+	}
+	n.pos = position{1, 1}
+	return n, nil
+}
+
+func decodeTOML(data []byte, v interface{}) error {
+	err := toml.Unmarshal(data, v)
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, _ := decodeErr.Position()
+		return fmt.Errorf("line %d: %w", line, err)
+	}
+	return err
+}
+
+// tomlPosition is where the key and the value of a TOML entry start
+type tomlPosition struct {
+	key, value position
+}
+
+// tomlPositions locates the entries of a TOML document, by their path joined
+// with NUL. The elements of arrays, including arrays of tables, are numbered
+// from 0.
+func tomlPositions(data []byte) map[string]tomlPosition {
+	w := &tomlWalker{
+		data:      data,
+		lines:     newLineIndex(data),
+		positions: make(map[string]tomlPosition),
+		counts:    make(map[string]int),
+	}
+	w.parser.Reset(data)
+	var table []string
+	for w.parser.NextExpression() {
+		e := w.parser.Expression()
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = nil
+			it := e.Key()
+			for it.Next() {
+				key := it.Node()
+				table = append(table, string(key.Data))
+				pos := w.position(key)
+				w.record(table, pos, pos)
+				path := strings.Join(table, "\x00")
+				if e.Kind == unstable.ArrayTable && !key.Next().Valid() {
+					w.counts[path]++
+				}
+				// Headers descend into the last table of an array of tables
+				if n, ok := w.counts[path]; ok {
+					table = append(table, strconv.Itoa(n-1))
+					w.record(table, pos, pos)
+				}
+			}
+		case unstable.KeyValue:
+			w.keyValue(e, table)
+		}
+	}
+	return w.positions
+}
+
+type tomlWalker struct {
+	parser    unstable.Parser
+	data      []byte
+	lines     *lineIndex
+	positions map[string]tomlPosition
+	counts    map[string]int // tables of each array of tables
+}
+
+// record keeps the first position of an entry, where a table is defined
+func (w *tomlWalker) record(path []string, key, value position) {
+	p := strings.Join(path, "\x00")
+	if _, ok := w.positions[p]; !ok {
+		w.positions[p] = tomlPosition{key: key, value: value}
+	}
+}
+
+func (w *tomlWalker) position(n *unstable.Node) position {
+	return w.lines.position(int(n.Raw.Offset))
+}
+
+func (w *tomlWalker) keyValue(e *unstable.Node, table []string) {
+	path := table[:len(table):len(table)]
+	var key *unstable.Node
+	it := e.Key()
+	for it.Next() {
+		key = it.Node()
+		path = append(path, string(key.Data))
+		if key.Next().Valid() { // dotted keys define tables
+			w.record(path, w.position(key), w.position(key))
+		}
+	}
+	// Not every value node has a range, so the value is found after the =
+	off := int(key.Raw.Offset + key.Raw.Length)
+	for off < len(w.data) && strings.IndexByte(" \t=", w.data[off]) >= 0 {
+		off++
+	}
+	valuePos := w.lines.position(off)
+	w.record(path, w.position(key), valuePos)
+	w.value(e.Value(), path, valuePos)
+}
+
+func (w *tomlWalker) value(v *unstable.Node, path []string, pos position) {
+	switch v.Kind {
+	case unstable.InlineTable:
+		it := v.Children()
+		for it.Next() {
+			w.keyValue(it.Node(), path)
+		}
+	case unstable.Array:
+		it := v.Children()
+		for i := 0; it.Next(); i++ {
+			item := it.Node()
+			itemPos := pos
+			if item.Raw.Length > 0 {
+				itemPos = w.position(item)
+			}
+			itemPath := append(path[:len(path):len(path)], strconv.Itoa(i))
+			w.record(itemPath, itemPos, itemPos)
+			w.value(item, itemPath, itemPos)
+		}
+	}
+}
+
+// tomlNode converts a decoded TOML value to a node, ordering the members of
+// tables as in the document
+func tomlNode(v interface{}, path []string, positions map[string]tomlPosition) (*configNode, error) {
+	n := &configNode{pos: positions[strings.Join(path, "\x00")].value}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		members := []configMember{}
+		for key, item := range v {
+			memberPath := append(path[:len(path):len(path)], key)
+			child, err := tomlNode(item, memberPath, positions)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, configMember{key: key, pos: positions[strings.Join(memberPath, "\x00")].key, value: child})
+		}
+		sort.Slice(members, func(i, j int) bool {
+			a, b := members[i].pos, members[j].pos
+			if a != b {
+				return a.line < b.line || a.line == b.line && a.col < b.col
+			}
+			return members[i].key < members[j].key
+		})
+		n.value = members
+	case []interface{}:
+		items := []*configNode{}
+		for i, item := range v {
+			child, err := tomlNode(item, append(path[:len(path):len(path)], strconv.Itoa(i)), positions)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, child)
+		}
+		n.value = items
+	case int64:
+		n.value = json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("line %d: %v is not a finite number", n.pos.line, v)
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		n.value = json.Number(s)
+	case time.Time:
+		n.value = v.Format(time.RFC3339Nano)
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		n.value = fmt.Sprint(v)
+	default:
+		n.value = v
+	}
+	return n, nil
+}
+
+// editTOML sets a dotted key of a TOML document to value. The document is
+// decoded and encoded again, which keeps dates but not comments, and sorts
+// the keys.
+func editTOML(data []byte, parts []string, value *configNode) ([]byte, error) {
+	doc := map[string]interface{}{}
+	if err := decodeTOML(data, &doc); err != nil {
+		return nil, err
+	}
+	v, err := tomlValue(value)
+	if err != nil {
+		return nil, err
+	}
+	table := doc
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := table[part]
+		if !ok {
+			child = map[string]interface{}{}
+			table[part] = child
+		}
+		if table, ok = child.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%s is not an object", part)
+		}
+	}
+	if v == nil {
+		delete(table, parts[len(parts)-1])
+	} else {
+		table[parts[len(parts)-1]] = v
+	}
+	return toml.Marshal(doc)
+}
+
+// encodeTOML writes an object node as a TOML document. TOML has no null, so
+// null members are left out.
+func encodeTOML(n *configNode) ([]byte, error) {
+	if n.kind() != "object" {
+		return nil, fmt.Errorf("the configuration must be an object")
+	}
+	v, err := tomlValue(n)
+	if err != nil {
+		return nil, err
+	}
+	return toml.Marshal(v)
+}
+
+// tomlValue converts a node to the values the TOML encoder takes, nil for
+// null
+func tomlValue(n *configNode) (interface{}, error) {
+	switch v := n.value.(type) {
+	case []configMember:
+		table := make(map[string]interface{}, len(v))
+		for _, m := range v {
+			value, err := tomlValue(m.value)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				table[m.key] = value
+			}
+		}
+		return table, nil
+	case []*configNode:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := tomlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				return nil, fmt.Errorf("null cannot be written as TOML")
+			}
+			items[i] = value
+		}
+		return items, nil
+	case json.Number:
+		if n.kind() == "integer" {
+			return v.Int64()
+		}
+		return v.Float64()
+	default:
+		return v, nil
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hitzhangjie/gitbook/book"
 	"github.com/yuin/goldmark"
@@ -21,8 +24,12 @@ import (
 type Builder struct {
 	Book      *book.Book
 	OutputDir string
+	LocalEdit bool // render "Edit this page" links for the in-browser editor of gitbook serve
 	template  *template.Template
 	md        goldmark.Markdown
+	source    goldmark.Markdown // parser of ParseChapter, see NewBuilder
+	pages     []generatedPage   // pages written by the current build, used for the sitemap
+	git       *gitHistory       // history snapshot of the current build, nil outside a repository
 }
 
 // NavItem represents a navigation item
@@ -32,9 +39,16 @@ type NavItem struct {
 	URL      string
 	Active   bool
 	Level    int
+	Number   string // hierarchical number (e.g. "1.2.3"), empty unless numbering is enabled
 	Children []NavItem
 }
 
+// Breadcrumb represents an ancestor of the current page in the summary
+type Breadcrumb struct {
+	Title string
+	URL   string
+}
+
 // TOCItem represents a table of contents item
 type TOCItem struct {
 	Title    string
@@ -47,10 +61,33 @@ type TOCItem struct {
 type PageData struct {
 	Title       string
 	BookTitle   string
+	Number      string
 	Content     template.HTML
 	NavTree     []NavItem
 	TOC         []TOCItem
+	Breadcrumbs []Breadcrumb
 	CurrentPath string
+	Meta        PageMeta
+
+	// Git metadata of the page source, empty outside a repository
+	LastModified time.Time
+	Authors      []string // most recent first
+	CommitHash   string
+	ShortHash    string // abbreviated CommitHash, as shown on the page
+
+	// Source links of the page
+	SourcePath string // markdown source relative to the book root
+	EditURL    string
+	SourceURL  string
+	LocalEdit  bool
+}
+
+// PageMeta holds the SEO and social metadata rendered into the page head
+type PageMeta struct {
+	Description  string
+	CanonicalURL string
+	Image        string
+	Author       string
 }
 
 //go:embed templates/page.html
@@ -60,8 +97,8 @@ var pageTemplate embed.FS
 var staticFiles embed.FS
 
 // NewBuilder creates a new builder
-func NewBuilder(bookRoot, outputDir string) (*Builder, error) {
-	b, err := book.LoadBook(bookRoot)
+func NewBuilder(bookRoot, outputDir string, opts ...book.LoadOption) (*Builder, error) {
+	b, err := book.LoadBook(bookRoot, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -93,11 +130,21 @@ func NewBuilder(bookRoot, outputDir string) (*Builder, error) {
 		),
 	)
 
+	// Exports working on the syntax tree also get footnotes and TeX math,
+	// which the website has no renderer for
+	source := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote, mathExtension{}),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+	)
+
 	return &Builder{
 		Book:      b,
 		OutputDir: absOutput,
 		template:  tmpl,
 		md:        md,
+		source:    source,
 	}, nil
 }
 
@@ -111,6 +158,22 @@ func (b *Builder) Build() error {
 	if err := os.MkdirAll(b.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
+	b.pages = nil
+	b.git = loadGitHistory(b.Book.Root)
+
+	// Ignore files may have changed since the last build
+	if err := b.Book.ReadIgnore(); err != nil {
+		return fmt.Errorf("failed to read ignore files: %w", err)
+	}
+
+	// A derived summary follows pages added or removed since the last build
+	if b.Book.Config.AutoSummary() {
+		summary, err := book.GenerateSummary(b.Book.Root, b.Book.Ignore)
+		if err != nil {
+			return fmt.Errorf("failed to generate summary: %w", err)
+		}
+		b.Book.Summary = summary
+	}
 
 	// Copy static assets
 	if err := b.copyAssets(); err != nil {
@@ -132,11 +195,16 @@ func (b *Builder) Build() error {
 		return fmt.Errorf("failed to generate index: %w", err)
 	}
 
+	// Generate sitemap.xml and robots.txt
+	if err := b.generateSitemap(); err != nil {
+		return fmt.Errorf("failed to generate sitemap: %w", err)
+	}
+
 	return nil
 }
 
 func (b *Builder) copyAssets() error {
-	// Walk the book root directory and copy all non-markdown files
+	// Walk the content root and copy all non-markdown files
 	// while preserving the directory structure
 	return filepath.Walk(b.Book.Root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -147,9 +215,17 @@ func (b *Builder) copyAssets() error {
 		if info.IsDir() {
 			// Skip output directory and common hidden/system directories
 			base := filepath.Base(path)
-			if base == "_book" || base == ".git" || base == ".gitbook" || strings.HasPrefix(base, ".") {
+			if path == b.OutputDir || base == "_book" || base == ".git" || base == ".gitbook" || strings.HasPrefix(base, ".") {
 				return filepath.SkipDir
 			}
+			if rel, err := filepath.Rel(b.Book.Root, path); err == nil && b.Book.Ignore.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Never copy files from outside the content root through symbolic links
+		if info.Mode()&os.ModeSymlink != 0 && !book.Within(b.Book.Root, path) {
 			return nil
 		}
 
@@ -165,6 +241,11 @@ func (b *Builder) copyAssets() error {
 			return err
 		}
 
+		// Skip paths matched by .bookignore
+		if b.Book.Ignore.Match(relPath, false) {
+			return nil
+		}
+
 		// Build destination path
 		dstPath := filepath.Join(b.OutputDir, relPath)
 
@@ -237,27 +318,37 @@ func (b *Builder) generatePages() error {
 	navTree := b.buildNavTree(b.Book.Summary.Chapters, "")
 
 	// Generate all pages
-	return b.generateChapters(b.Book.Summary.Chapters, "", navTree, "")
+	return b.generateChapters(b.Book.Summary.Chapters, navTree, nil, "")
 }
 
-func (b *Builder) generateChapters(chapters []book.Chapter, basePath string, navTree []NavItem, currentPath string) error {
-	for _, chapter := range chapters {
+// generateChapters renders chapters recursively. parents holds the ancestry of
+// the chapters being rendered and numberPrefix their parent's number (e.g. "1.2.").
+func (b *Builder) generateChapters(chapters []book.Chapter, navTree []NavItem, parents []Breadcrumb, numberPrefix string) error {
+	for i, chapter := range chapters {
+		number := numberPrefix + strconv.Itoa(i+1)
 		if chapter.Path == "" {
+			// Title-only entries only group their articles
+			crumbs := append(parents[:len(parents):len(parents)], Breadcrumb{Title: chapter.Title})
+			if err := b.generateChapters(chapter.Articles, navTree, crumbs, number+"."); err != nil {
+				return err
+			}
 			continue
 		}
 
-		// Read markdown file
+		pageNumber := ""
+		if b.numbering() {
+			pageNumber = number
+		}
+
+		// Read and render markdown file
 		mdPath := filepath.Join(b.Book.Root, chapter.Path)
-		content, err := os.ReadFile(mdPath)
-		if err != nil {
+		html, frontMatter, err := b.renderChapter(mdPath, pageNumber)
+		if os.IsNotExist(err) {
 			// Skip if file doesn't exist
 			continue
 		}
-
-		// Convert markdown to HTML
-		html, err := b.markdownToHTML(string(content))
 		if err != nil {
-			return fmt.Errorf("failed to convert markdown: %w", err)
+			return fmt.Errorf("%s: %w", chapter.Path, err)
 		}
 
 		// Extract TOC from HTML to ensure IDs match exactly with goldmark's generated IDs
@@ -279,19 +370,19 @@ func (b *Builder) generateChapters(chapters []book.Chapter, basePath string, nav
 		activeNavTree := b.markActiveNavItem(navTree, relPath)
 
 		// Generate full HTML page
-		bookTitle := "GitBook"
-		if b.Book.Config != nil && b.Book.Config.Title != "" {
-			bookTitle = b.Book.Config.Title
-		}
-
 		pageData := PageData{
 			Title:       chapter.Title,
-			BookTitle:   bookTitle,
+			BookTitle:   b.bookTitle(),
+			Number:      pageNumber,
 			Content:     template.HTML(html),
 			NavTree:     activeNavTree,
 			TOC:         toc,
+			Breadcrumbs: parents,
 			CurrentPath: relPath,
+			Meta:        b.pageMeta(frontMatter, relPath),
 		}
+		b.setGitInfo(&pageData, mdPath)
+		b.setEditLinks(&pageData, mdPath)
 
 		fullHTML, err := b.renderTemplate(pageData)
 		if err != nil {
@@ -301,11 +392,15 @@ func (b *Builder) generateChapters(chapters []book.Chapter, basePath string, nav
 		if err := os.WriteFile(outputPath, []byte(fullHTML), 0644); err != nil {
 			return err
 		}
+		b.pages = append(b.pages, generatedPage{URL: relPath, Source: mdPath})
 
-		// Recursively generate sub-chapters
-		// basePath is no longer needed since chapter.Path is already a full relative path
+		// Recursively generate sub-chapters with this chapter appended to their ancestry
 		if len(chapter.Articles) > 0 {
-			if err := b.generateChapters(chapter.Articles, "", navTree, relPath); err != nil {
+			crumbs := append(parents[:len(parents):len(parents)], Breadcrumb{
+				Title: chapter.Title,
+				URL:   "/" + strings.ReplaceAll(htmlPath, "\\", "/"),
+			})
+			if err := b.generateChapters(chapter.Articles, navTree, crumbs, number+"."); err != nil {
 				return err
 			}
 		}
@@ -316,15 +411,18 @@ func (b *Builder) generateChapters(chapters []book.Chapter, basePath string, nav
 
 func (b *Builder) generateIndex() error {
 	// Generate main index.html
-	readmePath := filepath.Join(b.Book.Root, "README.md")
-	if b.Book.Config != nil && b.Book.Config.Structure != nil && b.Book.Config.Structure.Readme != "" {
-		readmePath = filepath.Join(b.Book.Root, b.Book.Config.Structure.Readme)
-	}
+	readmePath := b.Book.Readme
 
 	var content template.HTML
 	var toc []TOCItem
+	frontMatter := book.FrontMatter{}
 	if data, err := os.ReadFile(readmePath); err == nil {
-		html, err := b.markdownToHTML(string(data))
+		fm, body, err := book.ParseFrontMatter(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(readmePath), err)
+		}
+		frontMatter = fm
+		html, err := b.markdownToHTML(string(body))
 		if err == nil {
 			content = template.HTML(html)
 			toc = b.extractTOCFromHTML(html)
@@ -332,10 +430,6 @@ func (b *Builder) generateIndex() error {
 	}
 
 	title := "Introduction"
-	bookTitle := "GitBook"
-	if b.Book.Config != nil && b.Book.Config.Title != "" {
-		bookTitle = b.Book.Config.Title
-	}
 
 	// Build navigation tree
 	var navTree []NavItem
@@ -346,19 +440,51 @@ func (b *Builder) generateIndex() error {
 
 	pageData := PageData{
 		Title:       title,
-		BookTitle:   bookTitle,
+		BookTitle:   b.bookTitle(),
 		Content:     content,
 		NavTree:     navTree,
 		TOC:         toc,
 		CurrentPath: "index.html",
+		Meta:        b.pageMeta(frontMatter, ""),
 	}
+	b.setGitInfo(&pageData, readmePath)
+	b.setEditLinks(&pageData, readmePath)
 
 	fullHTML, err := b.renderTemplate(pageData)
 	if err != nil {
 		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	return os.WriteFile(filepath.Join(b.OutputDir, "index.html"), []byte(fullHTML), 0644)
+	if err := os.WriteFile(filepath.Join(b.OutputDir, "index.html"), []byte(fullHTML), 0644); err != nil {
+		return err
+	}
+	b.pages = append(b.pages, generatedPage{URL: "", Source: readmePath})
+	return nil
+}
+
+// renderChapter renders a markdown source to an HTML fragment, prefixing its
+// first heading with number when it is not empty
+func (b *Builder) renderChapter(mdPath, number string) (string, book.FrontMatter, error) {
+	content, err := os.ReadFile(mdPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	frontMatter, body, err := book.ParseFrontMatter(content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Convert markdown to HTML
+	html, err := b.markdownToHTML(string(body))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to convert markdown: %w", err)
+	}
+
+	if number != "" {
+		html = numberFirstHeading(html, number)
+	}
+	return html, frontMatter, nil
 }
 
 func (b *Builder) markdownToHTML(md string) (string, error) {
@@ -378,17 +504,21 @@ func (b *Builder) renderTemplate(data PageData) (string, error) {
 }
 
 func (b *Builder) buildNavTree(chapters []book.Chapter, basePath string) []NavItem {
-	return b.buildNavTreeWithLevel(chapters, basePath, 1)
+	return b.buildNavTreeWithLevel(chapters, basePath, 1, "")
 }
 
-func (b *Builder) buildNavTreeWithLevel(chapters []book.Chapter, basePath string, level int) []NavItem {
+func (b *Builder) buildNavTreeWithLevel(chapters []book.Chapter, basePath string, level int, numberPrefix string) []NavItem {
 	var items []NavItem
-	for _, chapter := range chapters {
+	for i, chapter := range chapters {
+		number := numberPrefix + strconv.Itoa(i+1)
 		item := NavItem{
 			Title: chapter.Title,
 			Path:  chapter.Path,
 			Level: level,
 		}
+		if b.numbering() {
+			item.Number = number
+		}
 
 		if chapter.Path != "" {
 			// chapter.Path is already a relative path from book root (e.g., "4-basics/README.md")
@@ -400,7 +530,7 @@ func (b *Builder) buildNavTreeWithLevel(chapters []book.Chapter, basePath string
 
 		if len(chapter.Articles) > 0 {
 			// basePath is no longer needed since chapter.Path is already a full relative path
-			item.Children = b.buildNavTreeWithLevel(chapter.Articles, "", level+1)
+			item.Children = b.buildNavTreeWithLevel(chapter.Articles, "", level+1, number+".")
 		}
 
 		items = append(items, item)
@@ -429,6 +559,100 @@ func (b *Builder) markActiveNavItem(navTree []NavItem, currentPath string) []Nav
 	return result
 }
 
+// setGitInfo fills the git metadata of a page, falling back to the file's
+// modification time when the source has no history
+func (b *Builder) setGitInfo(data *PageData, source string) {
+	if info := b.git.lookup(source); info != nil {
+		data.LastModified = info.LastModified
+		data.Authors = info.Authors
+		data.CommitHash = info.CommitHash
+		data.ShortHash = info.CommitHash
+		if len(data.ShortHash) > 7 {
+			data.ShortHash = data.ShortHash[:7]
+		}
+		return
+	}
+	if fi, err := os.Stat(source); err == nil {
+		data.LastModified = fi.ModTime()
+	}
+}
+
+// setEditLinks fills the edit and view source links of a page. In local edit mode
+// the edit link opens the in-browser editor instead of the configured URL.
+func (b *Builder) setEditLinks(data *PageData, source string) {
+	rel, err := filepath.Rel(b.Book.Root, source)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	data.SourcePath = filepath.ToSlash(rel)
+
+	if b.LocalEdit {
+		data.LocalEdit = true
+		data.EditURL = "?edit=" + url.QueryEscape(data.SourcePath)
+	}
+
+	cfg := b.Book.Config
+	if cfg == nil || cfg.EditLink == nil {
+		return
+	}
+
+	// Paths in URL templates are relative to the repository root when there is one
+	repoPath := b.git.relPath(source)
+	if repoPath == "" {
+		repoPath = data.SourcePath
+	}
+	branch := cfg.EditLink.Branch
+	if branch == "" && b.git != nil {
+		branch = b.git.branch
+	}
+	if branch == "" {
+		branch = "main"
+	}
+	expand := strings.NewReplacer("{path}", repoPath, "{branch}", branch).Replace
+
+	if cfg.EditLink.URL != "" && !b.LocalEdit {
+		data.EditURL = expand(cfg.EditLink.URL)
+	}
+	sourceURL := cfg.EditLink.SourceURL
+	if sourceURL == "" && strings.Contains(cfg.EditLink.URL, "/edit/") {
+		sourceURL = strings.Replace(cfg.EditLink.URL, "/edit/", "/blob/", 1)
+	}
+	if sourceURL != "" {
+		data.SourceURL = expand(sourceURL)
+	}
+}
+
+// bookTitle returns the configured book title, defaulting to "GitBook"
+func (b *Builder) bookTitle() string {
+	if b.Book.Config != nil && b.Book.Config.Title != "" {
+		return b.Book.Config.Title
+	}
+	return "GitBook"
+}
+
+// language returns the configured book language, if any
+func (b *Builder) language() string {
+	if b.Book.Config != nil {
+		return b.Book.Config.Language
+	}
+	return ""
+}
+
+// numbering reports whether hierarchical chapter numbering is enabled
+func (b *Builder) numbering() bool {
+	return b.Book.Config != nil && b.Book.Config.Numbering
+}
+
+// numberFirstHeading prefixes the first <h1> of a rendered page with its chapter number,
+// so the number also shows up in the TOC and in ebooks converted from the HTML
+func numberFirstHeading(html, number string) string {
+	loc := regexp.MustCompile(`<h1[^>]*>`).FindStringIndex(html)
+	if loc == nil {
+		return html
+	}
+	return html[:loc[1]] + `<span class="heading-number">` + number + `</span> ` + html[loc[1]:]
+}
+
 // extractTOCFromHTML extracts TOC from HTML, ensuring IDs match exactly with goldmark's generated IDs
 func (b *Builder) extractTOCFromHTML(html string) []TOCItem {
 	var toc []TOCItem
@@ -0,0 +1,272 @@
+package builder
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// buildBook builds the book written from files and returns its output directory
+func buildBook(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, files)
+	b, err := NewBuilder(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Build(); err != nil {
+		t.Fatal(err)
+	}
+	return b.OutputDir
+}
+
+func readOutput(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBuildAutoSummaryDirectories(t *testing.T) {
+	out := buildBook(t, map[string]string{
+		"book.json":    `{"title": "API", "summary": {"auto": true}}`,
+		"README.md":    "# API\n",
+		"api/alpha.md": "# Alpha\n",
+		"api/beta.md":  "# Beta\n",
+	})
+
+	// api has no README, so it is a title-only chapter whose pages are still built
+	for _, page := range []string{"api/alpha.html", "api/beta.html"} {
+		html := readOutput(t, out, page)
+		if !strings.Contains(html, `<span class="breadcrumb-title">Api</span>`) {
+			t.Errorf("%s: breadcrumbs miss the title-only chapter:\n%s", page, html)
+		}
+	}
+}
+
+func TestMathOnlyParsedForExports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":  "# Intro\n",
+		"SUMMARY.md": "# Summary\n\n* [Euler](euler.md)\n",
+		"euler.md":   "# Euler\n\n$e^{i\\pi} = -1$\n",
+	})
+	b, err := NewBuilder(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The website has no math typesetting, so the TeX stays text
+	if html := readOutput(t, b.OutputDir, "euler.html"); strings.Contains(html, `\(`) {
+		t.Errorf("euler.html has math delimiters:\n%s", html)
+	}
+	doc, _, err := b.ParseChapter("euler.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	math := doc.LastChild().FirstChild()
+	if _, ok := math.(*Math); !ok {
+		t.Errorf("ParseChapter gave a %s, want Math", math.Kind())
+	}
+}
+
+func TestSetGitInfoShortHash(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Builder{git: &gitHistory{topLevel: root, files: map[string]*GitInfo{
+		"long.md":  {CommitHash: "0123456789abcdef"},
+		"short.md": {CommitHash: "abc"},
+	}}}
+
+	tests := []struct{ file, want string }{
+		{"long.md", "0123456"},
+		{"short.md", "abc"},
+		{"untracked.md", ""},
+	}
+	for _, tt := range tests {
+		var data PageData
+		b.setGitInfo(&data, filepath.Join(root, tt.file))
+		if data.ShortHash != tt.want {
+			t.Errorf("%s: ShortHash = %q, want %q", tt.file, data.ShortHash, tt.want)
+		}
+	}
+}
+
+func TestBuildSitemap(t *testing.T) {
+	files := map[string]string{
+		"book.json":    `{"title": "Guide", "siteUrl": "https://example.com/docs/"}`,
+		"README.md":    "# Intro\n",
+		"guide/one.md": "---\ndescription: All about one\n---\n# One\n",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [One](guide/one.md)\n",
+	}
+	out := buildBook(t, files)
+
+	var urlSet sitemapURLSet
+	if err := xml.Unmarshal([]byte(readOutput(t, out, "sitemap.xml")), &urlSet); err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	for _, u := range urlSet.URLs {
+		if u.LastMod == "" {
+			t.Errorf("%s has no lastmod", u.Loc)
+		}
+		locs = append(locs, u.Loc)
+	}
+	sort.Strings(locs)
+	want := []string{
+		"https://example.com/docs/",
+		"https://example.com/docs/README.html",
+		"https://example.com/docs/guide/one.html",
+	}
+	if !reflect.DeepEqual(locs, want) {
+		t.Errorf("sitemap locations = %q, want %q", locs, want)
+	}
+
+	if robots := readOutput(t, out, "robots.txt"); !strings.Contains(robots, "Sitemap: https://example.com/docs/sitemap.xml\n") {
+		t.Errorf("robots.txt does not point at the sitemap:\n%s", robots)
+	}
+	html := readOutput(t, out, "guide/one.html")
+	for _, tag := range []string{
+		`<link rel="canonical" href="https://example.com/docs/guide/one.html">`,
+		`<meta property="og:description" content="All about one">`,
+		`<meta name="twitter:description" content="All about one">`,
+	} {
+		if !strings.Contains(html, tag) {
+			t.Errorf("guide/one.html lacks %s", tag)
+		}
+	}
+
+	// A robots.txt of the book is kept, and without a site URL there is no sitemap
+	files["robots.txt"] = "User-agent: *\nDisallow: /\n"
+	files["book.json"] = `{"title": "Guide"}`
+	out = buildBook(t, files)
+	if _, err := os.Stat(filepath.Join(out, "sitemap.xml")); !os.IsNotExist(err) {
+		t.Errorf("sitemap.xml written without a site URL: %v", err)
+	}
+	if robots := readOutput(t, out, "robots.txt"); robots != files["robots.txt"] {
+		t.Errorf("robots.txt = %q, want the book's", robots)
+	}
+}
+
+func TestEditLinks(t *testing.T) {
+	files := map[string]string{
+		"book.json":    `{"editLink": {"url": "https://github.com/o/r/edit/{branch}/docs/{path}", "branch": "dev"}}`,
+		"README.md":    "# Intro\n",
+		"guide/one.md": "# One\n",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [One](guide/one.md)\n",
+	}
+	tests := []struct {
+		name      string
+		localEdit bool
+		want      []string
+	}{
+		{
+			name: "edit link",
+			want: []string{
+				`<a href="https://github.com/o/r/edit/dev/docs/guide/one.md" class="edit-link" target="_blank" rel="noopener">Edit this page</a>`,
+				`<a href="https://github.com/o/r/blob/dev/docs/guide/one.md" class="source-link" target="_blank" rel="noopener">View source</a>`,
+			},
+		},
+		{
+			name:      "local edit",
+			localEdit: true,
+			want: []string{
+				`<a href="?edit=guide%2Fone.md" class="edit-link" data-local-edit="guide/one.md">Edit this page</a>`,
+				`<a href="https://github.com/o/r/blob/dev/docs/guide/one.md" class="source-link" target="_blank" rel="noopener">View source</a>`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, files)
+			b, err := NewBuilder(root, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.LocalEdit = tt.localEdit
+			if err := b.Build(); err != nil {
+				t.Fatal(err)
+			}
+			html := readOutput(t, b.OutputDir, "guide/one.html")
+			for _, link := range tt.want {
+				if !strings.Contains(html, link) {
+					t.Errorf("guide/one.html lacks %s", link)
+				}
+			}
+		})
+	}
+}
+
+func TestBreadcrumbsAndNumbering(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"book.json":    `{"title": "Guide", "numbering": true}`,
+		"README.md":    "# Intro\n",
+		"guide/one.md": "# One\n",
+		"guide/two.md": "# Two\n\n## Details\n",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [Guide]()\n  * [One](guide/one.md)\n    * [Two](guide/two.md)\n",
+	})
+	b, err := NewBuilder(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	html := readOutput(t, b.OutputDir, "guide/two.html")
+	for _, want := range []string{
+		`<title>2.1.1 Two - Guide</title>`,
+		`<span class="breadcrumb-title">Guide</span>`,
+		`<a href="/guide/one.html" class="breadcrumb-link">One</a>`,
+		`<span class="breadcrumb-current">2.1.1 Two</span>`,
+		`<span class="nav-title" data-level="1"><span class="nav-number">2</span> Guide</span>`,
+		`<span class="nav-number">2.1</span> One</a>`,
+		`<h1 id="two"><span class="heading-number">2.1.1</span> Two</h1>`,
+		`<h2 id="details">Details</h2>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("guide/two.html lacks %s", want)
+		}
+	}
+
+	// Ebook tables of contents take the same numbers
+	chapters, err := b.RenderChapters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, ch := range chapters {
+		labels = append(labels, ch.Label())
+	}
+	want := []string{"1 Intro", "2 Guide", "2.1 One", "2.1.1 Two"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("chapter labels = %q, want %q", labels, want)
+	}
+}
@@ -0,0 +1,132 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GitInfo holds the git history of a source file
+type GitInfo struct {
+	LastModified time.Time // commit time of the most recent change
+	Authors      []string  // distinct authors, most recent first
+	CommitHash   string    // hash of the most recent commit touching the file
+}
+
+// gitHistory is a per-build snapshot of the repository history, indexed by file
+type gitHistory struct {
+	topLevel string
+	branch   string              // checked out branch, empty when HEAD is detached
+	files    map[string]*GitInfo // keyed by slash-separated path relative to topLevel
+}
+
+const (
+	gitRecordSep = "\x1e"
+	gitFieldSep  = "\x1f"
+)
+
+// loadGitHistory reads the history of every file under dir with a single git log
+// invocation. It returns nil when git is unavailable or dir is not inside a repository.
+func loadGitHistory(dir string) *gitHistory {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil
+	}
+
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil
+	}
+	topLevel := strings.TrimSpace(string(out))
+	if resolved, err := filepath.EvalSymlinks(topLevel); err == nil {
+		topLevel = resolved
+	}
+
+	cmd := exec.Command("git", "-C", dir, "-c", "core.quotePath=false", "log",
+		"--format="+gitRecordSep+"%H"+gitFieldSep+"%an"+gitFieldSep+"%cI", "--name-only", "--", ".")
+	out, err = cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	h := &gitHistory{topLevel: topLevel, files: make(map[string]*GitInfo)}
+	h.parse(out)
+
+	if out, err := exec.Command("git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+		if branch := strings.TrimSpace(string(out)); branch != "HEAD" {
+			h.branch = branch
+		}
+	}
+	return h
+}
+
+// parse consumes git log output. Commits arrive newest first, so the first commit
+// seen for a file is its most recent change.
+func (h *gitHistory) parse(out []byte) {
+	var hash, author string
+	var when time.Time
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, gitRecordSep) {
+			fields := strings.Split(strings.TrimPrefix(line, gitRecordSep), gitFieldSep)
+			if len(fields) != 3 {
+				hash = ""
+				continue
+			}
+			hash, author = fields[0], fields[1]
+			when, _ = time.Parse(time.RFC3339, fields[2])
+			continue
+		}
+		if line == "" || hash == "" {
+			continue
+		}
+
+		info, ok := h.files[line]
+		if !ok {
+			info = &GitInfo{LastModified: when, CommitHash: hash}
+			h.files[line] = info
+		}
+		if !containsString(info.Authors, author) {
+			info.Authors = append(info.Authors, author)
+		}
+	}
+}
+
+// relPath returns the slash-separated path of an absolute file path relative to
+// the repository root, or "" if it lies outside the repository
+func (h *gitHistory) relPath(path string) string {
+	if h == nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	rel, err := filepath.Rel(h.topLevel, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// lookup returns the history of the file at the given absolute path, or nil if untracked
+func (h *gitHistory) lookup(path string) *GitInfo {
+	rel := h.relPath(path)
+	if rel == "" {
+		return nil
+	}
+	return h.files[rel]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
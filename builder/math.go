@@ -0,0 +1,152 @@
+package builder
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMath is the node kind of inline math
+var KindMath = ast.NewNodeKind("Math")
+
+// KindMathBlock is the node kind of display math
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// Math is inline TeX math written between single dollar signs
+type Math struct {
+	ast.BaseInline
+	Value []byte // TeX source
+}
+
+// Kind implements ast.Node
+func (n *Math) Kind() ast.NodeKind { return KindMath }
+
+// Dump implements ast.Node
+func (n *Math) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Value": string(n.Value)}, nil)
+}
+
+// MathBlock is display TeX math written between $$ delimiters
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+// Kind implements ast.Node
+func (n *MathBlock) Kind() ast.NodeKind { return KindMathBlock }
+
+// IsRaw implements ast.Node
+func (n *MathBlock) IsRaw() bool { return true }
+
+// Dump implements ast.Node
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Value returns the TeX source of the block
+func (n *MathBlock) Value(source []byte) []byte {
+	var lines [][]byte
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		lines = append(lines, bytes.TrimRight(line.Value(source), "\r\n"))
+	}
+	return bytes.TrimSpace(bytes.Join(lines, []byte("\n")))
+}
+
+// mathExtension parses $inline$ and $$display$$ TeX math into Math and
+// MathBlock nodes for the exports that can typeset it, see ParseChapter
+type mathExtension struct{}
+
+func (mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithInlineParsers(util.Prioritized(mathParser{}, 500)),
+		parser.WithBlockParsers(util.Prioritized(mathBlockParser{}, 700)),
+	)
+}
+
+// mathParser parses inline math. As in pandoc, the opening $ must be followed
+// by a non-space and the closing $ preceded by a non-space and not followed by
+// a digit, so that prices such as "$5 and $10" stay text.
+type mathParser struct{}
+
+func (mathParser) Trigger() []byte { return []byte{'$'} }
+
+func (mathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if len(line) < 3 || line[1] == '$' || util.IsSpace(line[1]) {
+		return nil
+	}
+	for i := 2; i < len(line); i++ {
+		switch {
+		case line[i] == '\\':
+			i++
+		case line[i] == '$' && !util.IsSpace(line[i-1]) && (i+1 == len(line) || line[i+1] < '0' || line[i+1] > '9'):
+			node := &Math{Value: append([]byte(nil), line[1:i]...)}
+			block.Advance(i + 1)
+			return node
+		}
+	}
+	return nil
+}
+
+// mathBlockParser parses display math opened by a line starting with $$ and
+// closed by a line ending with $$; both may be the same line
+type mathBlockParser struct{}
+
+func (mathBlockParser) Trigger() []byte { return []byte{'$'} }
+
+func (mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{}
+	start := pos + 2
+	end := len(bytes.TrimRight(line, " \t\r\n"))
+	if end-start >= 2 && bytes.HasSuffix(line[start:end], []byte("$$")) {
+		node.closed = true
+		end -= 2
+	}
+	if start < end && !util.IsBlank(line[start:end]) {
+		offset := segment.Start - segment.Padding
+		node.Lines().Append(text.NewSegment(offset+start, offset+end))
+	}
+	reader.AdvanceToEOL()
+	return node, parser.NoChildren
+}
+
+func (mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if node.(*MathBlock).closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+
+	trimmed := bytes.TrimRight(line, " \t\r\n")
+	if bytes.HasSuffix(trimmed, []byte("$$")) {
+		end := len(trimmed) - 2
+		if !util.IsBlank(line[:end]) {
+			node.Lines().Append(text.NewSegment(segment.Start, segment.Start+end))
+		}
+		reader.AdvanceToEOL()
+		return parser.Close
+	}
+
+	node.Lines().Append(segment)
+	reader.AdvanceToEOL()
+	return parser.Continue | parser.NoChildren
+}
+
+func (mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (mathBlockParser) CanInterruptParagraph() bool { return true }
+
+func (mathBlockParser) CanAcceptIndentedLine() bool { return false }
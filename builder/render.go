@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// RenderedChapter is a chapter rendered without the site layout, as consumed by
+// the ebook generators
+type RenderedChapter struct {
+	Title       string
+	Number      string // hierarchical number, empty unless numbering is enabled
+	Level       int    // nesting level in the summary, starting at 1
+	Part        string // title of the part ("## Part") opened by this top-level chapter
+	Path        string // markdown source relative to the book root, empty for title-only entries
+	HTMLPath    string // page path relative to the output directory
+	Content     string // HTML fragment of the page body
+	FrontMatter book.FrontMatter
+}
+
+// Label returns the title of the chapter as shown in tables of contents
+func (c RenderedChapter) Label() string {
+	if c.Number != "" {
+		return c.Number + " " + c.Title
+	}
+	return c.Title
+}
+
+// RenderChapters renders every chapter of the summary in reading order. Entries
+// without a path are returned with an empty Content so that they still take part
+// in the table of contents; missing files are skipped along with their articles,
+// as on the website.
+func (b *Builder) RenderChapters() ([]RenderedChapter, error) {
+	if b.Book.Summary == nil {
+		return nil, nil
+	}
+	var chapters []RenderedChapter
+	err := b.renderChapters(b.Book.Summary.Chapters, 1, "", &chapters)
+	return chapters, err
+}
+
+func (b *Builder) renderChapters(chapters []book.Chapter, level int, numberPrefix string, out *[]RenderedChapter) error {
+	for i, chapter := range chapters {
+		number := numberPrefix + strconv.Itoa(i+1)
+		rendered := RenderedChapter{
+			Title: chapter.Title,
+			Level: level,
+			Part:  chapter.Part,
+		}
+		if b.numbering() {
+			rendered.Number = number
+		}
+
+		if chapter.Path != "" {
+			html, frontMatter, err := b.renderChapter(filepath.Join(b.Book.Root, chapter.Path), rendered.Number)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", chapter.Path, err)
+			}
+			rendered.Path = filepath.ToSlash(chapter.Path)
+			rendered.HTMLPath = strings.TrimSuffix(rendered.Path, ".md") + ".html"
+			rendered.Content = html
+			rendered.FrontMatter = frontMatter
+		}
+		*out = append(*out, rendered)
+
+		if err := b.renderChapters(chapter.Articles, level+1, number+".", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseChapter parses the markdown source of a chapter, given relative to the
+// book root, with the website's extensions plus footnotes and TeX math (Math
+// and MathBlock nodes). It returns the document and the source its nodes
+// refer to, without front matter.
+func (b *Builder) ParseChapter(chapterPath string) (ast.Node, []byte, error) {
+	content, err := os.ReadFile(filepath.Join(b.Book.Root, filepath.FromSlash(chapterPath)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, body, err := book.ParseFrontMatter(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.source.Parser().Parse(text.NewReader(body)), body, nil
+}
@@ -0,0 +1,325 @@
+package builder
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	stdhtml "html"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+//go:embed templates/single.css
+var singleStylesheet string
+
+// RenderSingleDocument renders every chapter of the summary, in reading order,
+// into one standalone HTML document without the site layout. Each chapter
+// becomes a <section> whose id is derived from its source path (see
+// SectionIDs), element IDs are prefixed per chapter so that they stay unique,
+// links between chapters point at in-document anchors and local references are
+// made relative to the book root. The document is meant to be written at the
+// root of the output directory.
+func (b *Builder) RenderSingleDocument() (string, error) {
+	return b.renderSingleDocument(false)
+}
+
+// RenderStandaloneDocument renders the book as one self-contained HTML page for
+// printing and offline reading: the single document with a title page, a table
+// of contents and a print stylesheet, local images embedded as data URIs.
+func (b *Builder) RenderStandaloneDocument() (string, error) {
+	return b.renderSingleDocument(true)
+}
+
+// BuildSingle writes the standalone document of the book to outputPath
+func (b *Builder) BuildSingle(outputPath string) error {
+	doc, err := b.RenderStandaloneDocument()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputPath, []byte(doc), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+func (b *Builder) renderSingleDocument(standalone bool) (string, error) {
+	chapters, err := b.RenderChapters()
+	if err != nil {
+		return "", err
+	}
+
+	var embed func(target string) (string, bool)
+	if standalone {
+		embed = b.dataURI
+	}
+
+	// Map chapter sources and pages to their section anchors
+	ids := SectionIDs(chapters)
+	sections := make(map[string]string)
+	for _, ch := range chapters {
+		if ch.Path != "" {
+			sections[ch.Path] = ids[ch.Path]
+			sections[ch.HTMLPath] = ids[ch.Path]
+		}
+	}
+
+	var body strings.Builder
+	seen := make(map[string]bool)
+	for _, ch := range chapters {
+		if ch.Path == "" {
+			// Title-only summary entries become part headings
+			body.WriteString(`<section class="part"><h1>` + stdhtml.EscapeString(ch.Label()) + "</h1></section>\n")
+			continue
+		}
+		if seen[ch.Path] {
+			continue
+		}
+		seen[ch.Path] = true
+
+		content, err := rewriteChapterRefs(ch, sections, embed)
+		if err != nil {
+			return "", err
+		}
+		body.WriteString(`<section class="chapter" id="` + ids[ch.Path] + `">` + "\n")
+		body.WriteString(content)
+		body.WriteString("</section>\n")
+	}
+
+	var doc strings.Builder
+	doc.WriteString("<!DOCTYPE html>\n<html")
+	if lang := b.language(); lang != "" {
+		doc.WriteString(` lang="` + stdhtml.EscapeString(lang) + `"`)
+	}
+	doc.WriteString(">\n<head>\n<meta charset=\"UTF-8\">\n")
+	doc.WriteString("<title>" + stdhtml.EscapeString(b.bookTitle()) + "</title>\n")
+	if cfg := b.Book.Config; cfg != nil {
+		if cfg.Author != "" {
+			doc.WriteString(`<meta name="author" content="` + stdhtml.EscapeString(cfg.Author) + `">` + "\n")
+		}
+		if cfg.Description != "" {
+			doc.WriteString(`<meta name="description" content="` + stdhtml.EscapeString(cfg.Description) + `">` + "\n")
+		}
+	}
+	if standalone {
+		doc.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1.0">` + "\n")
+		doc.WriteString("<style>\n" + singleStylesheet + "</style>\n")
+	}
+	doc.WriteString("</head>\n<body>\n")
+	if standalone {
+		doc.WriteString(b.singleTitlePage())
+		doc.WriteString(singleTOC(chapters, ids, b.language()))
+	}
+	doc.WriteString(body.String())
+	doc.WriteString("</body>\n</html>\n")
+	return doc.String(), nil
+}
+
+var sectionIDCleaner = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// SectionID returns the anchor of a chapter in single-document output. Paths
+// such as a/b.md and a-b.md share an anchor; SectionIDs tells them apart.
+func SectionID(chapterPath string) string {
+	p := strings.TrimSuffix(chapterPath, path.Ext(chapterPath))
+	return "ch-" + strings.Trim(sectionIDCleaner.ReplaceAllString(p, "-"), "-")
+}
+
+// SectionIDs returns the anchors of the chapters of a document by source path.
+// Chapters whose SectionID is taken by an earlier one get a numeric suffix.
+func SectionIDs(chapters []RenderedChapter) map[string]string {
+	ids := make(map[string]string)
+	used := make(map[string]bool)
+	for _, ch := range chapters {
+		if ch.Path == "" || ids[ch.Path] != "" {
+			continue
+		}
+		id := SectionID(ch.Path)
+		for n := 2; used[id]; n++ {
+			id = SectionID(ch.Path) + "-" + strconv.Itoa(n)
+		}
+		used[id] = true
+		ids[ch.Path] = id
+	}
+	return ids
+}
+
+// singleTitlePage renders the title, subtitle and author of the book
+func (b *Builder) singleTitlePage() string {
+	var sb strings.Builder
+	sb.WriteString(`<header class="book-title">` + "\n")
+	sb.WriteString("<h1>" + stdhtml.EscapeString(b.bookTitle()) + "</h1>\n")
+	if cfg := b.Book.Config; cfg != nil {
+		if cfg.Subtitle != "" {
+			sb.WriteString("<p>" + stdhtml.EscapeString(cfg.Subtitle) + "</p>\n")
+		}
+		if cfg.Author != "" {
+			sb.WriteString("<p>" + stdhtml.EscapeString(cfg.Author) + "</p>\n")
+		}
+	}
+	sb.WriteString("</header>\n")
+	return sb.String()
+}
+
+// singleTOC renders the summary as nested lists linking to the chapter sections
+func singleTOC(chapters []RenderedChapter, ids map[string]string, language string) string {
+	if len(chapters) == 0 {
+		return ""
+	}
+
+	title := "Contents"
+	if strings.HasPrefix(strings.ToLower(language), "zh") {
+		title = "目录"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<nav class="book-toc">` + "\n<h1>" + title + "</h1>\n")
+	depth := 0
+	for _, ch := range chapters {
+		if ch.Level > depth {
+			for depth < ch.Level {
+				sb.WriteString("<ol>\n<li>")
+				depth++
+			}
+		} else {
+			sb.WriteString("</li>\n")
+			for depth > ch.Level {
+				sb.WriteString("</ol>\n</li>\n")
+				depth--
+			}
+			sb.WriteString("<li>")
+		}
+		if ch.Path != "" {
+			sb.WriteString(`<a href="#` + ids[ch.Path] + `">` + stdhtml.EscapeString(ch.Label()) + "</a>")
+		} else {
+			sb.WriteString("<span>" + stdhtml.EscapeString(ch.Label()) + "</span>")
+		}
+	}
+	for ; depth > 0; depth-- {
+		sb.WriteString("</li>\n</ol>\n")
+	}
+	sb.WriteString("</nav>\n")
+	return sb.String()
+}
+
+// dataURI returns the image at target, relative to the book root, as a data URI
+func (b *Builder) dataURI(target string) (string, bool) {
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(strings.ToLower(path.Ext(target))), ";")
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", false
+	}
+	data, err := os.ReadFile(filepath.Join(b.Book.Root, filepath.FromSlash(target)))
+	if err != nil {
+		return "", false
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), true
+}
+
+// rewriteChapterRefs prefixes the element IDs of a chapter with its section ID
+// and rewrites links and image sources for a document at the book root. When
+// embed is set, local images are replaced by what it returns.
+func rewriteChapterRefs(ch RenderedChapter, sections map[string]string, embed func(target string) (string, bool)) (string, error) {
+	section := sections[ch.Path]
+	dir := path.Dir(ch.Path)
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(ch.Content), context)
+	if err != nil {
+		return "", err
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, a := range n.Attr {
+				switch a.Key {
+				case "id":
+					n.Attr[i].Val = section + "--" + a.Val
+				case "href", "src":
+					if embed != nil && a.Key == "src" && n.DataAtom == atom.Img {
+						if target, _, ok := ResolveRef(dir, a.Val); ok {
+							if uri, ok := embed(target); ok {
+								n.Attr[i].Val = uri
+								continue
+							}
+						}
+					}
+					n.Attr[i].Val = rewriteSingleRef(a.Val, dir, section, sections)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		walk(n)
+		if err := html.Render(&buf, n); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func rewriteSingleRef(ref, dir, section string, sections map[string]string) string {
+	if strings.HasPrefix(ref, "#") && len(ref) > 1 {
+		return "#" + section + "--" + ref[1:]
+	}
+	target, fragment, ok := ResolveRef(dir, ref)
+	if !ok {
+		return ref
+	}
+	if id, ok := sections[target]; ok {
+		if len(fragment) > 1 {
+			return "#" + id + "--" + fragment[1:]
+		}
+		return "#" + id
+	}
+	return (&url.URL{Path: target}).EscapedPath() + fragment
+}
+
+// ResolveRef resolves a link or image reference found in the page at dir
+// (relative to the book root) to a slash-separated path relative to the book
+// root and its fragment. ok is false for external, absolute-URL and
+// fragment-only references and for paths escaping the book root.
+func ResolveRef(dir, ref string) (target, fragment string, ok bool) {
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "//") {
+		return "", "", false
+	}
+	if i := strings.Index(ref, ":"); i >= 0 && !strings.ContainsAny(ref[:i], "/?#") {
+		return "", "", false // has a URL scheme
+	}
+
+	if i := strings.Index(ref, "#"); i >= 0 {
+		ref, fragment = ref[:i], ref[i:]
+	}
+	if i := strings.Index(ref, "?"); i >= 0 {
+		ref = ref[:i]
+	}
+	if ref == "" {
+		return "", "", false
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+
+	if strings.HasPrefix(ref, "/") {
+		target = path.Clean(strings.TrimPrefix(ref, "/"))
+	} else {
+		target = path.Join(dir, ref)
+	}
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return "", "", false
+	}
+	return target, fragment, true
+}
@@ -0,0 +1,102 @@
+package builder
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestBuildSingle(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"book.json":    `{"title": "Guide", "author": "Ann"}`,
+		"README.md":    "# Intro\n\nStart with [one](guide/one.md).\n",
+		"guide/one.md": "# One\n\n## Setup\n\nThen [set up two](two.md#setup).\n\n![logo](../img/logo.png)\n",
+		"guide/two.md": "# Two\n\n## Setup\n\nBack to [the top](#two).\n",
+		"img/logo.png": "PNG",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [Guide]()\n  * [One](guide/one.md)\n  * [Two](guide/two.md)\n",
+	})
+	b, err := NewBuilder(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "book.html")
+	if err := b.BuildSingle(out); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := readOutput(t, filepath.Dir(out), "book.html")
+	for _, want := range []string{
+		`<section class="chapter" id="ch-README">`,
+		`<a href="#ch-guide-one">one</a>`,
+		`<section class="part"><h1>Guide</h1></section>`,
+		// Headings with the same ID in two chapters stay distinct
+		`<h2 id="ch-guide-one--setup">Setup</h2>`,
+		`<h2 id="ch-guide-two--setup">Setup</h2>`,
+		`<a href="#ch-guide-two--setup">set up two</a>`,
+		`<a href="#ch-guide-two--two">the top</a>`,
+		`<img src="data:image/png;base64,UE5H" alt="logo"/>`,
+		`<nav class="book-toc">`,
+		`<a href="#ch-guide-two">Two</a>`,
+		"<style>\n" + singleStylesheet,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("the document lacks %s", want)
+		}
+	}
+}
+
+func TestSectionIDs(t *testing.T) {
+	chapters := []RenderedChapter{
+		{Path: "a/b.md"},
+		{Path: "a-b.md"},
+		{Path: "a-b-2.md"},
+		{Title: "Title only"},
+		{Path: "a/b.md"},
+	}
+	want := map[string]string{"a/b.md": "ch-a-b", "a-b.md": "ch-a-b-2", "a-b-2.md": "ch-a-b-2-2"}
+	if got := SectionIDs(chapters); !reflect.DeepEqual(got, want) {
+		t.Errorf("SectionIDs = %v, want %v", got, want)
+	}
+}
+
+func TestBuildSingleSectionCollisions(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":  "# Intro\n\nSee [nested](a/b.md#top) and [flat](a-b.md#top).\n",
+		"a/b.md":     "# Nested\n\n## Top\n",
+		"a-b.md":     "# Flat\n\n## Top\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n* [Nested](a/b.md)\n* [Flat](a-b.md)\n",
+	})
+	b, err := NewBuilder(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := b.RenderStandaloneDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<section class="chapter" id="ch-a-b">`,
+		`<section class="chapter" id="ch-a-b-2">`,
+		`<h2 id="ch-a-b--top">Top</h2>`,
+		`<h2 id="ch-a-b-2--top">Top</h2>`,
+		`<a href="#ch-a-b--top">nested</a>`,
+		`<a href="#ch-a-b-2--top">flat</a>`,
+		`<a href="#ch-a-b-2">Flat</a>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("the document lacks %s", want)
+		}
+	}
+	ids := make(map[string]bool)
+	for _, m := range regexp.MustCompile(` id="([^"]*)"`).FindAllStringSubmatch(doc, -1) {
+		if ids[m[1]] {
+			t.Errorf("id %s is used twice", m[1])
+		}
+		ids[m[1]] = true
+	}
+}
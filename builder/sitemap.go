@@ -0,0 +1,131 @@
+package builder
+
+import (
+	"encoding/xml"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// generatedPage records a page written by the builder
+type generatedPage struct {
+	URL    string // output path relative to the site root, "" for the index
+	Source string // absolute path of the markdown source
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// siteURL returns the configured public site URL without a trailing slash
+func (b *Builder) siteURL() string {
+	if b.Book.Config == nil {
+		return ""
+	}
+	return strings.TrimRight(b.Book.Config.SiteURL, "/")
+}
+
+// absoluteURL joins a site-relative output path onto the configured site URL
+func (b *Builder) absoluteURL(relPath string) string {
+	site := b.siteURL()
+	if site == "" {
+		return ""
+	}
+	return site + "/" + strings.TrimPrefix(filepath.ToSlash(relPath), "/")
+}
+
+// pageMeta builds the head metadata of a page from its front matter and the book config
+func (b *Builder) pageMeta(fm book.FrontMatter, relPath string) PageMeta {
+	meta := PageMeta{
+		Description: fm.String("description"),
+		Author:      fm.String("author"),
+	}
+	if b.Book.Config != nil {
+		if meta.Description == "" {
+			meta.Description = b.Book.Config.Description
+		}
+		if meta.Author == "" {
+			meta.Author = b.Book.Config.Author
+		}
+	}
+
+	meta.CanonicalURL = b.absoluteURL(relPath)
+
+	// Relative images are resolved against the page's directory
+	if image := fm.String("image"); image != "" {
+		if strings.Contains(image, "://") || b.siteURL() == "" {
+			meta.Image = image
+		} else if strings.HasPrefix(image, "/") {
+			meta.Image = b.absoluteURL(image)
+		} else {
+			meta.Image = b.absoluteURL(path.Join(path.Dir(filepath.ToSlash(relPath)), image))
+		}
+	}
+	return meta
+}
+
+// generateSitemap writes sitemap.xml covering every generated page and a robots.txt
+// pointing at it. Both require a configured site URL; a robots.txt shipped with the
+// book is left untouched.
+func (b *Builder) generateSitemap() error {
+	site := b.siteURL()
+	if site == "" {
+		return nil
+	}
+
+	urlSet := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	seen := make(map[string]bool)
+	for _, page := range b.pages {
+		loc := b.absoluteURL(page.URL)
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+
+		entry := sitemapURL{Loc: loc}
+		if modTime := b.lastModified(page.Source); !modTime.IsZero() {
+			entry.LastMod = modTime.UTC().Format(time.RFC3339)
+		}
+		urlSet.URLs = append(urlSet.URLs, entry)
+	}
+
+	data, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(filepath.Join(b.OutputDir, "sitemap.xml"), data, 0644); err != nil {
+		return err
+	}
+
+	robotsPath := filepath.Join(b.OutputDir, "robots.txt")
+	if _, err := os.Stat(robotsPath); err == nil {
+		return nil
+	}
+	robots := "User-agent: *\nAllow: /\n\nSitemap: " + site + "/sitemap.xml\n"
+	return os.WriteFile(robotsPath, []byte(robots), 0644)
+}
+
+// lastModified returns the last commit time of a source file, falling back to its
+// modification time, or the zero time if unknown
+func (b *Builder) lastModified(source string) time.Time {
+	if gi := b.git.lookup(source); gi != nil {
+		return gi.LastModified
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -107,6 +107,17 @@
     }, 500);
 })();
 
+// Page chrome outside .article-content that must follow partial page loads
+window.updatePageChrome = function(newDoc) {
+    ['#breadcrumbs', '#page-footer'].forEach((selector) => {
+        const oldEl = document.querySelector(selector);
+        const newEl = newDoc.querySelector(selector);
+        if (oldEl && newEl) {
+            oldEl.innerHTML = newEl.innerHTML;
+        }
+    });
+};
+
 // Navigation tree link handling (partial page load)
 (function() {
     // Expose initNavTreeLinks globally so it can be called after live reload
@@ -222,6 +233,9 @@
                     if (oldTOC && newTOC) {
                         oldTOC.innerHTML = newTOC.innerHTML;
                     }
+
+                    // Update breadcrumbs and other page chrome
+                    window.updatePageChrome(newDoc);
                     
                     // Update active state in navtree (without refreshing the whole tree)
                     updateNavTreeActiveState(url);
@@ -580,6 +594,9 @@
                         oldTOC.innerHTML = newTOC.innerHTML;
                     }
 
+                    // Update breadcrumbs and other page chrome
+                    window.updatePageChrome(newDoc);
+
                     // Update navigation active state only (don't refresh the whole navtree)
                     // Only update if structure actually changed (e.g., new chapters added)
                     const oldNavTree = document.querySelector('.nav-tree');
@@ -906,3 +923,189 @@
     });
 })();
 
+
+// In-browser editor (gitbook serve only)
+(function() {
+    const TOKEN_KEY = 'gitbook-edit-token';
+
+    let pane = null;
+    let current = null; // { path, mtime, saved }
+
+    function getToken(forcePrompt) {
+        let token = forcePrompt ? null : localStorage.getItem(TOKEN_KEY);
+        if (!token) {
+            token = window.prompt('Enter the edit token (printed by gitbook serve in the terminal)');
+            if (token) {
+                localStorage.setItem(TOKEN_KEY, token.trim());
+            }
+        }
+        return token ? token.trim() : null;
+    }
+
+    // Call the files API, prompting for the token again if it was rejected
+    async function callFilesAPI(options, retried) {
+        const token = getToken(retried);
+        if (!token) {
+            throw new Error('No edit token given');
+        }
+        const url = options.path ? '/api/files?path=' + encodeURIComponent(options.path) : '/api/files';
+        const response = await fetch(url, {
+            method: options.method || 'GET',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-Gitbook-Token': token
+            },
+            body: options.body ? JSON.stringify(options.body) : undefined
+        });
+        if (response.status === 401 && !retried) {
+            localStorage.removeItem(TOKEN_KEY);
+            return callFilesAPI(options, true);
+        }
+        const data = await response.json();
+        return { status: response.status, data: data };
+    }
+
+    function setStatus(message, type) {
+        const status = pane && pane.querySelector('.editor-status');
+        if (status) {
+            status.textContent = message;
+            status.dataset.type = type || 'info';
+        }
+    }
+
+    function isDirty() {
+        return pane && current && pane.querySelector('.editor-textarea').value !== current.saved;
+    }
+
+    function createPane() {
+        pane = document.createElement('section');
+        pane.className = 'editor-pane';
+        pane.id = 'editor-pane';
+        pane.innerHTML = `
+            <div class="editor-toolbar">
+                <span class="editor-path"></span>
+                <span class="editor-status"></span>
+                <button type="button" class="editor-button editor-save">Save</button>
+                <button type="button" class="editor-button editor-close">Close</button>
+            </div>
+            <textarea class="editor-textarea" spellcheck="false"></textarea>
+        `;
+        const mainContent = document.getElementById('main-content');
+        mainContent.parentNode.insertBefore(pane, mainContent);
+
+        const textarea = pane.querySelector('.editor-textarea');
+        textarea.addEventListener('input', () => {
+            setStatus(isDirty() ? 'Unsaved' : '', 'info');
+        });
+        textarea.addEventListener('keydown', (e) => {
+            if ((e.ctrlKey || e.metaKey) && e.key === 's') {
+                e.preventDefault();
+                save(false);
+            }
+            if (e.key === 'Tab') {
+                e.preventDefault();
+                const start = textarea.selectionStart;
+                textarea.setRangeText('  ', start, textarea.selectionEnd, 'end');
+            }
+        });
+        pane.querySelector('.editor-save').addEventListener('click', () => save(false));
+        pane.querySelector('.editor-close').addEventListener('click', closeEditor);
+    }
+
+    async function openEditor(path) {
+        if (isDirty() && !window.confirm('This file has unsaved changes. Discard them?')) {
+            return;
+        }
+        try {
+            const result = await callFilesAPI({ path: path });
+            if (result.status !== 200) {
+                throw new Error(result.data.error || 'Failed to load');
+            }
+            if (!pane) {
+                createPane();
+            }
+            current = { path: path, mtime: result.data.mtime, saved: result.data.content };
+            pane.querySelector('.editor-path').textContent = path;
+            pane.querySelector('.editor-textarea').value = result.data.content;
+            setStatus('', 'info');
+            document.body.classList.add('editing');
+
+            const url = new URL(window.location.href);
+            url.searchParams.set('edit', path);
+            window.history.replaceState(window.history.state, '', url);
+        } catch (error) {
+            window.alert('Cannot open the editor: ' + error.message);
+        }
+    }
+
+    function closeEditor() {
+        if (isDirty() && !window.confirm('This file has unsaved changes. Close anyway?')) {
+            return;
+        }
+        if (pane) {
+            pane.remove();
+            pane = null;
+        }
+        current = null;
+        document.body.classList.remove('editing');
+
+        const url = new URL(window.location.href);
+        url.searchParams.delete('edit');
+        window.history.replaceState(window.history.state, '', url);
+    }
+
+    // Save the file; the watcher picks up the change and live reload refreshes the preview
+    async function save(force) {
+        if (!pane || !current) return;
+        const content = pane.querySelector('.editor-textarea').value;
+        setStatus('Saving...', 'info');
+        try {
+            const result = await callFilesAPI({
+                method: 'PUT',
+                body: { path: current.path, content: content, mtime: current.mtime }
+            });
+            if (result.status === 409) {
+                // The file changed on disk since it was loaded
+                if (result.data.mtime && window.confirm('The file was changed elsewhere.\nOK: overwrite it with your version\nCancel: load the version on disk')) {
+                    current.mtime = result.data.mtime;
+                    return save(true);
+                }
+                if (result.data.mtime) {
+                    current.mtime = result.data.mtime;
+                    current.saved = result.data.content;
+                    pane.querySelector('.editor-textarea').value = result.data.content;
+                }
+                setStatus('Loaded the version on disk', 'error');
+                return;
+            }
+            if (result.status !== 200) {
+                throw new Error(result.data.error || 'Failed to save');
+            }
+            current.mtime = result.data.mtime;
+            current.saved = content;
+            setStatus('Saved', 'success');
+        } catch (error) {
+            setStatus('Failed to save: ' + error.message, 'error');
+        }
+    }
+
+    // Edit links are re-rendered on partial page loads, so delegate the click
+    document.addEventListener('click', (e) => {
+        const link = e.target.closest('.edit-link[data-local-edit]');
+        if (!link) return;
+        e.preventDefault();
+        openEditor(link.dataset.localEdit);
+    });
+
+    window.addEventListener('beforeunload', (e) => {
+        if (isDirty()) {
+            e.preventDefault();
+            e.returnValue = '';
+        }
+    });
+
+    const editPath = new URLSearchParams(window.location.search).get('edit');
+    if (editPath && document.querySelector('.edit-link[data-local-edit]')) {
+        openEditor(editPath);
+    }
+})();
@@ -27,10 +27,11 @@ func handleBuild(bookRoot string, fset *pflag.FlagSet, args []string) error {
 		panic("output directory is required")
 	}
 
-	builder, err := builder.NewBuilder(bookRoot, outputDir)
+	builder, err := builder.NewBuilder(bookRoot, outputDir, loadOptions(fset)...)
 	if err != nil {
 		return err
 	}
+	printWarnings(builder.Book.Warnings)
 
 	return builder.Build()
 }
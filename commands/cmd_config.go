@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewConfigCommand creates the config command and its subcommands
+func NewConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read, change and validate the book configuration",
+		Long:  "Read, change and validate book.json, book.yaml or book.toml from scripts. Keys are dotted paths such as title, pdf.paperSize or variables.version.",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <key> [book]",
+		Short: "Print the effective value of a key",
+		Long:  "Print the effective value of a key: strings as is, other values as JSON. Exits with status 1 if the key is not set.",
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(handleConfigGet(cmd.Flags(), args[0], optionalArg(args, 1)))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <key> <value> [book]",
+		Short: "Set a key in the configuration file",
+		Long:  "Set a key in the configuration file, book.json if there is none. The value is parsed as JSON unless the schema expects a string. In book.json and book.yaml only that key changes: other keys and their order are kept, and so are YAML comments; book.toml is rewritten with its keys sorted and without comments. The whole file is validated before it is written: problems the change introduces reject it, problems the file already had are printed as warnings.",
+		Args:  cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(handleConfigSet(args[0], args[1], optionalArg(args, 2)))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate [book]",
+		Short: "Check the configuration file against its JSON Schema",
+		Long:  "Check the configuration file against its JSON Schema, printing each problem with its line and column and exiting with status 1 if any is found",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(handleConfigValidate(optionalArg(args, 0)))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print [book]",
+		Short: "Print the effective configuration",
+		Long:  "Print the configuration the builders use, as JSON: the configuration file with GITBOOK_* environment variables, --set overrides and defaults applied",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(handleConfigPrint(cmd.Flags(), optionalArg(args, 0)))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of book.json",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			os.Stdout.Write(book.Schema)
+		},
+	})
+	return cmd
+}
+
+// optionalArg returns args[i], or the current directory
+func optionalArg(args []string, i int) string {
+	if len(args) > i {
+		return args[i]
+	}
+	return "."
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		PrintError(err)
+		os.Exit(1)
+	}
+}
+
+// effectiveConfig returns the configuration of a book with defaults, as
+// generic JSON values
+func effectiveConfig(fset *pflag.FlagSet, bookRoot string) (map[string]interface{}, error) {
+	b, err := book.LoadBook(bookRoot, loadOptions(fset)...)
+	if err != nil {
+		return nil, err
+	}
+	printWarnings(b.Warnings)
+	data, err := json.Marshal(b.Config.WithDefaults())
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+func handleConfigGet(fset *pflag.FlagSet, key, bookRoot string) error {
+	values, err := effectiveConfig(fset, bookRoot)
+	if err != nil {
+		return err
+	}
+	var value interface{} = values
+	for _, part := range strings.Split(key, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not set", key)
+		}
+		if value, ok = obj[part]; !ok {
+			return fmt.Errorf("%s is not set", key)
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		fmt.Println(s)
+		return nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func handleConfigSet(key, raw, bookRoot string) error {
+	configPath, err := book.ConfigPath(bookRoot)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(configPath)
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Problems the file already had are reported but do not block the change
+	known := make(map[string]bool)
+	if len(data) > 0 {
+		before, err := book.ValidateConfig(data, configPath)
+		if err != nil {
+			return err
+		}
+		for _, issue := range before {
+			known[issue.Path+": "+issue.Message] = true
+		}
+	}
+
+	data, err = book.EditConfig(data, configPath, key, raw)
+	if err != nil {
+		return err
+	}
+	issues, err := book.ValidateConfig(data, configPath)
+	if err != nil {
+		return err
+	}
+	var problems int
+	for _, issue := range issues {
+		if known[issue.Path+": "+issue.Message] {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", issue)
+			continue
+		}
+		fmt.Println(issue)
+		problems++
+	}
+	if problems > 0 {
+		return fmt.Errorf("%s: %d problems; nothing was written", name, problems)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func handleConfigValidate(bookRoot string) error {
+	configPath, err := book.ConfigPath(bookRoot)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(configPath)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	issues, err := book.ValidateConfig(data, configPath)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%s: %d problems", name, len(issues))
+	}
+	fmt.Printf("%s: no problems\n", name)
+	return nil
+}
+
+func handleConfigPrint(fset *pflag.FlagSet, bookRoot string) error {
+	b, err := book.LoadBook(bookRoot, loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(b.Warnings)
+	data, err := json.MarshalIndent(b.Config.WithDefaults(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
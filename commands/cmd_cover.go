@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/ebook"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCoverCommand creates the cover command
+func NewCoverCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cover [book]",
+		Short: "Generate a cover image for a book",
+		Long:  "Render cover.jpg and cover_small.jpg from the title, subtitle and author in book.json",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCommand("cover", cmd.Flags(), args)
+		},
+	}
+	cmd.Flags().Bool("force", false, "Overwrite an existing cover.jpg")
+	return cmd
+}
+
+func handleCover(bookRoot string, fset *pflag.FlagSet, args []string) error {
+	b, err := book.LoadBook(bookRoot, loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(b.Warnings)
+
+	coverPath := filepath.Join(b.Root, "cover.jpg")
+	if force, _ := fset.GetBool("force"); !force {
+		if _, err := os.Stat(coverPath); err == nil {
+			return fmt.Errorf("cover.jpg already exists in %s, use --force to overwrite it", b.Root)
+		}
+	}
+
+	gen, err := ebook.NewCoverGenerator(b.Root, b.Config)
+	if err != nil {
+		return err
+	}
+	if err := gen.WriteFiles(b.Root); err != nil {
+		return fmt.Errorf("failed to generate cover: %w", err)
+	}
+
+	fmt.Printf("Cover generated: %s\n", coverPath)
+	return nil
+}
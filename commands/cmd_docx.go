@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"path/filepath"
+
+	"github.com/hitzhangjie/gitbook/ebook"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewDOCXCommand creates the docx command
+func NewDOCXCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docx [book] [output]",
+		Short: "Build a Word document from a book",
+		Long:  "Generate a DOCX (Word) file from your book",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCommand("docx", cmd.Flags(), args)
+		},
+	}
+	addConverterFlag(cmd)
+	return cmd
+}
+
+func handleDOCX(bookRoot string, fset *pflag.FlagSet, args []string) error {
+	outputPath := "book.docx"
+	if len(args) >= 2 {
+		outputPath = args[1]
+	} else if len(args) == 1 {
+		if abs, err := filepath.Abs(args[0]); err == nil && abs != bookRoot {
+			outputPath = args[0]
+		}
+	}
+
+	outputDir := filepath.Join(bookRoot, "_book")
+	gen, err := ebook.NewGenerator(bookRoot, outputDir, "docx", loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(gen.Book.Warnings)
+	gen.Converter, _ = fset.GetString("converter")
+
+	return generate(gen, outputPath)
+}
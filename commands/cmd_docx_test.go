@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleDOCXOutputArgument(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":  "# Intro\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n",
+	})
+	out := filepath.Join(t.TempDir(), "out.docx")
+	if err := handleDOCX(root, NewDOCXCommand().Flags(), []string{root, out}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("the document was not written to the output argument: %v", err)
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 // NewEPUBCommand creates the epub command
 func NewEPUBCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "epub [book] [output]",
 		Short: "Build an epub from a book",
 		Long:  "Generate an EPUB file from your book",
@@ -18,6 +18,8 @@ func NewEPUBCommand() *cobra.Command {
 			runCommand("epub", cmd.Flags(), args)
 		},
 	}
+	addConverterFlag(cmd)
+	return cmd
 }
 
 func handleEPUB(bookRoot string, fset *pflag.FlagSet, args []string) error {
@@ -27,10 +29,12 @@ func handleEPUB(bookRoot string, fset *pflag.FlagSet, args []string) error {
 	}
 
 	outputDir := filepath.Join(bookRoot, "_book")
-	gen, err := ebook.NewGenerator(bookRoot, outputDir, "epub")
+	gen, err := ebook.NewGenerator(bookRoot, outputDir, "epub", loadOptions(fset)...)
 	if err != nil {
 		return err
 	}
+	printWarnings(gen.Book.Warnings)
+	gen.Converter, _ = fset.GetString("converter")
 
-	return gen.Generate(outputPath)
+	return generate(gen, outputPath)
 }
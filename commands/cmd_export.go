@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/export"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewExportCommand creates the export command
+func NewExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [book] [output] --to mdbook|docusaurus|hugo",
+		Short: "Export a book to another documentation tool",
+		Long:  "Convert the summary, pages, front matter, variables and assets of a book into an mdBook, Docusaurus or Hugo source tree, reporting constructs that cannot be translated",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCommand("export", cmd.Flags(), args)
+		},
+	}
+	cmd.Flags().String("to", "", "Export target: "+strings.Join(export.Targets, ", "))
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func handleExport(bookRoot string, fset *pflag.FlagSet, args []string) error {
+	target, _ := fset.GetString("to")
+	outputDir := "book-" + target
+	if len(args) >= 2 {
+		outputDir = args[1]
+	} else if len(args) == 1 {
+		if abs, err := filepath.Abs(args[0]); err == nil && abs != bookRoot {
+			outputDir = args[0]
+		}
+	}
+
+	exp, err := export.NewExporter(bookRoot, target, outputDir, loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(exp.Book.Warnings)
+	if err := exp.Export(); err != nil {
+		return err
+	}
+
+	for _, issue := range exp.Issues {
+		fmt.Printf("WARNING: %s\n", issue)
+	}
+	fmt.Printf("Exported to %s (%s), %d constructs need attention\n", outputDir, target, len(exp.Issues))
+	return nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewHTMLCommand creates the html command
+func NewHTMLCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "html [book] [output]",
+		Short: "Build the book as HTML",
+		Long:  "Build the static website, or with --single the whole book as one self-contained HTML file for printing and offline reading",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCommand("html", cmd.Flags(), args)
+		},
+	}
+	cmd.Flags().Bool("single", false, "Write the whole book as one self-contained HTML file")
+	return cmd
+}
+
+func handleHTML(bookRoot string, fset *pflag.FlagSet, args []string) error {
+	// The output is the second argument, or the only one when it is not the book
+	output := ""
+	if len(args) >= 2 {
+		output = args[1]
+	} else if len(args) == 1 {
+		if abs, err := filepath.Abs(args[0]); err == nil && abs != bookRoot {
+			output = args[0]
+		}
+	}
+
+	single, _ := fset.GetBool("single")
+	if !single {
+		b, err := builder.NewBuilder(bookRoot, output, loadOptions(fset)...)
+		if err != nil {
+			return err
+		}
+		printWarnings(b.Book.Warnings)
+		return b.Build()
+	}
+
+	if output == "" {
+		output = "book.html"
+	}
+	b, err := builder.NewBuilder(bookRoot, "", loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(b.Book.Warnings)
+	if err := b.BuildSingle(output); err != nil {
+		return err
+	}
+	fmt.Printf("Single-page HTML written to %s\n", output)
+	return nil
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/importer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewImportCommand creates the import command
+func NewImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <source> [book]",
+		Short: "Import an mdBook, Docusaurus or Markdown tree as a book",
+		Long:  "Generate book.json and SUMMARY.md from an mdBook book.toml and SUMMARY.md, a Docusaurus sidebars file or a bare directory of Markdown, and move, copy or link the files into GitBook layout. The book defaults to the source directory itself.",
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handleImport(cmd.Flags(), args); err != nil {
+				PrintError(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().String("from", "", "Source format: "+strings.Join(importer.Formats, ", ")+" (default: detected)")
+	cmd.Flags().String("mode", "move", "How files are placed into the book: "+strings.Join(importer.Modes, ", "))
+	cmd.Flags().Bool("dry-run", false, "Print the planned operations and a diff of book.json and SUMMARY.md without changing anything")
+	cmd.Flags().Bool("force", false, "Replace an existing SUMMARY.md and conflicting files")
+	return cmd
+}
+
+func handleImport(fset *pflag.FlagSet, args []string) error {
+	source := args[0]
+	bookRoot := source
+	if len(args) > 1 {
+		bookRoot = args[1]
+	}
+	format, _ := fset.GetString("from")
+	mode, _ := fset.GetString("mode")
+	dryRun, _ := fset.GetBool("dry-run")
+	force, _ := fset.GetBool("force")
+
+	plan, err := importer.NewPlan(source, bookRoot, format)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		diff, err := plan.Diff(mode)
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+	} else if err := plan.Apply(mode, force); err != nil {
+		return err
+	}
+
+	for _, note := range plan.Notes {
+		fmt.Printf("WARNING: %s\n", note)
+	}
+	if dryRun {
+		fmt.Printf("Dry run: %d files would be imported from %s (%s)\n", len(plan.Files), source, plan.Format)
+		return nil
+	}
+	fmt.Printf("Imported %d files from %s (%s) into %s\n", len(plan.Files), source, plan.Format, plan.Root)
+	return nil
+}
@@ -1,25 +1,38 @@
 package commands
 
 import (
+	"bufio"
+	"embed"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hitzhangjie/gitbook/book"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
+//go:embed templates
+var starterTemplates embed.FS
+
 // NewInitCommand creates the init command
 func NewInitCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "init [book]",
 		Short: "Setup and initialize a book",
-		Long:  "Initialize a book structure in the current directory or specified directory",
+		Long:  "Initialize a book structure in the current directory or specified directory, creating the files of SUMMARY.md entries that do not exist yet",
 		Run: func(cmd *cobra.Command, args []string) {
 			runCommand("init", cmd.Flags(), args)
 		},
 	}
+	cmd.Flags().String("template", "", "Starter layout: "+strings.Join(templateNames(), ", ")+", or a local directory to copy")
+	addInitWizardFlags(cmd)
+	return cmd
 }
 
 func handleInit(bookRoot string, fset *pflag.FlagSet, args []string) error {
@@ -27,62 +40,252 @@ func handleInit(bookRoot string, fset *pflag.FlagSet, args []string) error {
 	if len(args) > 0 {
 		initDir = args[0]
 	}
-	return doInit(initDir)
+	templateName, _ := fset.GetString("template")
+
+	var wizard *initWizard
+	if wizardRequested(fset) {
+		interactive, _ := fset.GetBool("interactive")
+		wizard = &initWizard{fset: fset, interactive: interactive, in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	}
+	return doInit(initDir, templateName, wizard)
 }
 
-// doInit initializes a new GitBook project
-func doInit(bookRoot string) error {
+// doInit initializes a new GitBook project from an optional starter template,
+// then creates the missing files of the summary. The wizard, if any, edits
+// book.json first.
+func doInit(bookRoot, templateName string, wizard *initWizard) error {
 	absRoot, err := filepath.Abs(bookRoot)
 	if err != nil {
 		return err
 	}
 
+	var tmpl fs.FS
+	if templateName != "" {
+		if tmpl, err = starterTemplate(templateName); err != nil {
+			return err
+		}
+	}
+
+	// Settings come from an existing configuration file, then the template's,
+	// then the defaults
+	configPath, err := book.ConfigPath(absRoot)
+	if err != nil {
+		return err
+	}
+	config, err := book.LoadConfig(configPath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !exists && tmpl != nil {
+		if data, err := fs.ReadFile(tmpl, "book.json"); err == nil {
+			config = &book.Config{}
+			if err := json.Unmarshal(data, config); err != nil {
+				return fmt.Errorf("failed to parse the template book.json: %w", err)
+			}
+		}
+	}
+	if config == nil {
+		config = &book.Config{
+			Title:   "My Book",
+			Author:  "",
+			Plugins: []string{},
+		}
+	}
+	if wizard != nil {
+		if err := wizard.Run(config); err != nil {
+			return err
+		}
+	}
+
+	// Content goes to the root setting, if any
+	contentRoot, err := book.ResolvePath(absRoot, config.Root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+
 	// Check if directory exists
-	if _, err := os.Stat(absRoot); os.IsNotExist(err) {
-		if err := os.MkdirAll(absRoot, 0755); err != nil {
+	if _, err := os.Stat(contentRoot); os.IsNotExist(err) {
+		if err := os.MkdirAll(contentRoot, 0755); err != nil {
 			return fmt.Errorf("failed to create directory: %w", err)
 		}
 	}
 
-	// Check if book.json already exists
-	configPath := filepath.Join(absRoot, "book.json")
-	if _, err := os.Stat(configPath); err == nil {
-		return fmt.Errorf("book.json already exists in %s", absRoot)
+	if tmpl != nil {
+		if err := copyStarterTemplate(tmpl, contentRoot); err != nil {
+			return fmt.Errorf("failed to copy template: %w", err)
+		}
+	}
+
+	// Create book.json if there is no configuration file
+	if !exists || wizard != nil {
+		if err := book.SaveConfig(configPath, config); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Base(configPath), err)
+		}
 	}
 
-	// Create default book.json
-	config := &book.Config{
-		Title:   "My Book",
-		Author:  "",
-		Plugins: []string{},
+	// A multi-language book initializes the book of each language
+	if langs, err := book.LoadSummary(filepath.Join(contentRoot, "LANGS.md")); err == nil {
+		for _, lang := range langs.Chapters {
+			dir := strings.TrimSuffix(lang.Path, "/")
+			if dir == "" || !localPath(dir) {
+				continue
+			}
+			if err := initChapters(contentRoot, filepath.Join(contentRoot, filepath.FromSlash(dir)), nil); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("GitBook initialized in %s\n", absRoot)
+		return nil
 	}
 
-	if err := book.SaveConfig(configPath, config); err != nil {
-		return fmt.Errorf("failed to create book.json: %w", err)
+	if err := initChapters(contentRoot, contentRoot, config.Structure); err != nil {
+		return err
+	}
+	fmt.Printf("GitBook initialized in %s\n", absRoot)
+	return nil
+}
+
+// initChapters creates the README.md and SUMMARY.md of a book if they don't
+// exist, then a page with a title heading for every summary entry whose file
+// is missing. The structure, if any, renames README.md and SUMMARY.md.
+func initChapters(absRoot, dir string, structure *book.Structure) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	readme, summaryFile := "README.md", "SUMMARY.md"
+	if structure != nil && structure.Readme != "" {
+		readme = structure.Readme
+	}
+	if structure != nil && structure.Summary != "" {
+		summaryFile = structure.Summary
 	}
 
 	// Create README.md if it doesn't exist
-	readmePath := filepath.Join(absRoot, "README.md")
+	readmePath := filepath.Join(dir, filepath.FromSlash(readme))
 	if _, err := os.Stat(readmePath); os.IsNotExist(err) {
 		readmeContent := `# Introduction
 
 This is the introduction to your book.`
+		if err := os.MkdirAll(filepath.Dir(readmePath), 0755); err != nil {
+			return err
+		}
 		if err := os.WriteFile(readmePath, []byte(readmeContent), 0644); err != nil {
-			return fmt.Errorf("failed to create README.md: %w", err)
+			return fmt.Errorf("failed to create %s: %w", readme, err)
 		}
 	}
 
 	// Create SUMMARY.md if it doesn't exist
-	summaryPath := filepath.Join(absRoot, "SUMMARY.md")
+	summaryPath := filepath.Join(dir, filepath.FromSlash(summaryFile))
 	if _, err := os.Stat(summaryPath); os.IsNotExist(err) {
 		summaryContent := `# Summary
 
-* [Introduction](README.md)`
+* [Introduction](` + readme + `)`
+		if err := os.MkdirAll(filepath.Dir(summaryPath), 0755); err != nil {
+			return err
+		}
 		if err := os.WriteFile(summaryPath, []byte(summaryContent), 0644); err != nil {
-			return fmt.Errorf("failed to create SUMMARY.md: %w", err)
+			return fmt.Errorf("failed to create %s: %w", summaryFile, err)
 		}
 	}
 
-	fmt.Printf("GitBook initialized in %s\n", absRoot)
+	summary, err := book.LoadSummary(summaryPath)
+	if err != nil {
+		return err
+	}
+	return createChapterFiles(absRoot, dir, summary.Chapters)
+}
+
+// createChapterFiles creates the missing pages of chapters, and their
+// directories, with the chapter title as heading
+func createChapterFiles(absRoot, dir string, chapters []book.Chapter) error {
+	for _, ch := range chapters {
+		p, _, _ := strings.Cut(ch.Path, "#")
+		if p != "" && localPath(p) {
+			if strings.HasSuffix(p, "/") {
+				p += "README.md"
+			}
+			file := filepath.Join(dir, filepath.FromSlash(p))
+			if _, err := os.Stat(file); os.IsNotExist(err) {
+				if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+					return fmt.Errorf("failed to create directory for %s: %w", p, err)
+				}
+				if err := os.WriteFile(file, []byte("# "+ch.Title+"\n"), 0644); err != nil {
+					return fmt.Errorf("failed to create %s: %w", p, err)
+				}
+				rel, _ := filepath.Rel(absRoot, file)
+				fmt.Printf("Created %s\n", filepath.ToSlash(rel))
+			}
+		}
+		if err := createChapterFiles(absRoot, dir, ch.Articles); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// localPath reports whether a summary link is a relative path inside the book
+func localPath(p string) bool {
+	if strings.Contains(p, "://") || strings.HasPrefix(p, "mailto:") || path.IsAbs(p) {
+		return false
+	}
+	clean := path.Clean(p)
+	return clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// templateNames lists the embedded starter templates
+func templateNames() []string {
+	entries, _ := starterTemplates.ReadDir("templates")
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// starterTemplate returns an embedded template, or a local directory
+func starterTemplate(name string) (fs.FS, error) {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return os.DirFS(name), nil
+	}
+	if !strings.ContainsAny(name, `/\`) {
+		if sub, err := fs.Sub(starterTemplates, "templates/"+name); err == nil {
+			if _, err := fs.Stat(sub, "."); err == nil {
+				return sub, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(templateNames(), ", "))
+}
+
+// copyStarterTemplate copies a template into the book root without replacing
+// existing files
+func copyStarterTemplate(src fs.FS, absRoot string) error {
+	return fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(absRoot, filepath.FromSlash(p))
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return os.MkdirAll(dst, 0755)
+		}
+		if p == "book.json" {
+			return nil // written by doInit, possibly in another format
+		}
+		if _, err := os.Stat(dst); err == nil {
+			fmt.Printf("Kept existing %s\n", p)
+			return nil
+		}
+		data, err := fs.ReadFile(src, p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, 0644)
+	})
+}
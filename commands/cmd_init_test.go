@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+func TestInitCreatesSummaryFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n* [Basics](basics/)\n  * [Vars](basics/vars.md#top)\n  * [Kept](kept.md)\n* [Site](https://example.com/)\n* [Draft]()\n",
+		"kept.md":    "# Kept as is\n",
+	})
+	if err := doInit(root, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"basics/README.md": "# Basics\n",
+		"basics/vars.md":   "# Vars\n",
+		"kept.md":          "# Kept as is\n",
+	}
+	for name, content := range want {
+		if got := readFile(t, filepath.Join(root, filepath.FromSlash(name))); got != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+	if got := readFile(t, filepath.Join(root, "README.md")); got != "# Introduction\n\nThis is the introduction to your book." {
+		t.Errorf("README.md = %q", got)
+	}
+	config, err := book.LoadConfig(filepath.Join(root, "book.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "My Book" {
+		t.Errorf("title = %q, want My Book", config.Title)
+	}
+}
+
+func TestInitTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		title    string
+		files    []string
+	}{
+		{"tutorial", "My Tutorial", []string{"getting-started/installation.md", "basics/concepts.md", "next-steps.md"}},
+		{"api", "API Reference", []string{"authentication.md", "endpoints/users.md", "objects/README.md"}},
+		{"multilang", "My Book", []string{"LANGS.md", "en/getting-started.md", "zh/README.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			root := t.TempDir()
+			if err := doInit(root, tt.template, nil); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.files {
+				if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+					t.Errorf("%s was not created: %v", name, err)
+				}
+			}
+			config, err := book.LoadConfig(filepath.Join(root, "book.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Title != tt.title {
+				t.Errorf("title = %q, want %q", config.Title, tt.title)
+			}
+		})
+	}
+
+	// A local directory is a template too, and unknown names are errors
+	tmpl := t.TempDir()
+	writeFiles(t, tmpl, map[string]string{
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n* [Local](local.md)\n",
+	})
+	root := t.TempDir()
+	if err := doInit(root, tmpl, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(root, "local.md")); got != "# Local\n" {
+		t.Errorf("local.md = %q", got)
+	}
+	if err := doInit(t.TempDir(), "no-such-template", nil); err == nil {
+		t.Error("doInit with an unknown template succeeded")
+	}
+}
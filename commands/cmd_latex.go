@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hitzhangjie/gitbook/ebook"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewLaTeXCommand creates the latex command
+func NewLaTeXCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "latex [book] [output]",
+		Short: "Export a book as a LaTeX project",
+		Long:  "Write main.tex, one file per chapter and the images into a directory, ready to compile with xelatex or lualatex",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCommand("latex", cmd.Flags(), args)
+		},
+	}
+	return cmd
+}
+
+func handleLaTeX(bookRoot string, fset *pflag.FlagSet, args []string) error {
+	outputPath := "book-latex"
+	if len(args) >= 2 {
+		outputPath = args[1]
+	} else if len(args) == 1 {
+		if abs, err := filepath.Abs(args[0]); err == nil && abs != bookRoot {
+			outputPath = args[0]
+		}
+	}
+
+	outputDir := filepath.Join(bookRoot, "_book")
+	gen, err := ebook.NewGenerator(bookRoot, outputDir, "latex", loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(gen.Book.Warnings)
+	gen.Converter = "native"
+
+	if err := generate(gen, outputPath); err != nil {
+		return err
+	}
+	fmt.Printf("LaTeX project written to %s\n", outputPath)
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 
 // NewMOBICommand creates the mobi command
 func NewMOBICommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "mobi [book] [output]",
 		Short: "Build a mobi from a book",
 		Long:  "Generate a MOBI file from your book",
@@ -18,6 +18,8 @@ func NewMOBICommand() *cobra.Command {
 			runCommand("mobi", cmd.Flags(), args)
 		},
 	}
+	addConverterFlag(cmd)
+	return cmd
 }
 
 func handleMOBI(bookRoot string, fset *pflag.FlagSet, args []string) error {
@@ -27,10 +29,12 @@ func handleMOBI(bookRoot string, fset *pflag.FlagSet, args []string) error {
 	}
 
 	outputDir := filepath.Join(bookRoot, "_book")
-	gen, err := ebook.NewGenerator(bookRoot, outputDir, "mobi")
+	gen, err := ebook.NewGenerator(bookRoot, outputDir, "mobi", loadOptions(fset)...)
 	if err != nil {
 		return err
 	}
+	printWarnings(gen.Book.Warnings)
+	gen.Converter, _ = fset.GetString("converter")
 
-	return gen.Generate(outputPath)
+	return generate(gen, outputPath)
 }
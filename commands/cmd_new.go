@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultChapterTemplate is the page created for a new chapter
+const defaultChapterTemplate = `---
+title: {{ printf "%q" .Title }}
+---
+
+# {{ .Title }}
+`
+
+// NewNewCommand creates the new command and its subcommands
+func NewNewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new",
+		Short: "Create book content",
+	}
+	cmd.AddCommand(newChapterCommand())
+	return cmd
+}
+
+func newChapterCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chapter <title> [book]",
+		Short: "Create a chapter and add it to SUMMARY.md",
+		Long:  "Create the Markdown file of a chapter from a template and insert its entry into SUMMARY.md, under --parent and after --after (paths or chapter numbers such as 2.1); by default the chapter is appended to the top level",
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handleNewChapter(cmd.Flags(), args); err != nil {
+				PrintError(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().String("parent", "", "Chapter to nest the new chapter under (default: the parent of --after, or the top level)")
+	cmd.Flags().String("after", "", "Sibling chapter to insert the new chapter after (default: last)")
+	cmd.Flags().String("path", "", "Path of the new page relative to the content root (default: derived from the title, next to the parent page)")
+	cmd.Flags().String("template", "", "text/template file for the new page, receiving .Title, .Path and .Parent")
+	return cmd
+}
+
+func handleNewChapter(fset *pflag.FlagSet, args []string) error {
+	title := strings.TrimSpace(args[0])
+	if title == "" {
+		return fmt.Errorf("chapter title is empty")
+	}
+	if strings.ContainsAny(title, "[]") {
+		return fmt.Errorf("chapter title %q contains brackets, which SUMMARY.md links cannot hold", title)
+	}
+	bookRoot := "."
+	if len(args) > 1 {
+		bookRoot = args[1]
+	}
+	parentRef, _ := fset.GetString("parent")
+	afterRef, _ := fset.GetString("after")
+	pagePath, _ := fset.GetString("path")
+	templateFile, _ := fset.GetString("template")
+
+	b, err := book.LoadBook(bookRoot, loadOptions(fset)...)
+	if err != nil {
+		return fmt.Errorf("failed to load book: %w", err)
+	}
+	printWarnings(b.Warnings)
+	contentRoot := b.Root
+	summaryPath, err := book.ResolvePath(contentRoot, b.Config.WithDefaults().Structure.Summary)
+	if err != nil {
+		return fmt.Errorf("invalid structure.summary: %w", err)
+	}
+	summary := b.Summary
+	if summary == nil {
+		summary = &book.Summary{}
+	}
+
+	// Resolve where the entry goes
+	var parent book.Index
+	var parentChapter *book.Chapter
+	if parentRef != "" {
+		if parent, err = summary.Locate(parentRef); err != nil {
+			return err
+		}
+		parentChapter = summary.Chapter(parent)
+	}
+	pos := -1
+	if afterRef != "" {
+		after, err := summary.Locate(afterRef)
+		if err != nil {
+			return err
+		}
+		switch {
+		case parentRef == "":
+			parent = after[:len(after)-1]
+			if len(parent) > 0 {
+				parentChapter = summary.Chapter(parent)
+			}
+		case len(after) != len(parent)+1 || after[:len(parent)].String() != parent.String():
+			return fmt.Errorf("%s is not directly under %s", afterRef, parentRef)
+		}
+		pos = after[len(after)-1] + 1
+	}
+
+	// Pick the page path
+	if pagePath == "" {
+		dir := ""
+		if parentChapter != nil && parentChapter.Path != "" {
+			dir = path.Dir(parentChapter.Path)
+		}
+		pagePath = path.Join(dir, chapterSlug(title)+".md")
+	}
+	pagePath = path.Clean(filepath.ToSlash(pagePath))
+	file, err := book.ResolvePath(contentRoot, pagePath)
+	if err != nil {
+		return fmt.Errorf("invalid chapter path: %w", err)
+	}
+	if idx, ok := summary.Find(pagePath); ok {
+		return fmt.Errorf("%s is already in the summary as chapter %s", pagePath, idx)
+	}
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%s already exists", pagePath)
+	}
+
+	// Create the page
+	tmplText := defaultChapterTemplate
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		tmplText = string(data)
+	}
+	tmpl, err := template.New("chapter").Parse(tmplText)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	parentTitle := ""
+	if parentChapter != nil {
+		parentTitle = parentChapter.Title
+	}
+	var content strings.Builder
+	if err := tmpl.Execute(&content, map[string]string{"Title": title, "Path": pagePath, "Parent": parentTitle}); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	if err := summary.Insert(parent, pos, book.Chapter{Title: title, Path: pagePath}); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", pagePath, err)
+	}
+	if err := book.SaveSummary(summaryPath, summary); err != nil {
+		return fmt.Errorf("failed to write SUMMARY.md: %w", err)
+	}
+
+	idx, _ := summary.Find(pagePath)
+	fmt.Printf("Created %s as chapter %s\n", pagePath, idx)
+	return nil
+}
+
+// chapterSlug turns a title into a file name: lower case letters and digits
+// joined by hyphens
+func chapterSlug(title string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	if sb.Len() == 0 {
+		return "chapter"
+	}
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNewChapter(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		title   string
+		want    string // SUMMARY.md afterwards
+		created string
+	}{
+		{
+			name:    "append to the top level",
+			title:   "Three Things",
+			want:    "# Summary\n\n* [Intro](README.md)\n* [Basics](basics/README.md)\n  * [Vars](basics/vars.md)\n* [Three Things](three-things.md)\n",
+			created: "three-things.md",
+		},
+		{
+			name:    "under a parent, next to its page",
+			flags:   map[string]string{"parent": "basics/README.md"},
+			title:   "Types",
+			want:    "# Summary\n\n* [Intro](README.md)\n* [Basics](basics/README.md)\n  * [Vars](basics/vars.md)\n  * [Types](basics/types.md)\n",
+			created: "basics/types.md",
+		},
+		{
+			name:    "after a chapter number",
+			flags:   map[string]string{"after": "1", "path": "setup.md"},
+			title:   "Setup",
+			want:    "# Summary\n\n* [Intro](README.md)\n* [Setup](setup.md)\n* [Basics](basics/README.md)\n  * [Vars](basics/vars.md)\n",
+			created: "setup.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{
+				"SUMMARY.md":       "# Summary\n\n* [Intro](README.md)\n* [Basics](basics/README.md)\n  * [Vars](basics/vars.md)\n",
+				"README.md":        "# Intro\n",
+				"basics/README.md": "# Basics\n",
+				"basics/vars.md":   "# Vars\n",
+			})
+			cmd := newChapterCommand()
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := handleNewChapter(cmd.Flags(), []string{tt.title, root}); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, filepath.Join(root, "SUMMARY.md")); got != tt.want {
+				t.Errorf("SUMMARY.md =\n%s\nwant\n%s", got, tt.want)
+			}
+			want := "---\ntitle: \"" + tt.title + "\"\n---\n\n# " + tt.title + "\n"
+			if got := readFile(t, filepath.Join(root, filepath.FromSlash(tt.created))); got != want {
+				t.Errorf("%s = %q, want %q", tt.created, got, want)
+			}
+		})
+	}
+}
+
+func TestNewChapterInContentRoot(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"book.json":       `{"root": "docs"}`,
+		"docs/SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n",
+		"docs/README.md":  "# Intro\n",
+	})
+	if err := handleNewChapter(newChapterCommand().Flags(), []string{"Usage", root}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# Summary\n\n* [Intro](README.md)\n* [Usage](usage.md)\n"
+	if got := readFile(t, filepath.Join(root, "docs", "SUMMARY.md")); got != want {
+		t.Errorf("docs/SUMMARY.md =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "docs", "usage.md")); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"SUMMARY.md", "usage.md"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was written outside the content root", name)
+		}
+	}
+
+	cmd := newChapterCommand()
+	if err := cmd.Flags().Set("path", "../escape.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := handleNewChapter(cmd.Flags(), []string{"Escape", root}); err == nil {
+		t.Error("expected an error for a path outside the content root")
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 // NewPDFCommand creates the pdf command
 func NewPDFCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "pdf [book] [output]",
 		Short: "Build a pdf from a book",
 		Long:  "Generate a PDF file from your book",
@@ -18,6 +18,8 @@ func NewPDFCommand() *cobra.Command {
 			runCommand("pdf", cmd.Flags(), args)
 		},
 	}
+	addConverterFlag(cmd)
+	return cmd
 }
 
 func handlePDF(bookRoot string, fset *pflag.FlagSet, args []string) error {
@@ -27,10 +29,12 @@ func handlePDF(bookRoot string, fset *pflag.FlagSet, args []string) error {
 	}
 
 	outputDir := filepath.Join(bookRoot, "_book")
-	gen, err := ebook.NewGenerator(bookRoot, outputDir, "pdf")
+	gen, err := ebook.NewGenerator(bookRoot, outputDir, "pdf", loadOptions(fset)...)
 	if err != nil {
 		return err
 	}
+	printWarnings(gen.Book.Warnings)
+	gen.Converter, _ = fset.GetString("converter")
 
-	return gen.Generate(outputPath)
+	return generate(gen, outputPath)
 }
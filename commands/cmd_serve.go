@@ -17,15 +17,18 @@ func NewServeCommand() *cobra.Command {
 		},
 	}
 	cmd.Flags().String("http", "localhost:4000", "HTTP listen address (e.g. 0.0.0.0:4000)")
+	cmd.Flags().String("edit-token", "", "Token required to save files from the in-browser editor (random if empty)")
 	return cmd
 }
 
 func handleServe(bookRoot string, fset *pflag.FlagSet, args []string) error {
 	httpAddr, _ := fset.GetString("http")
-	srv, err := server.NewServer(bookRoot, httpAddr)
+	srv, err := server.NewServer(bookRoot, httpAddr, loadOptions(fset)...)
 	if err != nil {
 		return err
 	}
+	printWarnings(srv.Book.Warnings)
+	srv.EditToken, _ = fset.GetString("edit-token")
 
 	return srv.Start()
 }
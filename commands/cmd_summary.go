@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewSummaryCommand creates the summary command
+func NewSummaryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "summary [book]",
+		Short: "Generate SUMMARY.md from the directory tree",
+		Long:  "Build the table of contents from the Markdown files of the book: titles from the title front matter or the first heading, order from the order front matter or numeric file name prefixes, skipping paths matched by .bookignore. Set \"summary\": {\"auto\": true} in book.json to build from the tree without a SUMMARY.md.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCommand("summary", cmd.Flags(), args)
+		},
+	}
+	cmd.Flags().Bool("print", false, "Print the summary instead of writing SUMMARY.md")
+	cmd.Flags().Bool("force", false, "Overwrite an existing SUMMARY.md")
+	return cmd
+}
+
+func handleSummary(bookRoot string, fset *pflag.FlagSet, args []string) error {
+	b, err := book.LoadBook(bookRoot, loadOptions(fset)...)
+	if err != nil {
+		return err
+	}
+	printWarnings(b.Warnings)
+	contentRoot := b.Root
+
+	summary, err := book.GenerateSummary(contentRoot, b.Ignore)
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", err)
+	}
+	if print, _ := fset.GetBool("print"); print {
+		return book.WriteSummary(os.Stdout, summary)
+	}
+
+	summaryPath, err := book.ResolvePath(contentRoot, b.Config.WithDefaults().Structure.Summary)
+	if err != nil {
+		return fmt.Errorf("invalid structure.summary: %w", err)
+	}
+	if force, _ := fset.GetBool("force"); !force {
+		if _, err := os.Stat(summaryPath); err == nil {
+			return fmt.Errorf("%s already exists in %s, use --force to overwrite it", filepath.Base(summaryPath), contentRoot)
+		}
+	}
+	if err := book.SaveSummary(summaryPath, summary); err != nil {
+		return fmt.Errorf("failed to write SUMMARY.md: %w", err)
+	}
+	fmt.Printf("Summary generated: %s\n", summaryPath)
+	return nil
+}
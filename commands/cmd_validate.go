@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hitzhangjie/gitbook/ebook"
+	"github.com/spf13/cobra"
+)
+
+// NewValidateCommand creates the validate command
+func NewValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate <file.epub>",
+		Short: "Check an EPUB file for packaging errors",
+		Long:  "Check the container, mimetype, package document, navigation document and content documents of an EPUB file, exiting with status 1 if errors are found",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handleValidate(args[0]); err != nil {
+				PrintError(err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func handleValidate(filename string) error {
+	findings, err := ebook.ValidateEPUB(filename)
+	if err != nil {
+		return err
+	}
+
+	errors, warnings := 0, 0
+	for _, f := range findings {
+		fmt.Println(f)
+		if f.Severity == ebook.SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	if errors > 0 {
+		return fmt.Errorf("%s: %d errors, %d warnings", filename, errors, warnings)
+	}
+	fmt.Printf("%s: no errors, %d warnings\n", filename, warnings)
+	return nil
+}
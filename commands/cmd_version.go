@@ -2,6 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/ebook"
 
 	"github.com/spf13/cobra"
 )
@@ -14,6 +17,18 @@ func NewVersionCommand() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println("gitbook is an utility rewritten in Go, which is based on gitbook-cli 2.3.2")
 			fmt.Println("gitbook version: v0.0.1")
+			fmt.Println("ebook converters:")
+			for _, c := range ebook.Converters(nil) {
+				version := "not available"
+				if c.Available() {
+					if v, err := c.Version(); err == nil && v != "" {
+						version = v
+					} else {
+						version = "available"
+					}
+				}
+				fmt.Printf("  %-8s %-15s %s\n", c.Name(), strings.Join(c.Formats(), ","), version)
+			}
 		},
 	}
 }
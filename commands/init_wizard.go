@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/ebook"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// wizardFormats lists the output formats offered by the init wizard
+var wizardFormats = []string{"website", "pdf", "epub", "mobi", "docx", "latex"}
+
+// wizardPaperSizes lists the paper sizes understood by every PDF backend
+var wizardPaperSizes = []string{"a4", "a5", "letter", "legal"}
+
+var (
+	languagePattern   = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	pluginPattern     = regexp.MustCompile(`^-?[a-z0-9][a-z0-9._-]*$`)
+	docClassPattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+	structureFileFlag = map[string]string{"readme": "README", "summary": "SUMMARY", "glossary": "GLOSSARY", "langs": "LANGS"}
+)
+
+// addInitWizardFlags adds the flags answering the questions of the init wizard
+func addInitWizardFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("interactive", "i", false, "Prompt for the book settings, using the flags below as defaults")
+	cmd.Flags().String("title", "", "Book title")
+	cmd.Flags().String("author", "", "Book author")
+	cmd.Flags().String("language", "", "Book language, e.g. en or zh-hans")
+	cmd.Flags().String("description", "", "Book description")
+	cmd.Flags().String("plugins", "", "Comma-separated plugin names")
+	cmd.Flags().String("formats", "", "Comma-separated output formats: "+strings.Join(wizardFormats, ", "))
+	cmd.Flags().String("paper-size", "", "PDF paper size: "+strings.Join(wizardPaperSizes, ", "))
+	cmd.Flags().String("converter", "", "Ebook converter backend")
+	cmd.Flags().String("document-class", "", "LaTeX document class")
+	for flag, name := range structureFileFlag {
+		cmd.Flags().String(flag, "", "File used instead of "+name+".md")
+	}
+}
+
+// initWizard fills book.json settings from flags, prompting for each one when
+// interactive. Every answer is validated; invalid flag values are errors and
+// invalid answers are asked again.
+type initWizard struct {
+	fset        *pflag.FlagSet
+	interactive bool
+	in          *bufio.Reader
+	out         io.Writer
+}
+
+// wizardRequested reports whether init should run the wizard: --interactive
+// or any of its flags
+func wizardRequested(fset *pflag.FlagSet) bool {
+	requested := false
+	fset.Visit(func(f *pflag.Flag) {
+		if f.Name != "template" {
+			requested = true
+		}
+	})
+	return requested
+}
+
+// Run asks every question and applies the answers to config
+func (w *initWizard) Run(config *book.Config) error {
+	if w.interactive {
+		fmt.Fprintln(w.out, "Press enter to keep the value in brackets.")
+	}
+
+	var err error
+	if config.Title, err = w.ask("Title", "title", config.Title, func(v string) error {
+		if v == "" {
+			return fmt.Errorf("the title is required")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if config.Author, err = w.ask("Author", "author", config.Author, nil); err != nil {
+		return err
+	}
+	if config.Language, err = w.ask("Language (e.g. en, zh-hans)", "language", config.Language, func(v string) error {
+		if v != "" && !languagePattern.MatchString(v) {
+			return fmt.Errorf("%q is not a language tag such as en or zh-hans", v)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if config.Description, err = w.ask("Description", "description", config.Description, nil); err != nil {
+		return err
+	}
+
+	plugins, err := w.ask("Plugins (comma-separated)", "plugins", strings.Join(config.Plugins, ","), func(v string) error {
+		for _, p := range splitList(v) {
+			if !pluginPattern.MatchString(p) {
+				return fmt.Errorf("%q is not a plugin name", p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	config.Plugins = splitList(plugins)
+	if config.Plugins == nil {
+		config.Plugins = []string{}
+	}
+
+	formats, err := w.ask("Output formats ("+strings.Join(wizardFormats, ", ")+")", "formats", "website", func(v string) error {
+		for _, f := range splitList(v) {
+			if !contains(wizardFormats, f) {
+				return fmt.Errorf("unknown format %q", f)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.askFormatOptions(config, splitList(formats)); err != nil {
+		return err
+	}
+	return w.askStructure(config)
+}
+
+// askFormatOptions asks for the settings of the chosen output formats
+func (w *initWizard) askFormatOptions(config *book.Config, formats []string) error {
+	if contains(formats, "pdf") {
+		current := "a4"
+		if config.PDF != nil && config.PDF.PaperSize != "" {
+			current = config.PDF.PaperSize
+		}
+		size, err := w.ask("PDF paper size ("+strings.Join(wizardPaperSizes, ", ")+")", "paper-size", current, func(v string) error {
+			if !contains(wizardPaperSizes, strings.ToLower(v)) {
+				return fmt.Errorf("unknown paper size %q", v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if config.PDF == nil {
+			config.PDF = &book.PDF{}
+		}
+		config.PDF.PaperSize = strings.ToLower(size)
+	}
+
+	if contains(formats, "pdf") || contains(formats, "epub") || contains(formats, "mobi") || contains(formats, "docx") {
+		var names []string
+		for _, c := range ebook.Converters(config) {
+			names = append(names, c.Name())
+		}
+		current := ""
+		if config.Ebook != nil {
+			current = config.Ebook.Converter
+		}
+		converter, err := w.ask("Ebook converter ("+strings.Join(names, ", ")+", empty for automatic)", "converter", current, func(v string) error {
+			if v != "" && !contains(names, v) {
+				return fmt.Errorf("unknown converter %q", v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if converter != "" {
+			if config.Ebook == nil {
+				config.Ebook = &book.Ebook{}
+			}
+			config.Ebook.Converter = converter
+		}
+	}
+
+	if contains(formats, "latex") {
+		current := "book"
+		if config.LaTeX != nil && config.LaTeX.DocumentClass != "" {
+			current = config.LaTeX.DocumentClass
+		}
+		class, err := w.ask("LaTeX document class", "document-class", current, func(v string) error {
+			if !docClassPattern.MatchString(v) {
+				return fmt.Errorf("%q is not a document class name", v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if config.LaTeX == nil {
+			config.LaTeX = &book.LaTeX{}
+		}
+		config.LaTeX.DocumentClass = class
+	}
+	return nil
+}
+
+// askStructure asks for the files replacing README.md, SUMMARY.md,
+// GLOSSARY.md and LANGS.md
+func (w *initWizard) askStructure(config *book.Config) error {
+	s := book.Structure{}
+	if config.Structure != nil {
+		s = *config.Structure
+	}
+	for _, f := range []struct {
+		flag  string
+		value *string
+	}{
+		{"readme", &s.Readme},
+		{"summary", &s.Summary},
+		{"glossary", &s.Glossary},
+		{"langs", &s.Languages},
+	} {
+		name := structureFileFlag[f.flag]
+		v, err := w.ask(name+" file (empty for "+name+".md)", f.flag, *f.value, validStructureFile)
+		if err != nil {
+			return err
+		}
+		if v == name+".md" {
+			v = ""
+		}
+		*f.value = v
+	}
+	if s != (book.Structure{}) {
+		config.Structure = &s
+	} else {
+		config.Structure = nil
+	}
+	return nil
+}
+
+// validStructureFile checks that a structure override is a Markdown file inside the book
+func validStructureFile(v string) error {
+	if v == "" {
+		return nil
+	}
+	if !localPath(v) || path.Clean(v) == "." {
+		return fmt.Errorf("%q must be a path inside the book", v)
+	}
+	if ext := strings.ToLower(path.Ext(v)); ext != ".md" && ext != ".markdown" {
+		return fmt.Errorf("%q must be a Markdown file", v)
+	}
+	return nil
+}
+
+// ask returns the answer to a question: the flag value if set, else current,
+// or what the user types when interactive
+func (w *initWizard) ask(label, flag, current string, validate func(string) error) (string, error) {
+	value := current
+	if f := w.fset.Lookup(flag); f != nil && f.Changed {
+		value = f.Value.String()
+	}
+	value = strings.TrimSpace(value)
+	if validate == nil {
+		validate = func(string) error { return nil }
+	}
+
+	if !w.interactive {
+		if err := validate(value); err != nil {
+			return "", fmt.Errorf("--%s: %w", flag, err)
+		}
+		return value, nil
+	}
+
+	for {
+		if value != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", label, value)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", label)
+		}
+		line, err := w.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("no answer for %s: %w", strings.ToLower(label), err)
+		}
+		answer := strings.TrimSpace(line)
+		switch answer {
+		case "":
+			answer = value
+		case "-":
+			answer = "" // clears the current value
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(w.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// splitList splits a comma-separated answer, dropping empty items
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
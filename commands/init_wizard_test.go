@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// runWizard initializes a book in a new directory with the wizard answering
+// from flags, and from input when interactive, and returns its configuration
+func runWizard(t *testing.T, flags map[string]string, input string) (*book.Config, error) {
+	t.Helper()
+	fset := NewInitCommand().Flags()
+	for name, value := range flags {
+		if err := fset.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wizard := &initWizard{fset: fset, interactive: input != "", in: bufio.NewReader(strings.NewReader(input)), out: io.Discard}
+	root := t.TempDir()
+	if err := doInit(root, "", wizard); err != nil {
+		return nil, err
+	}
+	return book.LoadConfig(filepath.Join(root, "book.json"))
+}
+
+func TestInitWizardFlags(t *testing.T) {
+	config, err := runWizard(t, map[string]string{
+		"title":          "Guide",
+		"author":         "Ann",
+		"language":       "zh-hans",
+		"plugins":        "search, -sharing",
+		"formats":        "pdf,latex",
+		"paper-size":     "Letter",
+		"document-class": "report",
+		"summary":        "TOC.md",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "Guide" || config.Author != "Ann" || config.Language != "zh-hans" {
+		t.Errorf("title, author, language = %q, %q, %q", config.Title, config.Author, config.Language)
+	}
+	if want := []string{"search", "-sharing"}; !reflect.DeepEqual(config.Plugins, want) {
+		t.Errorf("plugins = %q, want %q", config.Plugins, want)
+	}
+	if config.PDF == nil || config.PDF.PaperSize != "letter" {
+		t.Errorf("pdf = %+v, want paper size letter", config.PDF)
+	}
+	if config.LaTeX == nil || config.LaTeX.DocumentClass != "report" {
+		t.Errorf("latex = %+v, want document class report", config.LaTeX)
+	}
+	if config.Structure == nil || *config.Structure != (book.Structure{Summary: "TOC.md"}) {
+		t.Errorf("structure = %+v, want summary TOC.md", config.Structure)
+	}
+}
+
+func TestInitWizardDefaults(t *testing.T) {
+	// Unasked settings keep the defaults of a new book
+	config, err := runWizard(t, map[string]string{"author": "Ann"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "My Book" || config.Author != "Ann" || config.Language != "" || len(config.Plugins) != 0 {
+		t.Errorf("config = %+v", config)
+	}
+	if config.PDF != nil || config.Ebook != nil || config.LaTeX != nil || config.Structure != nil {
+		t.Errorf("format settings for the default website format: %+v", config)
+	}
+}
+
+func TestInitWizardInvalidFlags(t *testing.T) {
+	tests := []struct {
+		flag, value, want string
+	}{
+		{"title", " ", "--title: the title is required"},
+		{"language", "english!", `--language: "english!" is not a language tag`},
+		{"plugins", "Bad Plugin", `--plugins: "Bad Plugin" is not a plugin name`},
+		{"formats", "html", `--formats: unknown format "html"`},
+		{"readme", "../intro.md", `--readme: "../intro.md" must be a path inside the book`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			_, err := runWizard(t, map[string]string{tt.flag: tt.value}, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitWizardInteractive(t *testing.T) {
+	// An invalid answer is asked again; enter keeps the value in brackets
+	input := strings.Join([]string{
+		"Guide",        // title
+		"",             // author
+		"not a tag",    // language, asked again
+		"en",           // language
+		"About Go",     // description
+		"",             // plugins
+		"epub",         // formats
+		"",             // converter
+		"", "", "", "", // structure
+	}, "\n") + "\n"
+	config, err := runWizard(t, map[string]string{"author": "Ann"}, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "Guide" || config.Author != "Ann" || config.Language != "en" || config.Description != "About Go" {
+		t.Errorf("config = %+v", config)
+	}
+
+	if _, err := runWizard(t, nil, "Guide\n"); err == nil || !strings.Contains(err.Error(), "no answer for author") {
+		t.Errorf("error = %v, want no answer for author", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 
 	"github.com/fatih/color"
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/ebook"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -16,10 +18,46 @@ func RegisterCommands(rootCmd *cobra.Command) {
 	// GitBook commands (serve, build, pdf, epub, etc.)
 	rootCmd.AddCommand(NewServeCommand())
 	rootCmd.AddCommand(NewBuildCommand())
+	rootCmd.AddCommand(NewHTMLCommand())
 	rootCmd.AddCommand(NewPDFCommand())
 	rootCmd.AddCommand(NewEPUBCommand())
 	rootCmd.AddCommand(NewMOBICommand())
+	rootCmd.AddCommand(NewDOCXCommand())
+	rootCmd.AddCommand(NewLaTeXCommand())
 	rootCmd.AddCommand(NewInitCommand())
+	rootCmd.AddCommand(NewCoverCommand())
+	rootCmd.AddCommand(NewValidateCommand())
+	rootCmd.AddCommand(NewExportCommand())
+	rootCmd.AddCommand(NewImportCommand())
+	rootCmd.AddCommand(NewNewCommand())
+	rootCmd.AddCommand(NewSummaryCommand())
+	rootCmd.AddCommand(NewConfigCommand())
+
+	// Configuration overrides apply to every command
+	rootCmd.PersistentFlags().StringArray("set", nil, "Override a configuration key, as key=value; repeatable, applied after GITBOOK_* environment variables")
+}
+
+// loadOptions returns how commands load the book, from the flags they inherit
+// from the root command
+func loadOptions(fset *pflag.FlagSet) []book.LoadOption {
+	overrides, _ := fset.GetStringArray("set")
+	return []book.LoadOption{book.WithOverrides(overrides)}
+}
+
+// printWarnings prints the problems found while loading a book
+func printWarnings(warnings []book.ConfigIssue) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", w)
+	}
+}
+
+// generate writes an ebook and prints what the generator worked around
+func generate(gen *ebook.Generator, outputPath string) error {
+	err := gen.Generate(outputPath)
+	for _, w := range gen.Warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", w)
+	}
+	return err
 }
 
 // getBookRoot returns the book root directory
@@ -42,8 +80,8 @@ func runCommand(commandName string, fset *pflag.FlagSet, args []string) {
 		absBookRoot = bookRoot
 	}
 
-	// Ensure book root exists
-	if _, err := os.Stat(absBookRoot); err != nil {
+	// Ensure book root exists and is a directory (a lone argument may be an output file)
+	if info, err := os.Stat(absBookRoot); err != nil || !info.IsDir() {
 		absBookRoot, _ = os.Getwd()
 	}
 
@@ -52,6 +90,8 @@ func runCommand(commandName string, fset *pflag.FlagSet, args []string) {
 		err = handleInit(absBookRoot, fset, args)
 	case "build":
 		err = handleBuild(absBookRoot, fset, args)
+	case "html":
+		err = handleHTML(absBookRoot, fset, args)
 	case "serve":
 		err = handleServe(absBookRoot, fset, args)
 	case "pdf":
@@ -60,6 +100,16 @@ func runCommand(commandName string, fset *pflag.FlagSet, args []string) {
 		err = handleEPUB(absBookRoot, fset, args)
 	case "mobi":
 		err = handleMOBI(absBookRoot, fset, args)
+	case "docx":
+		err = handleDOCX(absBookRoot, fset, args)
+	case "latex":
+		err = handleLaTeX(absBookRoot, fset, args)
+	case "cover":
+		err = handleCover(absBookRoot, fset, args)
+	case "export":
+		err = handleExport(absBookRoot, fset, args)
+	case "summary":
+		err = handleSummary(absBookRoot, fset, args)
 	default:
 		err = fmt.Errorf("unknown command: %s", commandName)
 	}
@@ -70,6 +120,11 @@ func runCommand(commandName string, fset *pflag.FlagSet, args []string) {
 	}
 }
 
+// addConverterFlag adds the --converter flag shared by the ebook commands
+func addConverterFlag(cmd *cobra.Command) {
+	cmd.Flags().String("converter", "", "Ebook converter backend: native, calibre, pandoc or command (default: first available)")
+}
+
 // PrintError prints an error message
 func PrintError(err error) {
 	fmt.Println()
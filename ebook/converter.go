@@ -0,0 +1,384 @@
+package ebook
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+// Converter is a backend turning a built book into an ebook format
+type Converter interface {
+	// Name returns the name used to select the converter
+	Name() string
+	// Formats returns the output formats the converter supports
+	Formats() []string
+	// Available reports whether the converter can run on this machine
+	Available() bool
+	// Version returns the version of the underlying tool
+	Version() (string, error)
+	// Convert performs the conversion described by job
+	Convert(job *Job) error
+}
+
+// Job describes a single conversion
+type Job struct {
+	Format     string
+	OutputPath string // absolute path of the ebook to write
+	WorkDir    string // output directory of the built website
+	Cover      string // absolute path of the cover image, empty for none
+	Builder    *builder.Builder
+
+	document string
+}
+
+// Config returns the configuration of the book being converted, never nil
+func (j *Job) Config() *book.Config {
+	if j.Builder.Book.Config == nil {
+		return &book.Config{}
+	}
+	return j.Builder.Book.Config
+}
+
+// Document returns the path of the single-document rendering of the book,
+// writing it on first use. It lives at the root of WorkDir so that asset
+// paths resolve.
+func (j *Job) Document() (string, error) {
+	if j.document != "" {
+		return j.document, nil
+	}
+
+	doc, err := j.Builder.RenderSingleDocument()
+	if err != nil {
+		return "", fmt.Errorf("failed to assemble book: %w", err)
+	}
+
+	docPath := filepath.Join(j.WorkDir, documentName)
+	if err := os.WriteFile(docPath, []byte(doc), 0644); err != nil {
+		return "", err
+	}
+	j.document = docPath
+	return docPath, nil
+}
+
+// ConverterFactory creates a converter from the book configuration
+type ConverterFactory func(cfg *book.Config) Converter
+
+type registeredConverter struct {
+	name    string
+	factory ConverterFactory
+}
+
+// converters holds the registered backends in order of preference
+var converters []registeredConverter
+
+func init() {
+	RegisterConverter("native", func(*book.Config) Converter { return nativeConverter{} })
+	RegisterConverter("calibre", func(*book.Config) Converter { return calibreConverter{} })
+	RegisterConverter("pandoc", func(*book.Config) Converter { return pandocConverter{} })
+	RegisterConverter("command", func(cfg *book.Config) Converter {
+		c := commandConverter{}
+		if cfg != nil && cfg.Ebook != nil {
+			c.template = cfg.Ebook.Command
+		}
+		return c
+	})
+}
+
+// RegisterConverter registers a converter backend. Backends registered first
+// are preferred when no converter is selected explicitly.
+func RegisterConverter(name string, factory ConverterFactory) {
+	for i, c := range converters {
+		if c.name == name {
+			converters[i].factory = factory
+			return
+		}
+	}
+	converters = append(converters, registeredConverter{name: name, factory: factory})
+}
+
+// Converters returns every registered converter in order of preference
+func Converters(cfg *book.Config) []Converter {
+	var list []Converter
+	for _, c := range converters {
+		list = append(list, c.factory(cfg))
+	}
+	return list
+}
+
+// SelectConverter returns the converter to use for format. An empty name picks
+// the first available converter supporting the format.
+func SelectConverter(name, format string, cfg *book.Config) (Converter, error) {
+	if name != "" {
+		for _, c := range Converters(cfg) {
+			if c.Name() != name {
+				continue
+			}
+			if !supportsFormat(c, format) {
+				return nil, fmt.Errorf("converter %s does not support %s (supported: %s)", name, format, strings.Join(c.Formats(), ", "))
+			}
+			if !c.Available() {
+				return nil, fmt.Errorf("converter %s is not available on this machine", name)
+			}
+			return c, nil
+		}
+		return nil, fmt.Errorf("unknown converter: %s (known: %s)", name, strings.Join(converterNames(cfg), ", "))
+	}
+
+	var candidates []string
+	for _, c := range Converters(cfg) {
+		if !supportsFormat(c, format) {
+			continue
+		}
+		if c.Available() {
+			return c, nil
+		}
+		candidates = append(candidates, c.Name())
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	return nil, fmt.Errorf("no converter available for %s (tried: %s)", format, strings.Join(candidates, ", "))
+}
+
+func converterNames(cfg *book.Config) []string {
+	var names []string
+	for _, c := range Converters(cfg) {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+func supportsFormat(c Converter, format string) bool {
+	for _, f := range c.Formats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// toolVersion runs "<tool> --version" and extracts the first version number
+func toolVersion(tool string) (string, error) {
+	out, err := exec.Command(tool, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	if v := versionPattern.FindString(string(out)); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("cannot parse %s version", tool)
+}
+
+// runTool runs an external converter inside the work directory
+func runTool(workDir, tool string, args ...string) error {
+	cmd := exec.Command(tool, args...)
+	cmd.Dir = workDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// nativeConverter writes EPUB 3, DOCX and LaTeX projects without external tools
+type nativeConverter struct{}
+
+func (nativeConverter) Name() string             { return "native" }
+func (nativeConverter) Formats() []string        { return []string{"epub", "docx", "latex"} }
+func (nativeConverter) Available() bool          { return true }
+func (nativeConverter) Version() (string, error) { return "builtin", nil }
+
+func (nativeConverter) Convert(job *Job) error {
+	switch job.Format {
+	case "epub":
+		w, err := NewEPUBWriter(job.Builder)
+		if err != nil {
+			return fmt.Errorf("failed to render chapters: %w", err)
+		}
+		w.Cover = job.Cover
+		return w.Write(job.OutputPath)
+	case "docx":
+		w, err := NewDOCXWriter(job.Builder)
+		if err != nil {
+			return fmt.Errorf("failed to render chapters: %w", err)
+		}
+		return w.Write(job.OutputPath)
+	case "latex":
+		w, err := NewLaTeXWriter(job.Builder)
+		if err != nil {
+			return fmt.Errorf("failed to render chapters: %w", err)
+		}
+		return w.Write(job.OutputPath)
+	default:
+		return fmt.Errorf("native converter does not support %s", job.Format)
+	}
+}
+
+// calibreConverter converts with calibre's ebook-convert
+type calibreConverter struct{}
+
+func (calibreConverter) Name() string             { return "calibre" }
+func (calibreConverter) Formats() []string        { return []string{"pdf", "epub", "mobi", "docx"} }
+func (calibreConverter) Version() (string, error) { return toolVersion("ebook-convert") }
+
+func (calibreConverter) Available() bool {
+	_, err := exec.LookPath("ebook-convert")
+	return err == nil
+}
+
+func (calibreConverter) Convert(job *Job) error {
+	docPath, err := job.Document()
+	if err != nil {
+		return err
+	}
+
+	args := []string{docPath, job.OutputPath,
+		"--chapter", "//*[name()='section' and @class='chapter']",
+		"--level1-toc", "//h:h1",
+		"--level2-toc", "//h:h2",
+	}
+	cfg := job.Config()
+	if cfg.Title != "" {
+		args = append(args, "--title", cfg.Title)
+	}
+	if cfg.Author != "" {
+		args = append(args, "--authors", cfg.Author)
+	}
+	if cfg.Language != "" {
+		args = append(args, "--language", cfg.Language)
+	}
+	args = append(args, calibreOptions(job)...)
+
+	return runTool(job.WorkDir, "ebook-convert", args...)
+}
+
+// pandocConverter converts with pandoc
+type pandocConverter struct{}
+
+func (pandocConverter) Name() string             { return "pandoc" }
+func (pandocConverter) Formats() []string        { return []string{"pdf", "epub", "docx"} }
+func (pandocConverter) Version() (string, error) { return toolVersion("pandoc") }
+
+func (pandocConverter) Available() bool {
+	_, err := exec.LookPath("pandoc")
+	return err == nil
+}
+
+func (pandocConverter) Convert(job *Job) error {
+	docPath, err := job.Document()
+	if err != nil {
+		return err
+	}
+
+	args := []string{docPath, "-f", "html", "-o", job.OutputPath,
+		"--resource-path", job.WorkDir, "--toc"}
+	switch job.Format {
+	case "pdf", "docx":
+		// pandoc picks the writer from the output extension
+	case "epub":
+		args = append(args, "-t", "epub3")
+	default:
+		return fmt.Errorf("pandoc does not support format: %s", job.Format)
+	}
+
+	cfg := job.Config()
+	if cfg.Title != "" {
+		args = append(args, "--metadata", "title="+cfg.Title)
+	}
+	if cfg.Author != "" {
+		args = append(args, "--metadata", "author="+cfg.Author)
+	}
+	if cfg.Language != "" {
+		args = append(args, "--metadata", "lang="+cfg.Language)
+	}
+	args = append(args, pandocOptions(job)...)
+
+	return runTool(job.WorkDir, "pandoc", args...)
+}
+
+// commandConverter runs a user-defined command line template
+type commandConverter struct {
+	template string
+}
+
+func (commandConverter) Name() string             { return "command" }
+func (commandConverter) Formats() []string        { return []string{"pdf", "epub", "mobi", "docx"} }
+func (commandConverter) Version() (string, error) { return "", nil }
+
+func (c commandConverter) Available() bool {
+	args := splitCommandLine(c.template)
+	if len(args) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(args[0])
+	return err == nil
+}
+
+func (c commandConverter) Convert(job *Job) error {
+	args := splitCommandLine(c.template)
+	if len(args) == 0 {
+		return fmt.Errorf("command converter requires ebook.command in book.json")
+	}
+
+	docPath, err := job.Document()
+	if err != nil {
+		return err
+	}
+
+	cfg := job.Config()
+	expand := strings.NewReplacer(
+		"{input}", docPath,
+		"{output}", job.OutputPath,
+		"{format}", job.Format,
+		"{title}", cfg.Title,
+		"{author}", cfg.Author,
+		"{language}", cfg.Language,
+	).Replace
+	for i := range args {
+		args[i] = expand(args[i])
+	}
+
+	return runTool(job.WorkDir, args[0], args[1:]...)
+}
+
+// splitCommandLine splits a command line template into arguments, honoring
+// single and double quotes
+func splitCommandLine(s string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,211 @@
+package ebook
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  tool  a\tb\n", []string{"tool", "a", "b"}},
+		{`tool "two words" 'single "quoted"'`, []string{"tool", "two words", `single "quoted"`}},
+		{`tool --title="{title}" x""y ''`, []string{"tool", "--title={title}", "xy", ""}},
+		{`tool "unterminated arg`, []string{"tool", "unterminated arg"}},
+	}
+	for _, tt := range tests {
+		if got := splitCommandLine(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectConverter(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // no external tools
+
+	tests := []struct {
+		name, format string
+		want         string // converter name, or a substring of the error
+		ok           bool
+	}{
+		{"", "epub", "native", true},
+		{"native", "latex", "native", true},
+		{"", "pdf", "no converter available for pdf (tried: calibre, pandoc, command)", false},
+		{"", "odt", "unsupported format: odt", false},
+		{"native", "pdf", "converter native does not support pdf", false},
+		{"pandoc", "epub", "converter pandoc is not available", false},
+		{"typo", "epub", "unknown converter: typo", false},
+	}
+	for _, tt := range tests {
+		c, err := SelectConverter(tt.name, tt.format, &book.Config{})
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("SelectConverter(%q, %q): %v", tt.name, tt.format, err)
+		case tt.ok && c.Name() != tt.want:
+			t.Errorf("SelectConverter(%q, %q) = %s, want %s", tt.name, tt.format, c.Name(), tt.want)
+		case !tt.ok && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("SelectConverter(%q, %q) error = %v, want %q", tt.name, tt.format, err, tt.want)
+		}
+	}
+}
+
+type stubConverter struct {
+	name string
+}
+
+func (c stubConverter) Name() string           { return c.name }
+func (stubConverter) Formats() []string        { return []string{"epub", "pdf"} }
+func (stubConverter) Available() bool          { return true }
+func (stubConverter) Version() (string, error) { return "1.0", nil }
+func (stubConverter) Convert(job *Job) error   { return nil }
+
+func TestRegisterConverterOverride(t *testing.T) {
+	saved := append([]registeredConverter(nil), converters...)
+	t.Cleanup(func() { converters = saved })
+
+	// Replacing a backend keeps its place in the order of preference
+	RegisterConverter("native", func(*book.Config) Converter { return stubConverter{name: "native"} })
+	c, err := SelectConverter("", "pdf", &book.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(stubConverter); !ok {
+		t.Errorf("SelectConverter picked %T, want the replacement", c)
+	}
+	if got, want := converterNames(nil), []string{"native", "calibre", "pandoc", "command"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("converters = %q, want %q", got, want)
+	}
+
+	// New backends come last
+	RegisterConverter("extra", func(*book.Config) Converter { return stubConverter{name: "extra"} })
+	if names := converterNames(nil); names[len(names)-1] != "extra" {
+		t.Errorf("converters = %q, want extra last", names)
+	}
+	if c, err := SelectConverter("extra", "epub", nil); err != nil || c.Name() != "extra" {
+		t.Errorf("SelectConverter(extra) = %v, %v", c, err)
+	}
+}
+
+func TestCommandConverter(t *testing.T) {
+	bin := t.TempDir()
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$(dirname \"$0\")/args\"\ncp \"$2\" \"$4\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "stubconv"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	files := map[string]string{
+		"book.json":  `{"title": "Stub Book", "ebook": {"command": "stubconv --in '{input}' --out \"{output}\" --title={title}"}}`,
+		"README.md":  "# Intro\n\nHello.\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := NewGenerator(root, "", "pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Converter = "command"
+	output := filepath.Join(t.TempDir(), "book.pdf")
+	if err := g.Generate(output); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(bin, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(args) != 5 || args[0] != "--in" || args[2] != "--out" || args[3] != output || args[4] != "--title=Stub Book" {
+		t.Fatalf("stubconv ran with %q", args)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "Hello.") {
+		t.Errorf("the converted document does not hold the book:\n%s", content)
+	}
+
+	// The intermediate document and the generated cover are not published
+	for _, name := range []string{documentName, "cover.jpg", "cover_small.jpg"} {
+		if _, err := os.Stat(filepath.Join(g.OutputDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was left in the output directory: %v", name, err)
+		}
+	}
+}
+
+func TestCalibreConvertsWholeBook(t *testing.T) {
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncp \"$1\" \"$2\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "ebook-convert"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	files := map[string]string{
+		"book.json":    `{"title": "Guide"}`,
+		"README.md":    "# Intro\n\nSee [setup](guide/one.md#setup).\n",
+		"guide/one.md": "# One\n\n## Setup\n\n![logo](../img/logo.png)\n",
+		"guide/two.md": "# Two\n\nLast chapter.\n",
+		"img/logo.png": "\x89PNG\r\n\x1a\n",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [One](guide/one.md)\n  * [Two](guide/two.md)\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := NewGenerator(root, "", "mobi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Converter = "calibre"
+	output := filepath.Join(t.TempDir(), "book.mobi")
+	if err := g.Generate(output); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(data)
+
+	for _, want := range []string{
+		`<section class="chapter" id="ch-README">`,
+		`<section class="chapter" id="ch-guide-one">`,
+		`<section class="chapter" id="ch-guide-two">`,
+		"Last chapter.",
+		`<a href="#ch-guide-one--setup">setup</a>`,
+		`<img src="img/logo.png" alt="logo"/>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("the converted document lacks %s", want)
+		}
+	}
+	// The site layout is left out
+	for _, chrome := range []string{"sidebar", "<script", "nav-tree"} {
+		if strings.Contains(doc, chrome) {
+			t.Errorf("the converted document has %s:\n%s", chrome, doc)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package ebook
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Standard GitBook cover sizes
+const (
+	CoverWidth       = 1800
+	CoverHeight      = 2360
+	SmallCoverWidth  = 200
+	SmallCoverHeight = 262
+)
+
+var (
+	defaultCoverBackground = color.RGBA{0x2c, 0x3e, 0x50, 0xff}
+	defaultCoverColor      = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// systemCJKFonts are tried, in order, for glyphs missing from the bundled font.
+// No CJK font is bundled: without one of these or ebook.coverStyle.font, CJK
+// text cannot be drawn and ebooks are generated without a cover.
+var systemCJKFonts = []string{
+	// Linux
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/wqy-microhei/wqy-microhei.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-zenhei.ttc",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	// macOS
+	"/System/Library/Fonts/PingFang.ttc",
+	"/System/Library/Fonts/STHeiti Medium.ttc",
+	"/Library/Fonts/Arial Unicode.ttf",
+	// Windows
+	`C:\Windows\Fonts\msyh.ttc`,
+	`C:\Windows\Fonts\simhei.ttf`,
+	`C:\Windows\Fonts\msgothic.ttc`,
+}
+
+// CoverGenerator renders a cover image from the book metadata
+type CoverGenerator struct {
+	Title           string
+	Subtitle        string
+	Author          string
+	Background      color.Color
+	BackgroundImage string // absolute path, drawn over Background
+	Color           color.Color
+	// Fonts are font files tried before the bundled Go fonts; system CJK fonts
+	// are tried after them for the remaining glyphs
+	Fonts []string
+}
+
+// NewCoverGenerator creates a cover generator from the book configuration
+func NewCoverGenerator(root string, cfg *book.Config) (*CoverGenerator, error) {
+	g := &CoverGenerator{
+		Title:      "GitBook",
+		Background: defaultCoverBackground,
+		Color:      defaultCoverColor,
+	}
+	if cfg == nil {
+		return g, nil
+	}
+
+	if cfg.Title != "" {
+		g.Title = cfg.Title
+	}
+	g.Subtitle = cfg.Subtitle
+	g.Author = cfg.Author
+
+	if cfg.Ebook == nil || cfg.Ebook.CoverStyle == nil {
+		return g, nil
+	}
+	style := cfg.Ebook.CoverStyle
+	if style.Background != "" {
+		c, err := parseHexColor(style.Background)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ebook.coverStyle.background: %w", err)
+		}
+		g.Background = c
+	}
+	if style.Color != "" {
+		c, err := parseHexColor(style.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ebook.coverStyle.color: %w", err)
+		}
+		g.Color = c
+	}
+	if style.BackgroundImage != "" {
+		g.BackgroundImage = filepath.Join(root, filepath.FromSlash(style.BackgroundImage))
+	}
+	if style.Font != "" {
+		fontPath := style.Font
+		if !filepath.IsAbs(fontPath) {
+			fontPath = filepath.Join(root, filepath.FromSlash(fontPath))
+		}
+		g.Fonts = append(g.Fonts, fontPath)
+	}
+	return g, nil
+}
+
+// MissingGlyphsError reports cover text that none of the fonts can draw
+type MissingGlyphsError struct {
+	Chars string
+}
+
+func (e *MissingGlyphsError) Error() string {
+	return fmt.Sprintf("no font can draw %q on the cover: set ebook.coverStyle.font to a font file that has these characters, or install a CJK font such as Noto Sans CJK", e.Chars)
+}
+
+// Render draws the cover at the standard size
+func (g *CoverGenerator) Render() (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, CoverWidth, CoverHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(g.Background), image.Point{}, draw.Src)
+
+	if g.BackgroundImage != "" {
+		if err := drawBackgroundImage(img, g.BackgroundImage); err != nil {
+			return nil, fmt.Errorf("failed to load cover background: %w", err)
+		}
+	}
+
+	regular, err := g.loadFonts(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := g.loadFonts(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	if missing := missingGlyphs(regular, g.Title+g.Subtitle+g.Author); missing != "" {
+		return nil, &MissingGlyphsError{Chars: missing}
+	}
+
+	const margin = 150
+	width := CoverWidth - 2*margin
+	ink := image.NewUniform(g.Color)
+
+	// Title: shrink until it fits in four lines
+	size := 160.0
+	lines := wrapText(g.Title, bold.faces(size), width)
+	for len(lines) > 4 && size > 60 {
+		size -= 10
+		lines = wrapText(g.Title, bold.faces(size), width)
+	}
+	y := CoverHeight * 28 / 100
+	y = drawLines(img, ink, bold.faces(size), lines, y, size*1.25)
+
+	// Accent rule between the title and the subtitle
+	y += 60
+	rule := image.Rect(CoverWidth/2-150, y, CoverWidth/2+150, y+8)
+	draw.Draw(img, rule, ink, image.Point{}, draw.Over)
+	y += 8 + 120
+
+	if g.Subtitle != "" {
+		lines := wrapText(g.Subtitle, regular.faces(72), width)
+		drawLines(img, ink, regular.faces(72), lines, y, 90)
+	}
+
+	if g.Author != "" {
+		lines := wrapText(g.Author, regular.faces(64), width)
+		drawLines(img, ink, regular.faces(64), lines, CoverHeight-200-80*(len(lines)-1), 80)
+	}
+
+	return img, nil
+}
+
+// WriteFiles renders the cover and writes cover.jpg and cover_small.jpg into dir
+func (g *CoverGenerator) WriteFiles(dir string) error {
+	img, err := g.Render()
+	if err != nil {
+		return err
+	}
+
+	small := image.NewRGBA(image.Rect(0, 0, SmallCoverWidth, SmallCoverHeight))
+	draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	if err := writeJPEG(filepath.Join(dir, "cover.jpg"), img); err != nil {
+		return err
+	}
+	return writeJPEG(filepath.Join(dir, "cover_small.jpg"), small)
+}
+
+func writeJPEG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: 90}); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	return f.Close()
+}
+
+// drawBackgroundImage scales the image at path to fill dst, cropping the overflow
+func drawBackgroundImage(dst *image.RGBA, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+
+	sb, db := src.Bounds(), dst.Bounds()
+	if sb.Empty() {
+		return nil
+	}
+	// Crop the source to the aspect ratio of the cover
+	crop := sb
+	if sb.Dx()*db.Dy() > sb.Dy()*db.Dx() {
+		w := sb.Dy() * db.Dx() / db.Dy()
+		crop.Min.X += (sb.Dx() - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := sb.Dx() * db.Dy() / db.Dx()
+		crop.Min.Y += (sb.Dy() - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+	draw.CatmullRom.Scale(dst, db, src, crop, draw.Over, nil)
+	return nil
+}
+
+// fontChain holds fonts in order of preference; each glyph is drawn with the
+// first font that has it
+type fontChain []*opentype.Font
+
+// loadFonts builds the chain: configured fonts, the bundled font, then system CJK fonts
+func (g *CoverGenerator) loadFonts(bundled []byte) (fontChain, error) {
+	var chain fontChain
+	for _, path := range g.Fonts {
+		fonts, err := loadFontFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load font %s: %w", path, err)
+		}
+		chain = append(chain, fonts...)
+	}
+
+	f, err := opentype.Parse(bundled)
+	if err != nil {
+		return nil, err
+	}
+	chain = append(chain, f)
+
+	for _, path := range systemCJKFonts {
+		if fonts, err := loadFontFile(path); err == nil {
+			chain = append(chain, fonts[0])
+			break
+		}
+	}
+	return chain, nil
+}
+
+// loadFontFile parses a font file or the fonts of a collection (.ttc)
+func loadFontFile(path string) ([]*opentype.Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	var fonts []*opentype.Font
+	for i := 0; i < collection.NumFonts(); i++ {
+		f, err := collection.Font(i)
+		if err != nil {
+			return nil, err
+		}
+		fonts = append(fonts, f)
+	}
+	if len(fonts) == 0 {
+		return nil, fmt.Errorf("no fonts in %s", filepath.Base(path))
+	}
+	return fonts, nil
+}
+
+// faces returns a face per font of the chain at size pixels
+func (c fontChain) faces(size float64) []font.Face {
+	var faces []font.Face
+	for _, f := range c {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err == nil {
+			faces = append(faces, face)
+		}
+	}
+	return faces
+}
+
+// missingGlyphs returns the characters of s that no font of the chain can draw
+func missingGlyphs(c fontChain, s string) string {
+	faces := c.faces(12)
+	var missing []rune
+	for _, r := range s {
+		if unicode.IsSpace(r) || strings.ContainsRune(string(missing), r) {
+			continue
+		}
+		if _, ok := faceFor(faces, r); !ok {
+			missing = append(missing, r)
+		}
+	}
+	return string(missing)
+}
+
+// faceFor returns the first face having a glyph for r
+func faceFor(faces []font.Face, r rune) (font.Face, bool) {
+	for _, face := range faces {
+		if _, ok := face.GlyphAdvance(r); ok {
+			return face, true
+		}
+	}
+	return faces[0], false
+}
+
+func measureText(faces []font.Face, s string) fixed.Int26_6 {
+	var width fixed.Int26_6
+	for _, r := range s {
+		face, _ := faceFor(faces, r)
+		adv, _ := face.GlyphAdvance(r)
+		width += adv
+	}
+	return width
+}
+
+// wrapText breaks s into lines no wider than width pixels. Latin text breaks
+// between words, CJK text between any two characters.
+func wrapText(s string, faces []font.Face, width int) []string {
+	limit := fixed.I(width)
+
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.Join(strings.Fields(s), " ") {
+		switch {
+		case r == ' ':
+			flush()
+			tokens = append(tokens, " ")
+		case isWideRune(r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+
+	var lines []string
+	var line string
+	for _, tok := range tokens {
+		if line == "" && tok == " " {
+			continue
+		}
+		if line != "" && measureText(faces, line+tok) > limit {
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = strings.TrimLeft(tok, " ")
+			continue
+		}
+		line += tok
+	}
+	if line = strings.TrimRight(line, " "); line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// drawLines draws centered lines with their first baseline at y and returns
+// the baseline of the last line
+func drawLines(dst *image.RGBA, ink image.Image, faces []font.Face, lines []string, y int, lineHeight float64) int {
+	baseline := y
+	for i, line := range lines {
+		baseline = y + int(float64(i)*lineHeight)
+		x := fixed.I(CoverWidth/2) - measureText(faces, line)/2
+		for _, r := range line {
+			face, _ := faceFor(faces, r)
+			d := &font.Drawer{Dst: dst, Src: ink, Face: face, Dot: fixed.Point26_6{X: x, Y: fixed.I(baseline)}}
+			d.DrawString(string(r))
+			x = d.Dot.X
+		}
+	}
+	return baseline
+}
+
+// parseHexColor parses #rgb and #rrggbb colors
+func parseHexColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("expected #rgb or #rrggbb, got %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("expected #rgb or #rrggbb, got %q", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
@@ -0,0 +1,94 @@
+package ebook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+func TestCoverWithoutCJKFont(t *testing.T) {
+	saved := systemCJKFonts
+	systemCJKFonts = nil
+	defer func() { systemCJKFonts = saved }()
+
+	g, err := NewCoverGenerator(t.TempDir(), &book.Config{Title: "Guide", Author: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Render(); err != nil {
+		t.Fatalf("a Latin cover failed: %v", err)
+	}
+
+	g.Title = "Go 指南"
+	_, err = g.Render()
+	if err == nil || !strings.Contains(err.Error(), `"指南"`) || !strings.Contains(err.Error(), "ebook.coverStyle.font") {
+		t.Errorf("error = %v, want one naming the characters and ebook.coverStyle.font", err)
+	}
+}
+
+func TestCoverPath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cover.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := coverPath(root, &book.Config{}); got != "cover.png" || err != nil {
+		t.Errorf("default cover = %q, %v; want cover.png", got, err)
+	}
+
+	// A configured cover never falls back to the default one
+	cfg := &book.Config{Ebook: &book.Ebook{Cover: "images/front.jpg"}}
+	if _, err := coverPath(root, cfg); err == nil || !strings.Contains(err.Error(), "images/front.jpg") {
+		t.Errorf("error = %v, want one naming images/front.jpg", err)
+	}
+
+	// Covers outside the book are refused
+	for _, cover := range []string{"../../x.jpg", filepath.Join(root, "cover.png")} {
+		cfg := &book.Config{Ebook: &book.Ebook{Cover: cover}}
+		if got, err := coverPath(root, cfg); err == nil {
+			t.Errorf("coverPath(%s) = %q, want an error", cover, got)
+		}
+	}
+}
+
+func TestGenerateWithoutCoverFont(t *testing.T) {
+	saved := systemCJKFonts
+	systemCJKFonts = nil
+	defer func() { systemCJKFonts = saved }()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"book.json":  `{"title": "Go 语言指南"}`,
+		"README.md":  "# Intro\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, err := NewGenerator(root, "", "epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Converter = "native"
+	output := filepath.Join(t.TempDir(), "book.epub")
+	if err := g.Generate(output); err != nil {
+		t.Fatalf("the ebook failed without a cover font: %v", err)
+	}
+
+	if len(g.Warnings) != 1 || !strings.Contains(g.Warnings[0], `"语言指南"`) {
+		t.Errorf("warnings = %q, want one naming the characters", g.Warnings)
+	}
+	for name := range readZip(t, output) {
+		if strings.Contains(name, "cover") {
+			t.Errorf("the ebook has a cover: %s", name)
+		}
+	}
+	if errs := validationErrors(t, output); len(errs) > 0 {
+		t.Errorf("the ebook has errors:\n%s", strings.Join(errs, "\n"))
+	}
+}
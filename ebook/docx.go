@@ -0,0 +1,779 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+//go:embed static/docx-styles.xml
+var docxStyles []byte
+
+const (
+	docxNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"` +
+		` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+		` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"` +
+		` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+		` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"`
+	docxRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+	bulletList  = 0 // abstract numbering of bullet lists
+	orderedList = 1 // abstract numbering of ordered lists
+
+	emuPerPixel = 9525 // at 96 DPI
+	emuPerTwip  = 635
+)
+
+// docxPageSizes maps pdf.paperSize values to page sizes in twips
+var docxPageSizes = map[string][2]int{
+	"a4":     {11906, 16838},
+	"a5":     {8391, 11906},
+	"letter": {12240, 15840},
+	"legal":  {12240, 20160},
+}
+
+// docxImageTypes lists the image formats that can be embedded
+var docxImageTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// DOCXWriter writes an Office Open XML document from the goldmark AST of each
+// chapter, without external tools. Raw HTML is dropped.
+type DOCXWriter struct {
+	Config   *book.Config
+	BookRoot string
+	Chapters []builder.RenderedChapter
+	parse    func(chapterPath string) (ast.Node, []byte, error)
+}
+
+// docxRel is a relationship of the main document part
+type docxRel struct {
+	ID       string
+	Type     string
+	Target   string
+	External bool
+}
+
+// docxMedia is an image part
+type docxMedia struct {
+	Name string // relative to the word directory
+	Data []byte
+}
+
+// docxNum is a list instance; each list restarts its numbering
+type docxNum struct {
+	Abstract int
+	Level    int
+	Start    int
+}
+
+// docxPara holds the properties of a paragraph
+type docxPara struct {
+	Style  string
+	NumID  int // 0 for none
+	Level  int
+	Indent int // left indent in twips
+	Align  string
+}
+
+// docxRun holds the formatting of a run
+type docxRun struct {
+	Bold, Italic, Strike, Code, Link bool
+}
+
+// docxDocument accumulates the main document part while walking chapters
+type docxDocument struct {
+	w          *DOCXWriter
+	body       strings.Builder
+	rels       []docxRel
+	media      []docxMedia
+	images     map[string]string // source path -> relationship ID
+	nums       []docxNum
+	bookmarks  map[string]string // chapter path -> bookmark name
+	bookmarkID int
+	drawingID  int
+	textWidth  int // in twips
+
+	// state of the chapter being written
+	source         []byte
+	dir            string
+	number         string
+	headingOffset  int
+	listLevel      int
+	pageBreak      bool
+	pendingAnchors []string
+}
+
+// NewDOCXWriter prepares the chapters of a book for writing
+func NewDOCXWriter(b *builder.Builder) (*DOCXWriter, error) {
+	chapters, err := b.RenderChapters()
+	if err != nil {
+		return nil, err
+	}
+	return &DOCXWriter{
+		Config:   b.Book.Config,
+		BookRoot: b.Book.Root,
+		Chapters: chapters,
+		parse:    b.ParseChapter,
+	}, nil
+}
+
+// Write writes the document to outputPath
+func (w *DOCXWriter) Write(outputPath string) error {
+	d := &docxDocument{
+		w:         w,
+		images:    make(map[string]string),
+		bookmarks: make(map[string]string),
+	}
+	pageWidth, pageHeight := w.pageSize()
+	d.textWidth = pageWidth - 2*1440
+
+	if err := d.writeBody(); err != nil {
+		return err
+	}
+
+	var doc strings.Builder
+	doc.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	doc.WriteString(`<w:document ` + docxNamespaces + `><w:body>`)
+	doc.WriteString(d.body.String())
+	fmt.Fprintf(&doc, `<w:sectPr><w:pgSz w:w="%d" w:h="%d"/>`, pageWidth, pageHeight)
+	doc.WriteString(`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr>`)
+	doc.WriteString("</w:body></w:document>\n")
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", []byte(d.contentTypes())},
+		{"_rels/.rels", []byte(docxPackageRels)},
+		{"docProps/core.xml", []byte(w.coreProperties())},
+		{"word/document.xml", []byte(doc.String())},
+		{"word/_rels/document.xml.rels", []byte(d.documentRels())},
+		{"word/styles.xml", docxStyles},
+		{"word/numbering.xml", []byte(d.numbering())},
+		{"word/settings.xml", []byte(docxSettings)},
+	}
+	for _, file := range files {
+		if err := writeZipFile(zw, file.name, file.data); err != nil {
+			return err
+		}
+	}
+	for _, m := range d.media {
+		if err := writeZipFile(zw, "word/"+m.Name, m.Data); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (w *DOCXWriter) title() string {
+	if w.Config != nil && w.Config.Title != "" {
+		return w.Config.Title
+	}
+	return "GitBook"
+}
+
+func (w *DOCXWriter) pageSize() (int, int) {
+	if w.Config != nil && w.Config.PDF != nil {
+		if size, ok := docxPageSizes[strings.ToLower(w.Config.PDF.PaperSize)]; ok {
+			return size[0], size[1]
+		}
+	}
+	return docxPageSizes["a4"][0], docxPageSizes["a4"][1]
+}
+
+// writeBody writes the title page, the table of contents and every chapter
+func (d *docxDocument) writeBody() error {
+	w := d.w
+	d.paragraph(docxPara{Style: "Title"}, func() { d.text(w.title(), docxRun{}) })
+	if w.Config != nil && w.Config.Subtitle != "" {
+		d.paragraph(docxPara{Style: "Subtitle"}, func() { d.text(w.Config.Subtitle, docxRun{}) })
+	}
+	if w.Config != nil && w.Config.Author != "" {
+		d.paragraph(docxPara{Style: "Subtitle"}, func() { d.text(w.Config.Author, docxRun{}) })
+	}
+
+	// Word fills the TOC field in when the document is opened (see updateFields in settings.xml)
+	tocTitle := "Contents"
+	if w.Config != nil && strings.HasPrefix(strings.ToLower(w.Config.Language), "zh") {
+		tocTitle = "目录"
+	}
+	d.paragraph(docxPara{Style: "TOCHeading"}, func() { d.text(tocTitle, docxRun{}) })
+	d.body.WriteString(`<w:p><w:r><w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>`)
+	d.body.WriteString(`<w:r><w:instrText xml:space="preserve"> TOC \o "1-3" \h \z \u </w:instrText></w:r>`)
+	d.body.WriteString(`<w:r><w:fldChar w:fldCharType="separate"/></w:r>`)
+	d.body.WriteString(`<w:r><w:t>Update the field to show the table of contents.</w:t></w:r>`)
+	d.body.WriteString(`<w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`)
+
+	// Bookmark every chapter so that links between chapters keep working
+	chapterCount := 0
+	for _, ch := range w.Chapters {
+		if ch.Path != "" && d.bookmarks[ch.Path] == "" {
+			chapterCount++
+			name := fmt.Sprintf("chapter_%d", chapterCount)
+			d.bookmarks[ch.Path] = name
+			d.bookmarks[ch.HTMLPath] = name
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, ch := range w.Chapters {
+		if ch.Path != "" && seen[ch.Path] {
+			continue
+		}
+		d.pageBreak = ch.Level == 1
+		d.headingOffset = ch.Level - 1
+
+		if ch.Path == "" {
+			// Title-only summary entries become headings
+			d.paragraph(docxPara{Style: d.headingStyle(1)}, func() { d.text(ch.Label(), docxRun{}) })
+			continue
+		}
+		seen[ch.Path] = true
+
+		doc, source, err := w.parse(ch.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ch.Path, err)
+		}
+		d.source = source
+		d.dir = path.Dir(ch.Path)
+		d.number = ch.Number
+		d.pendingAnchors = append(d.pendingAnchors, d.bookmarks[ch.Path])
+		d.blocks(doc, docxPara{})
+	}
+	return nil
+}
+
+func (d *docxDocument) headingStyle(level int) string {
+	level += d.headingOffset
+	if level > 6 {
+		level = 6
+	}
+	return fmt.Sprintf("Heading%d", level)
+}
+
+// paragraph writes a paragraph whose runs are produced by content
+func (d *docxDocument) paragraph(p docxPara, content func()) {
+	var props strings.Builder
+	if p.Style != "" {
+		props.WriteString(`<w:pStyle w:val="` + p.Style + `"/>`)
+	}
+	if d.pageBreak {
+		props.WriteString("<w:pageBreakBefore/>")
+		d.pageBreak = false
+	}
+	if p.NumID != 0 {
+		fmt.Fprintf(&props, `<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, p.Level, p.NumID)
+	} else if p.Indent > 0 {
+		fmt.Fprintf(&props, `<w:ind w:left="%d"/>`, p.Indent)
+	}
+	if p.Align != "" {
+		props.WriteString(`<w:jc w:val="` + p.Align + `"/>`)
+	}
+	d.body.WriteString("<w:p>")
+	if props.Len() > 0 {
+		d.body.WriteString("<w:pPr>" + props.String() + "</w:pPr>")
+	}
+
+	for _, name := range d.pendingAnchors {
+		d.bookmarkID++
+		fmt.Fprintf(&d.body, `<w:bookmarkStart w:id="%d" w:name="%s"/><w:bookmarkEnd w:id="%d"/>`, d.bookmarkID, name, d.bookmarkID)
+	}
+	d.pendingAnchors = nil
+
+	content()
+	d.body.WriteString("</w:p>")
+}
+
+// blocks writes the block children of n
+func (d *docxDocument) blocks(n ast.Node, p docxPara) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		d.block(c, p)
+	}
+}
+
+func (d *docxDocument) block(n ast.Node, p docxPara) {
+	switch n := n.(type) {
+	case *ast.Heading:
+		d.paragraph(docxPara{Style: d.headingStyle(n.Level), Indent: p.Indent}, func() {
+			if d.number != "" {
+				d.text(d.number+" ", docxRun{})
+				d.number = ""
+			}
+			d.inlines(n, docxRun{})
+		})
+	case *ast.Paragraph, *ast.TextBlock:
+		d.paragraph(p, func() { d.inlines(n, docxRun{}) })
+	case *ast.Blockquote:
+		p.Style = "Quote"
+		p.NumID = 0
+		d.blocks(n, p)
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		d.codeBlock(n, p)
+	case *ast.List:
+		d.list(n, p)
+	case *ast.ThematicBreak:
+		d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`)
+	case *east.Table:
+		d.table(n, p)
+	case *builder.MathBlock:
+		d.paragraph(docxPara{Align: "center", Indent: p.Indent}, func() {
+			d.text(string(n.Value(d.source)), docxRun{Italic: true})
+		})
+	case *east.Footnote:
+		// Footnotes are listed at the end of the chapter, numbered like their references
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			if c == n.FirstChild() {
+				if para, ok := c.(*ast.Paragraph); ok {
+					d.paragraph(p, func() {
+						d.text(fmt.Sprintf("[%d] ", n.Index), docxRun{})
+						d.inlines(para, docxRun{})
+					})
+					continue
+				}
+			}
+			d.block(c, p)
+		}
+	case *ast.HTMLBlock:
+		// Raw HTML has no Word equivalent
+	default:
+		d.blocks(n, p)
+	}
+}
+
+// codeBlock writes a code block as one monospace paragraph, lines separated by breaks
+func (d *docxDocument) codeBlock(n ast.Node, p docxPara) {
+	lines := n.Lines()
+	d.paragraph(docxPara{Style: "SourceCode", Indent: p.Indent}, func() {
+		for i := 0; i < lines.Len(); i++ {
+			if i > 0 {
+				d.body.WriteString("<w:r><w:br/></w:r>")
+			}
+			line := lines.At(i)
+			d.text(strings.TrimRight(string(line.Value(d.source)), "\r\n"), docxRun{})
+		}
+	})
+}
+
+// list writes the items of a list; the first paragraph of an item carries the
+// bullet or number, the following blocks are indented to match
+func (d *docxDocument) list(l *ast.List, p docxPara) {
+	level := d.listLevel
+	if level > 8 {
+		level = 8
+	}
+	num := docxNum{Abstract: bulletList, Level: level, Start: 1}
+	if l.IsOrdered() {
+		num.Abstract = orderedList
+		num.Start = l.Start
+	}
+	d.nums = append(d.nums, num)
+	numID := len(d.nums)
+
+	d.listLevel++
+	defer func() { d.listLevel-- }()
+
+	for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+		first := true
+		for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+			cp := docxPara{Style: "ListParagraph", Indent: 720 * (level + 1)}
+			if p.Style == "Quote" {
+				cp.Style = p.Style
+			}
+			if first {
+				cp.NumID, cp.Level = numID, level
+				first = false
+			}
+			d.block(c, cp)
+		}
+	}
+}
+
+// table writes a GFM table, repeating the header row on every page
+func (d *docxDocument) table(t *east.Table, p docxPara) {
+	columns := len(t.Alignments)
+	if columns == 0 {
+		return
+	}
+	width := (d.textWidth - p.Indent) / columns
+
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="0" w:type="auto"/>`)
+	if p.Indent > 0 {
+		fmt.Fprintf(&d.body, `<w:tblInd w:w="%d" w:type="dxa"/>`, p.Indent)
+	}
+	d.body.WriteString(`<w:tblLook w:val="04A0" w:firstRow="1" w:lastRow="0" w:firstColumn="0" w:lastColumn="0" w:noHBand="0" w:noVBand="1"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(&d.body, `<w:gridCol w:w="%d"/>`, width)
+	}
+	d.body.WriteString("</w:tblGrid>")
+
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		_, header := row.(*east.TableHeader)
+		d.body.WriteString("<w:tr>")
+		if header {
+			d.body.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			align := ""
+			if c, ok := cell.(*east.TableCell); ok {
+				switch c.Alignment {
+				case east.AlignLeft:
+					align = "left"
+				case east.AlignCenter:
+					align = "center"
+				case east.AlignRight:
+					align = "right"
+				}
+			}
+			fmt.Fprintf(&d.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr>`, width)
+			d.paragraph(docxPara{Align: align}, func() { d.inlines(cell, docxRun{Bold: header}) })
+			d.body.WriteString("</w:tc>")
+		}
+		d.body.WriteString("</w:tr>")
+	}
+	d.body.WriteString("</w:tbl>")
+	// Word requires a paragraph between consecutive tables
+	d.body.WriteString("<w:p/>")
+}
+
+var htmlBreak = regexp.MustCompile(`(?i)^<br\s*/?>$`)
+
+// inlines writes the inline children of n as runs
+func (d *docxDocument) inlines(n ast.Node, r docxRun) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			d.text(string(c.Segment.Value(d.source)), r)
+			if c.HardLineBreak() {
+				d.body.WriteString("<w:r><w:br/></w:r>")
+			} else if c.SoftLineBreak() {
+				d.text(" ", r)
+			}
+		case *ast.String:
+			d.text(string(c.Value), r)
+		case *ast.CodeSpan:
+			code := r
+			code.Code = true
+			d.inlines(c, code)
+		case *ast.Emphasis:
+			em := r
+			if c.Level >= 2 {
+				em.Bold = true
+			} else {
+				em.Italic = true
+			}
+			d.inlines(c, em)
+		case *east.Strikethrough:
+			del := r
+			del.Strike = true
+			d.inlines(c, del)
+		case *ast.Link:
+			d.hyperlink(string(c.Destination), r, func(r docxRun) { d.inlines(c, r) })
+		case *ast.AutoLink:
+			url := string(c.URL(d.source))
+			if c.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(url, "mailto:") {
+				url = "mailto:" + url
+			}
+			label := string(c.Label(d.source))
+			d.hyperlink(url, r, func(r docxRun) { d.text(label, r) })
+		case *ast.Image:
+			d.image(c, r)
+		case *east.FootnoteLink:
+			d.text(fmt.Sprintf("[%d]", c.Index), r)
+		case *builder.Math:
+			math := r
+			math.Italic = true
+			d.text(string(c.Value), math)
+		case *east.TaskCheckBox:
+			if c.IsChecked {
+				d.text("☒ ", r)
+			} else {
+				d.text("☐ ", r)
+			}
+		case *ast.RawHTML:
+			var raw strings.Builder
+			for i := 0; i < c.Segments.Len(); i++ {
+				seg := c.Segments.At(i)
+				raw.Write(seg.Value(d.source))
+			}
+			if htmlBreak.MatchString(strings.TrimSpace(raw.String())) {
+				d.body.WriteString("<w:r><w:br/></w:r>")
+			}
+		default:
+			d.inlines(c, r)
+		}
+	}
+}
+
+// text writes s as a run, turning tabs into Word tabs
+func (d *docxDocument) text(s string, r docxRun) {
+	s = strings.Map(func(c rune) rune {
+		if c < 0x20 && c != '\t' {
+			return -1 // not allowed in XML
+		}
+		return c
+	}, s)
+	if s == "" {
+		return
+	}
+
+	d.body.WriteString("<w:r>")
+	if props := r.properties(); props != "" {
+		d.body.WriteString("<w:rPr>" + props + "</w:rPr>")
+	}
+	for i, part := range strings.Split(s, "\t") {
+		if i > 0 {
+			d.body.WriteString("<w:tab/>")
+		}
+		if part != "" {
+			d.body.WriteString(`<w:t xml:space="preserve">` + xmlEscape(part) + "</w:t>")
+		}
+	}
+	d.body.WriteString("</w:r>")
+}
+
+func (r docxRun) properties() string {
+	var sb strings.Builder
+	switch {
+	case r.Code:
+		sb.WriteString(`<w:rStyle w:val="VerbatimChar"/>`)
+	case r.Link:
+		sb.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+	}
+	if r.Bold {
+		sb.WriteString("<w:b/>")
+	}
+	if r.Italic {
+		sb.WriteString("<w:i/>")
+	}
+	if r.Strike {
+		sb.WriteString("<w:strike/>")
+	}
+	return sb.String()
+}
+
+// hyperlink writes a link to an external URL or to another chapter. Links to
+// other local files are written as plain text.
+func (d *docxDocument) hyperlink(dest string, r docxRun, content func(docxRun)) {
+	link := r
+	link.Link = true
+
+	if target, _, ok := builder.ResolveRef(d.dir, dest); ok {
+		if name, ok := d.bookmarks[target]; ok {
+			d.body.WriteString(`<w:hyperlink w:anchor="` + name + `" w:history="1">`)
+			content(link)
+			d.body.WriteString("</w:hyperlink>")
+			return
+		}
+		content(r)
+		return
+	}
+	if dest == "" || strings.HasPrefix(dest, "#") {
+		content(r)
+		return
+	}
+
+	id := d.addRel("hyperlink", dest, true)
+	d.body.WriteString(`<w:hyperlink r:id="` + id + `" w:history="1">`)
+	content(link)
+	d.body.WriteString("</w:hyperlink>")
+}
+
+// image embeds a local PNG, JPEG or GIF image, scaled down to the text width.
+// Other images are replaced by their alternative text.
+func (d *docxDocument) image(img *ast.Image, r docxRun) {
+	alt := plainText(img, d.source)
+
+	target, _, ok := builder.ResolveRef(d.dir, string(img.Destination))
+	if !ok {
+		d.text(alt, r)
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(d.w.BookRoot, filepath.FromSlash(target)))
+	if err != nil {
+		d.text(alt, r)
+		return
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if _, ok := docxImageTypes[format]; err != nil || !ok || cfg.Width == 0 || cfg.Height == 0 {
+		d.text(alt, r)
+		return
+	}
+
+	id, ok := d.images[target]
+	if !ok {
+		name := fmt.Sprintf("media/image%d.%s", len(d.media)+1, format)
+		d.media = append(d.media, docxMedia{Name: name, Data: data})
+		id = d.addRel("image", name, false)
+		d.images[target] = id
+	}
+
+	cx, cy := cfg.Width*emuPerPixel, cfg.Height*emuPerPixel
+	if maxWidth := d.textWidth * emuPerTwip; cx > maxWidth {
+		cy = cy * maxWidth / cx
+		cx = maxWidth
+	}
+
+	d.drawingID++
+	fmt.Fprintf(&d.body, `<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0"><wp:extent cx="%d" cy="%d"/>`, cx, cy)
+	fmt.Fprintf(&d.body, `<wp:docPr id="%d" name="Picture %d" descr="%s"/>`, d.drawingID, d.drawingID, xmlEscape(alt))
+	d.body.WriteString(`<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect="1"/></wp:cNvGraphicFramePr>`)
+	d.body.WriteString(`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic>`)
+	fmt.Fprintf(&d.body, `<pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`, d.drawingID, xmlEscape(path.Base(target)))
+	fmt.Fprintf(&d.body, `<pic:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`, id)
+	fmt.Fprintf(&d.body, `<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`, cx, cy)
+	d.body.WriteString(`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`)
+}
+
+// plainText returns the text content of an inline node
+func plainText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			sb.Write(c.Segment.Value(source))
+		case *ast.String:
+			sb.Write(c.Value)
+		default:
+			sb.WriteString(plainText(c, source))
+		}
+	}
+	return sb.String()
+}
+
+// addRel adds a relationship to the main document part and returns its ID.
+// rId1 to rId3 are reserved for styles, numbering and settings.
+func (d *docxDocument) addRel(kind, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(d.rels)+4)
+	d.rels = append(d.rels, docxRel{ID: id, Type: docxRelationships + "/" + kind, Target: target, External: external})
+	return id
+}
+
+func (d *docxDocument) documentRels() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	rels := append([]docxRel{
+		{ID: "rId1", Type: docxRelationships + "/styles", Target: "styles.xml"},
+		{ID: "rId2", Type: docxRelationships + "/numbering", Target: "numbering.xml"},
+		{ID: "rId3", Type: docxRelationships + "/settings", Target: "settings.xml"},
+	}, d.rels...)
+	for _, rel := range rels {
+		mode := ""
+		if rel.External {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&sb, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, rel.ID, rel.Type, xmlEscape(rel.Target), mode)
+	}
+	sb.WriteString("</Relationships>\n")
+	return sb.String()
+}
+
+func (d *docxDocument) contentTypes() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	for _, format := range []string{"png", "jpeg", "gif"} {
+		fmt.Fprintf(&sb, `<Default Extension="%s" ContentType="%s"/>`, format, docxImageTypes[format])
+	}
+	sb.WriteString(`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>`)
+	sb.WriteString(`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>`)
+	sb.WriteString(`<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>`)
+	sb.WriteString(`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>`)
+	sb.WriteString("</Types>\n")
+	return sb.String()
+}
+
+// numbering writes the bullet and ordered list definitions and one instance per list
+func (d *docxDocument) numbering() string {
+	bullets := []string{"•", "◦", "▪"}
+	formats := []string{"decimal", "lowerLetter", "lowerRoman"}
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	for _, abstract := range []int{bulletList, orderedList} {
+		fmt.Fprintf(&sb, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, abstract)
+		for lvl := 0; lvl < 9; lvl++ {
+			format, text := "bullet", bullets[lvl%len(bullets)]
+			if abstract == orderedList {
+				format, text = formats[lvl%len(formats)], fmt.Sprintf("%%%d.", lvl+1)
+			}
+			fmt.Fprintf(&sb, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%s"/><w:lvlJc w:val="left"/>`, lvl, format, text)
+			fmt.Fprintf(&sb, `<w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`, 720*(lvl+1))
+		}
+		sb.WriteString("</w:abstractNum>")
+	}
+	for i, num := range d.nums {
+		fmt.Fprintf(&sb, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`, i+1, num.Abstract)
+		fmt.Fprintf(&sb, `<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="%d"/></w:lvlOverride></w:num>`, num.Level, num.Start)
+	}
+	sb.WriteString("</w:numbering>\n")
+	return sb.String()
+}
+
+func (w *DOCXWriter) coreProperties() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`)
+	sb.WriteString("<dc:title>" + xmlEscape(w.title()) + "</dc:title>")
+	if w.Config != nil {
+		if w.Config.Author != "" {
+			sb.WriteString("<dc:creator>" + xmlEscape(w.Config.Author) + "</dc:creator>")
+		}
+		if w.Config.Description != "" {
+			sb.WriteString("<dc:description>" + xmlEscape(w.Config.Description) + "</dc:description>")
+		}
+		if w.Config.Language != "" {
+			sb.WriteString("<dc:language>" + xmlEscape(w.Config.Language) + "</dc:language>")
+		}
+	}
+	now := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+	sb.WriteString(`<dcterms:created xsi:type="dcterms:W3CDTF">` + now + "</dcterms:created>")
+	sb.WriteString(`<dcterms:modified xsi:type="dcterms:W3CDTF">` + now + "</dcterms:modified>")
+	sb.WriteString("</cp:coreProperties>\n")
+	return sb.String()
+}
+
+const docxPackageRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	"</Relationships>\n"
+
+const docxSettings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:updateFields w:val="true"/><w:defaultTabStop w:val="720"/>` +
+	`<w:compat><w:compatSetting w:name="compatibilityMode" w:uri="http://schemas.microsoft.com/office/word" w:val="15"/></w:compat>` +
+	"</w:settings>\n"
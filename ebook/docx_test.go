@@ -0,0 +1,118 @@
+package ebook
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+// newTestBuilder writes files into a new book and returns its builder
+func newTestBuilder(t *testing.T, files map[string]string) *builder.Builder {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := builder.NewBuilder(root, filepath.Join(root, "_book"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// readZip returns the entries of a zip file by name
+func readZip(t *testing.T, file string) map[string]string {
+	t.Helper()
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	entries := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(data)
+	}
+	return entries
+}
+
+func TestDOCXWriter(t *testing.T) {
+	b := newTestBuilder(t, map[string]string{
+		"book.json":  `{"title": "Guide", "author": "Ann"}`,
+		"README.md":  "# Intro\n\nSee [one](one.md).\n",
+		"one.md":     "# One\n\n## Steps\n\n1. first\n2. second\n\n- a\n  - b\n",
+		"one/two.md": "# Two\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n* [One](one.md)\n  * [Two](one/two.md)\n",
+	})
+	w, err := NewDOCXWriter(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "book.docx")
+	if err := w.Write(out); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readZip(t, out)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/numbering.xml", "word/styles.xml"} {
+		content, ok := entries[name]
+		if !ok {
+			t.Fatalf("%s is missing", name)
+		}
+		if err := xml.Unmarshal([]byte(content), new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed: %v", name, err)
+		}
+	}
+
+	// Headings are shifted by the level of their chapter in the summary
+	doc := entries["word/document.xml"]
+	headings := regexp.MustCompile(`<w:pStyle w:val="(Heading\d)"/>.*?<w:t[^>]*>([^<]*)</w:t>`).FindAllStringSubmatch(doc, -1)
+	var got []string
+	for _, h := range headings {
+		got = append(got, h[1]+" "+h[2])
+	}
+	want := []string{"Heading1 Intro", "Heading1 One", "Heading2 Steps", "Heading2 Two"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("headings = %q, want %q", got, want)
+	}
+	if !strings.Contains(doc, `<w:hyperlink w:anchor="chapter_2"`) {
+		t.Error("the link to one.md does not point to its bookmark")
+	}
+
+	// Each list gets its own numbering instance, at its nesting level
+	if !strings.Contains(doc, `<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr>`) ||
+		!strings.Contains(doc, `<w:numPr><w:ilvl w:val="1"/><w:numId w:val="3"/></w:numPr>`) {
+		t.Errorf("list paragraphs lack their numbering:\n%s", doc)
+	}
+	numbering := entries["word/numbering.xml"]
+	for _, num := range []string{
+		`<w:num w:numId="1"><w:abstractNumId w:val="1"/>`, // ordered
+		`<w:num w:numId="2"><w:abstractNumId w:val="0"/>`, // bullets
+		`<w:num w:numId="3"><w:abstractNumId w:val="0"/><w:lvlOverride w:ilvl="1">`,
+	} {
+		if !strings.Contains(numbering, num) {
+			t.Errorf("numbering.xml lacks %s:\n%s", num, numbering)
+		}
+	}
+}
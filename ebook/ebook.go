@@ -1,23 +1,29 @@
 package ebook
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
+	"github.com/hitzhangjie/gitbook/book"
 	"github.com/hitzhangjie/gitbook/builder"
 )
 
-// Generator generates ebooks (PDF, EPUB, MOBI)
+// Generator generates ebooks (PDF, EPUB, MOBI, DOCX) and LaTeX projects
 type Generator struct {
 	BookRoot  string
 	OutputDir string
-	Format    string // pdf, epub, mobi
+	Format    string // pdf, epub, mobi, docx, latex
+	Converter string // converter backend, overrides ebook.converter in book.json
+	Book      *book.Book
+	Warnings  []string // problems Generate worked around, for the caller to report
+
+	builder *builder.Builder
 }
 
 // NewGenerator creates a new ebook generator
-func NewGenerator(bookRoot, outputDir, format string) (*Generator, error) {
+func NewGenerator(bookRoot, outputDir, format string, opts ...book.LoadOption) (*Generator, error) {
 	if outputDir == "" {
 		outputDir = filepath.Join(bookRoot, "_book")
 	}
@@ -27,104 +33,99 @@ func NewGenerator(bookRoot, outputDir, format string) (*Generator, error) {
 		return nil, err
 	}
 
+	b, err := builder.NewBuilder(bookRoot, absOutput, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create builder: %w", err)
+	}
+
 	return &Generator{
 		BookRoot:  bookRoot,
 		OutputDir: absOutput,
 		Format:    format,
+		Book:      b.Book,
+		builder:   b,
 	}, nil
 }
 
+// documentName is the single-document rendering of the book handed to converters,
+// written at the root of the output directory so that asset paths resolve and
+// removed once the conversion is done
+const documentName = "_ebook.html"
+
 // Generate generates an ebook
 func (g *Generator) Generate(outputPath string) error {
-	// First build the book
-	builder, err := builder.NewBuilder(g.BookRoot, g.OutputDir)
+	// Converters run inside the output directory, resolve the output path first
+	absOutputPath, err := filepath.Abs(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create builder: %w", err)
+		return err
 	}
 
-	if err := builder.Build(); err != nil {
-		return fmt.Errorf("failed to build book: %w", err)
+	// Pick the converter before building so that a missing tool fails fast
+	b := g.builder
+	name := g.Converter
+	if name == "" && b.Book.Config != nil && b.Book.Config.Ebook != nil {
+		name = b.Book.Config.Ebook.Converter
 	}
-
-	// Generate ebook using calibre or pandoc
-	switch g.Format {
-	case "pdf":
-		return g.generatePDF(outputPath)
-	case "epub":
-		return g.generateEPUB(outputPath)
-	case "mobi":
-		return g.generateMOBI(outputPath)
-	default:
-		return fmt.Errorf("unsupported format: %s", g.Format)
+	conv, err := SelectConverter(name, g.Format, b.Book.Config)
+	if err != nil {
+		return err
 	}
-}
 
-func (g *Generator) generatePDF(outputPath string) error {
-	// Try to use calibre's ebook-convert
-	if _, err := exec.LookPath("ebook-convert"); err == nil {
-		return g.convertWithCalibre("pdf", outputPath)
+	if err := b.Build(); err != nil {
+		return fmt.Errorf("failed to build book: %w", err)
 	}
 
-	// Try to use pandoc
-	if _, err := exec.LookPath("pandoc"); err == nil {
-		return g.convertWithPandoc("pdf", outputPath)
+	// Intermediate files stay out of the published website
+	tmpDir, err := os.MkdirTemp("", "gitbook-ebook-")
+	if err != nil {
+		return err
 	}
-
-	return fmt.Errorf("neither calibre (ebook-convert) nor pandoc found. Please install one of them")
-}
-
-func (g *Generator) generateEPUB(outputPath string) error {
-	// Try to use calibre's ebook-convert
-	if _, err := exec.LookPath("ebook-convert"); err == nil {
-		return g.convertWithCalibre("epub", outputPath)
+	defer os.RemoveAll(tmpDir)
+
+	// Word documents and LaTeX projects open on a title page instead of a cover image
+	var cover string
+	if g.Format != "docx" && g.Format != "latex" {
+		if cover, err = g.cover(b, tmpDir); err != nil {
+			return err
+		}
 	}
 
-	// Try to use pandoc
-	if _, err := exec.LookPath("pandoc"); err == nil {
-		return g.convertWithPandoc("epub", outputPath)
+	job := &Job{
+		Format:     g.Format,
+		OutputPath: absOutputPath,
+		WorkDir:    g.OutputDir,
+		Cover:      cover,
+		Builder:    b,
 	}
-
-	return fmt.Errorf("neither calibre (ebook-convert) nor pandoc found. Please install one of them")
-}
-
-func (g *Generator) generateMOBI(outputPath string) error {
-	// Try to use calibre's ebook-convert
-	if _, err := exec.LookPath("ebook-convert"); err == nil {
-		return g.convertWithCalibre("mobi", outputPath)
+	err = conv.Convert(job)
+	if job.document != "" {
+		os.Remove(job.document)
 	}
-
-	return fmt.Errorf("calibre (ebook-convert) not found. Please install calibre")
+	return err
 }
 
-func (g *Generator) convertWithCalibre(format, outputPath string) error {
-	indexPath := filepath.Join(g.OutputDir, "index.html")
-	
-	cmd := exec.Command("ebook-convert", indexPath, outputPath)
-	cmd.Dir = g.OutputDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	return cmd.Run()
-}
+// cover returns the absolute path of the book's cover image. Books without one
+// get a cover generated into dir, or none if its text cannot be drawn.
+func (g *Generator) cover(b *builder.Builder, dir string) (string, error) {
+	cover, err := coverPath(b.Book.Root, b.Book.Config)
+	if err != nil {
+		return "", err
+	}
+	if cover != "" {
+		return filepath.Join(b.Book.Root, filepath.FromSlash(cover)), nil
+	}
 
-func (g *Generator) convertWithPandoc(format, outputPath string) error {
-	indexPath := filepath.Join(g.OutputDir, "index.html")
-	
-	var pandocFormat string
-	switch format {
-	case "pdf":
-		pandocFormat = "pdf"
-	case "epub":
-		pandocFormat = "epub"
-	default:
-		return fmt.Errorf("pandoc does not support format: %s", format)
+	gen, err := NewCoverGenerator(b.Book.Root, b.Book.Config)
+	if err != nil {
+		return "", err
+	}
+	if err := gen.WriteFiles(dir); err != nil {
+		var glyphsErr *MissingGlyphsError
+		if errors.As(err, &glyphsErr) {
+			g.Warnings = append(g.Warnings, fmt.Sprintf("%v; the ebook has no cover", err))
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to generate cover: %w", err)
 	}
-	
-	cmd := exec.Command("pandoc", indexPath, "-o", outputPath, "-t", pandocFormat)
-	cmd.Dir = g.OutputDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	return cmd.Run()
+	return filepath.Join(dir, "cover.jpg"), nil
 }
-
@@ -0,0 +1,524 @@
+package ebook
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	_ "embed"
+	"fmt"
+	stdhtml "html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+//go:embed static/ebook.css
+var ebookCSS []byte
+
+const (
+	epubContentDir = "OEBPS"
+	epubStylesheet = "styles/ebook.css"
+)
+
+// mediaTypes lists the resource types that may be packaged into an EPUB
+var mediaTypes = map[string]string{
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".svg":   "image/svg+xml",
+	".webp":  "image/webp",
+	".css":   "text/css",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+}
+
+// EPUBWriter packages rendered chapters into an EPUB 3 file without external tools
+type EPUBWriter struct {
+	Config   *book.Config
+	BookRoot string
+	Chapters []builder.RenderedChapter
+	Cover    string // absolute path of the cover image, empty for none
+}
+
+// epubDocument is a content document of the package
+type epubDocument struct {
+	ID         string
+	Href       string // relative to the content directory
+	Title      string
+	Body       string
+	Properties string
+}
+
+// tocEntry is a node of the table of contents built from the summary hierarchy
+type tocEntry struct {
+	Label    string
+	Href     string
+	Children []*tocEntry
+}
+
+// NewEPUBWriter renders the chapters of a book for packaging
+func NewEPUBWriter(b *builder.Builder) (*EPUBWriter, error) {
+	chapters, err := b.RenderChapters()
+	if err != nil {
+		return nil, err
+	}
+	w := &EPUBWriter{
+		Config:   b.Book.Config,
+		BookRoot: b.Book.Root,
+		Chapters: chapters,
+	}
+	cover, err := coverPath(b.Book.Root, b.Book.Config)
+	if err != nil {
+		return nil, err
+	}
+	if cover != "" {
+		w.Cover = filepath.Join(b.Book.Root, filepath.FromSlash(cover))
+	}
+	return w, nil
+}
+
+// Write writes the EPUB to outputPath
+func (w *EPUBWriter) Write(outputPath string) error {
+	docs, resources, toc, err := w.collect()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("book has no chapters to package")
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	// The mimetype entry must come first, stored uncompressed and without the
+	// extra field a modification time would add
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, epubMimetype); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"META-INF/container.xml", []byte(epubContainerXML)},
+		{epubContentDir + "/content.opf", []byte(w.packageDocument(docs, resources))},
+		{epubContentDir + "/nav.xhtml", []byte(w.navDocument(toc))},
+		{epubContentDir + "/toc.ncx", []byte(w.ncxDocument(toc))},
+		{epubContentDir + "/" + epubStylesheet, ebookCSS},
+	}
+	for _, doc := range docs {
+		files = append(files, struct {
+			name string
+			data []byte
+		}{epubContentDir + "/" + doc.Href, []byte(w.contentDocument(doc))})
+	}
+	for _, file := range files {
+		if err := writeZipFile(zw, file.name, file.data); err != nil {
+			return err
+		}
+	}
+
+	cover := w.coverImage()
+	for _, res := range resources {
+		source := filepath.Join(w.BookRoot, filepath.FromSlash(res))
+		if res == cover {
+			source = w.Cover
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(zw, epubContentDir+"/"+res, data); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// collect converts chapters to XHTML content documents, gathering the local
+// resources they reference and the table of contents
+func (w *EPUBWriter) collect() ([]epubDocument, []string, []*tocEntry, error) {
+	// Map chapter sources and pages to their content documents so that
+	// cross-chapter links keep working inside the EPUB
+	docHref := make(map[string]string)
+	for _, ch := range w.Chapters {
+		if ch.Path == "" {
+			continue
+		}
+		href := strings.TrimSuffix(ch.Path, path.Ext(ch.Path)) + ".xhtml"
+		docHref[ch.Path] = href
+		docHref[ch.HTMLPath] = href
+	}
+
+	var docs []epubDocument
+	var resources []string
+	seenDocs := make(map[string]bool)
+	seenResources := make(map[string]bool)
+
+	for _, ch := range w.Chapters {
+		if ch.Path == "" || seenDocs[ch.Path] {
+			continue
+		}
+		seenDocs[ch.Path] = true
+		href := docHref[ch.Path]
+		dir := path.Dir(ch.Path)
+
+		body, err := toXHTML(ch.Content, func(attr, value string) string {
+			target, fragment, ok := builder.ResolveRef(dir, value)
+			if !ok {
+				return value
+			}
+			if doc, ok := docHref[target]; ok {
+				return escapeHref(relativeHref(dir, doc)) + fragment
+			}
+			if _, ok := mediaTypes[strings.ToLower(path.Ext(target))]; !ok {
+				return value
+			}
+			if _, err := os.Stat(filepath.Join(w.BookRoot, filepath.FromSlash(target))); err != nil {
+				return value
+			}
+			if !seenResources[target] {
+				seenResources[target] = true
+				resources = append(resources, target)
+			}
+			return escapeHref(relativeHref(dir, target)) + fragment
+		})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", ch.Path, err)
+		}
+
+		docs = append(docs, epubDocument{
+			ID:         fmt.Sprintf("chapter-%d", len(docs)+1),
+			Href:       href,
+			Title:      ch.Label(),
+			Body:       body,
+			Properties: contentProperties(body),
+		})
+	}
+
+	if cover := w.coverImage(); cover != "" && !seenResources[cover] {
+		resources = append(resources, cover)
+	}
+
+	return docs, resources, buildTOC(w.Chapters, docHref), nil
+}
+
+// coverImage returns the path of the cover image inside the package, if any.
+// A cover that is not a book file (outside the book root or in a _-prefixed
+// directory such as the _book output) is packaged at the top of the content
+// directory.
+func (w *EPUBWriter) coverImage() string {
+	if w.Cover == "" {
+		return ""
+	}
+	ext := strings.ToLower(filepath.Ext(w.Cover))
+	if _, ok := mediaTypes[ext]; !ok {
+		return ""
+	}
+	rel, err := filepath.Rel(w.BookRoot, w.Cover)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || strings.HasPrefix(rel, "_") {
+		return "cover" + ext
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (w *EPUBWriter) title() string {
+	if w.Config != nil && w.Config.Title != "" {
+		return w.Config.Title
+	}
+	return "GitBook"
+}
+
+func (w *EPUBWriter) language() string {
+	if w.Config != nil && w.Config.Language != "" {
+		return w.Config.Language
+	}
+	return "en"
+}
+
+// identifier derives a stable URN from the book title and author
+func (w *EPUBWriter) identifier() string {
+	seed := w.title()
+	if w.Config != nil {
+		seed += "\x00" + w.Config.Author
+	}
+	sum := sha1.Sum([]byte(seed))
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func (w *EPUBWriter) packageDocument(docs []epubDocument, resources []string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&sb, `<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="%s">`+"\n", xmlEscape(w.language()))
+
+	cover := w.coverImage()
+
+	sb.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(&sb, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", w.identifier())
+	fmt.Fprintf(&sb, "    <dc:title>%s</dc:title>\n", xmlEscape(w.title()))
+	fmt.Fprintf(&sb, "    <dc:language>%s</dc:language>\n", xmlEscape(w.language()))
+	if w.Config != nil && w.Config.Author != "" {
+		fmt.Fprintf(&sb, "    <dc:creator>%s</dc:creator>\n", xmlEscape(w.Config.Author))
+	}
+	if w.Config != nil && w.Config.Description != "" {
+		fmt.Fprintf(&sb, "    <dc:description>%s</dc:description>\n", xmlEscape(w.Config.Description))
+	}
+	fmt.Fprintf(&sb, "    <meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	if cover != "" {
+		sb.WriteString("    <meta name=\"cover\" content=\"cover-image\"/>\n")
+	}
+	sb.WriteString("  </metadata>\n")
+
+	sb.WriteString("  <manifest>\n")
+	sb.WriteString("    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	sb.WriteString("    <item id=\"ncx\" href=\"toc.ncx\" media-type=\"application/x-dtbncx+xml\"/>\n")
+	fmt.Fprintf(&sb, "    <item id=\"stylesheet\" href=\"%s\" media-type=\"text/css\"/>\n", epubStylesheet)
+	for _, doc := range docs {
+		props := ""
+		if doc.Properties != "" {
+			props = ` properties="` + doc.Properties + `"`
+		}
+		fmt.Fprintf(&sb, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"%s/>\n", doc.ID, xmlEscape(escapeHref(doc.Href)), props)
+	}
+	for i, res := range resources {
+		id := fmt.Sprintf("resource-%d", i+1)
+		props := ""
+		if res == cover {
+			id = "cover-image"
+			props = ` properties="cover-image"`
+		}
+		mediaType := mediaTypes[strings.ToLower(path.Ext(res))]
+		fmt.Fprintf(&sb, "    <item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", id, xmlEscape(escapeHref(res)), mediaType, props)
+	}
+	sb.WriteString("  </manifest>\n")
+
+	sb.WriteString("  <spine toc=\"ncx\">\n")
+	for _, doc := range docs {
+		fmt.Fprintf(&sb, "    <itemref idref=\"%s\"/>\n", doc.ID)
+	}
+	sb.WriteString("  </spine>\n")
+	sb.WriteString("</package>\n")
+	return sb.String()
+}
+
+func (w *EPUBWriter) contentDocument(doc epubDocument) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(&sb, `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xmlns:xlink="http://www.w3.org/1999/xlink" xml:lang="%[1]s" lang="%[1]s">`+"\n", xmlEscape(w.language()))
+	sb.WriteString("<head>\n")
+	sb.WriteString("  <meta charset=\"UTF-8\"/>\n")
+	fmt.Fprintf(&sb, "  <title>%s</title>\n", xmlEscape(doc.Title))
+	fmt.Fprintf(&sb, "  <link rel=\"stylesheet\" type=\"text/css\" href=\"%s\"/>\n", escapeHref(relativeHref(path.Dir(doc.Href), epubStylesheet)))
+	sb.WriteString("</head>\n")
+	sb.WriteString("<body>\n<section epub:type=\"chapter\">\n")
+	sb.WriteString(doc.Body)
+	sb.WriteString("\n</section>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+func (w *EPUBWriter) navDocument(toc []*tocEntry) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(&sb, `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">`+"\n", xmlEscape(w.language()))
+	sb.WriteString("<head>\n")
+	sb.WriteString("  <meta charset=\"UTF-8\"/>\n")
+	fmt.Fprintf(&sb, "  <title>%s</title>\n", xmlEscape(w.title()))
+	fmt.Fprintf(&sb, "  <link rel=\"stylesheet\" type=\"text/css\" href=\"%s\"/>\n", epubStylesheet)
+	sb.WriteString("</head>\n<body>\n")
+	sb.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", xmlEscape(w.title()))
+	writeNavList(&sb, toc)
+	sb.WriteString("</nav>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+func writeNavList(sb *strings.Builder, entries []*tocEntry) {
+	sb.WriteString("<ol>\n")
+	for _, e := range entries {
+		// A heading without a link is only allowed when it has children
+		if e.Href == "" && len(e.Children) == 0 {
+			continue
+		}
+		sb.WriteString("<li>")
+		if e.Href != "" {
+			fmt.Fprintf(sb, "<a href=\"%s\">%s</a>", xmlEscape(escapeHref(e.Href)), xmlEscape(e.Label))
+		} else {
+			fmt.Fprintf(sb, "<span>%s</span>", xmlEscape(e.Label))
+		}
+		if len(e.Children) > 0 {
+			sb.WriteString("\n")
+			writeNavList(sb, e.Children)
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ol>\n")
+}
+
+func (w *EPUBWriter) ncxDocument(toc []*tocEntry) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">` + "\n")
+	sb.WriteString("  <head>\n")
+	fmt.Fprintf(&sb, "    <meta name=\"dtb:uid\" content=\"%s\"/>\n", w.identifier())
+	fmt.Fprintf(&sb, "    <meta name=\"dtb:depth\" content=\"%d\"/>\n", tocDepth(toc))
+	sb.WriteString("    <meta name=\"dtb:totalPageCount\" content=\"0\"/>\n")
+	sb.WriteString("    <meta name=\"dtb:maxPageNumber\" content=\"0\"/>\n")
+	sb.WriteString("  </head>\n")
+	fmt.Fprintf(&sb, "  <docTitle><text>%s</text></docTitle>\n", xmlEscape(w.title()))
+	sb.WriteString("  <navMap>\n")
+	playOrder := 0
+	writeNavPoints(&sb, toc, &playOrder, "    ")
+	sb.WriteString("  </navMap>\n")
+	sb.WriteString("</ncx>\n")
+	return sb.String()
+}
+
+func writeNavPoints(sb *strings.Builder, entries []*tocEntry, playOrder *int, indent string) {
+	for _, e := range entries {
+		// NCX nav points need a target; headings point at their first article
+		href := e.Href
+		if href == "" {
+			href = firstHref(e.Children)
+		}
+		if href == "" {
+			continue
+		}
+		*playOrder++
+		fmt.Fprintf(sb, "%s<navPoint id=\"navpoint-%d\" playOrder=\"%d\">\n", indent, *playOrder, *playOrder)
+		fmt.Fprintf(sb, "%s  <navLabel><text>%s</text></navLabel>\n", indent, xmlEscape(e.Label))
+		fmt.Fprintf(sb, "%s  <content src=\"%s\"/>\n", indent, xmlEscape(escapeHref(href)))
+		writeNavPoints(sb, e.Children, playOrder, indent+"  ")
+		fmt.Fprintf(sb, "%s</navPoint>\n", indent)
+	}
+}
+
+// buildTOC rebuilds the summary hierarchy from the flattened chapter list.
+// Parts become headings holding their top-level chapters.
+func buildTOC(chapters []builder.RenderedChapter, docHref map[string]string) []*tocEntry {
+	var root []*tocEntry
+	type frame struct {
+		level int
+		entry *tocEntry
+	}
+	var stack []frame
+
+	for _, ch := range chapters {
+		if ch.Part != "" {
+			part := &tocEntry{Label: ch.Part}
+			root = append(root, part)
+			stack = []frame{{level: 0, entry: part}}
+		}
+		entry := &tocEntry{Label: ch.Label(), Href: docHref[ch.Path]}
+		for len(stack) > 0 && stack[len(stack)-1].level >= ch.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			root = append(root, entry)
+		} else {
+			parent := stack[len(stack)-1].entry
+			parent.Children = append(parent.Children, entry)
+		}
+		stack = append(stack, frame{level: ch.Level, entry: entry})
+	}
+	return root
+}
+
+func firstHref(entries []*tocEntry) string {
+	for _, e := range entries {
+		if e.Href != "" {
+			return e.Href
+		}
+		if href := firstHref(e.Children); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+func tocDepth(entries []*tocEntry) int {
+	depth := 0
+	for _, e := range entries {
+		if d := 1 + tocDepth(e.Children); d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// contentProperties returns the manifest properties required by a content document
+func contentProperties(body string) string {
+	var props []string
+	if strings.Contains(body, "<svg") {
+		props = append(props, "svg")
+	}
+	if strings.Contains(body, "<math") {
+		props = append(props, "mathml")
+	}
+	if strings.Contains(body, `src="http://`) || strings.Contains(body, `src="https://`) {
+		props = append(props, "remote-resources")
+	}
+	return strings.Join(props, " ")
+}
+
+// relativeHref returns the slash-separated path of target relative to dir
+func relativeHref(dir, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// escapeHref percent-encodes a relative path for use in an href
+func escapeHref(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func xmlEscape(s string) string {
+	return stdhtml.EscapeString(s)
+}
+
+const epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
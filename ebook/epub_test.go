@@ -0,0 +1,125 @@
+package ebook
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+func TestBuildTOCParts(t *testing.T) {
+	chapters := []builder.RenderedChapter{
+		{Title: "Intro", Level: 1, Path: "README.md"},
+		{Title: "One", Level: 1, Path: "one.md", Part: "Basics"},
+		{Title: "One A", Level: 2, Path: "one/a.md"},
+		{Title: "Two", Level: 1, Path: "two.md"},
+		{Title: "Three", Level: 1, Path: "three.md", Part: "Advanced"},
+	}
+	docHref := map[string]string{}
+	for _, ch := range chapters {
+		docHref[ch.Path] = ch.Path + ".xhtml"
+	}
+
+	got := tocString(buildTOC(chapters, docHref))
+	want := "Intro(README.md.xhtml) Basics[One(one.md.xhtml)[One A(one/a.md.xhtml)] Two(two.md.xhtml)] Advanced[Three(three.md.xhtml)]"
+	if got != want {
+		t.Errorf("buildTOC =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func tocString(entries []*tocEntry) string {
+	s := ""
+	for i, e := range entries {
+		if i > 0 {
+			s += " "
+		}
+		s += e.Label
+		if e.Href != "" {
+			s += "(" + e.Href + ")"
+		}
+		if len(e.Children) > 0 {
+			s += "[" + tocString(e.Children) + "]"
+		}
+	}
+	return s
+}
+
+func TestEPUBWriter(t *testing.T) {
+	b := newTestBuilder(t, map[string]string{
+		"book.json":    `{"title": "Guide", "author": "Ann", "language": "en", "description": "About Go"}`,
+		"README.md":    "# Intro\n\nSee [setup](guide/one.md#setup).\n",
+		"guide/one.md": "# One\n\n## Setup\n\n![logo](../img/logo.png)\n",
+		"guide/two.md": "# Two\n",
+		"img/logo.png": "\x89PNG\r\n\x1a\n",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [One](guide/one.md)\n  * [Two](guide/two.md)\n",
+	})
+	w, err := NewEPUBWriter(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "book.epub")
+	if err := w.Write(out); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readZip(t, out)
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	wantNames := []string{
+		"META-INF/container.xml",
+		"OEBPS/README.xhtml",
+		"OEBPS/content.opf",
+		"OEBPS/guide/one.xhtml",
+		"OEBPS/guide/two.xhtml",
+		"OEBPS/img/logo.png",
+		"OEBPS/nav.xhtml",
+		"OEBPS/styles/ebook.css",
+		"OEBPS/toc.ncx",
+		"mimetype",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("entries = %q, want %q", names, wantNames)
+	}
+
+	tests := []struct {
+		entry string
+		want  []string
+	}{
+		{"OEBPS/content.opf", []string{
+			"<dc:title>Guide</dc:title>",
+			"<dc:language>en</dc:language>",
+			"<dc:creator>Ann</dc:creator>",
+			"<dc:description>About Go</dc:description>",
+			`<item id="stylesheet" href="styles/ebook.css" media-type="text/css"/>`,
+			`<item id="resource-1" href="img/logo.png" media-type="image/png"/>`,
+			"<itemref idref=\"chapter-1\"/>\n    <itemref idref=\"chapter-2\"/>\n    <itemref idref=\"chapter-3\"/>",
+		}},
+		{"OEBPS/nav.xhtml", []string{
+			"<li><a href=\"guide/one.xhtml\">One</a>\n<ol>\n<li><a href=\"guide/two.xhtml\">Two</a></li>\n</ol>\n</li>",
+		}},
+		{"OEBPS/toc.ncx", []string{
+			`<meta name="dtb:depth" content="2"/>`,
+			"<content src=\"guide/one.xhtml\"/>\n      <navPoint id=\"navpoint-3\" playOrder=\"3\">",
+		}},
+		{"OEBPS/README.xhtml", []string{
+			`<a href="guide/one.xhtml#setup">setup</a>`,
+		}},
+		{"OEBPS/guide/one.xhtml", []string{
+			`<link rel="stylesheet" type="text/css" href="../styles/ebook.css"/>`,
+			`<img src="../img/logo.png" alt="logo"/>`,
+		}},
+	}
+	for _, tt := range tests {
+		for _, want := range tt.want {
+			if !strings.Contains(entries[tt.entry], want) {
+				t.Errorf("%s lacks %s:\n%s", tt.entry, want, entries[tt.entry])
+			}
+		}
+	}
+}
@@ -0,0 +1,606 @@
+package ebook
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+//go:embed static/latex-preamble.tex
+var latexPreamble string
+
+// latexSections are the sectioning commands from the first summary level down
+var latexSections = []string{"chapter", "section", "subsection", "subsubsection", "paragraph", "subparagraph"}
+
+// latexLanguages maps code fence languages to listings languages
+var latexLanguages = map[string]string{
+	"go":         "Go",
+	"golang":     "Go",
+	"c":          "C",
+	"cpp":        "C++",
+	"c++":        "C++",
+	"java":       "Java",
+	"python":     "Python",
+	"py":         "Python",
+	"sh":         "bash",
+	"bash":       "bash",
+	"shell":      "bash",
+	"sql":        "SQL",
+	"html":       "HTML",
+	"xml":        "XML",
+	"ruby":       "Ruby",
+	"php":        "PHP",
+	"perl":       "Perl",
+	"make":       "make",
+	"makefile":   "make",
+	"tex":        "TeX",
+	"latex":      "TeX",
+	"javascript": "Java", // closest built-in syntax
+}
+
+// latexImageTypes lists the image formats pdfTeX, XeTeX and LuaTeX all include
+var latexImageTypes = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".pdf": true}
+
+// latexPaperSizes maps pdf.paperSize values to class options
+var latexPaperSizes = map[string]string{
+	"a4":     "a4paper",
+	"a5":     "a5paper",
+	"letter": "letterpaper",
+	"legal":  "legalpaper",
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+var latexURLEscaper = strings.NewReplacer(`\`, `\\`, `#`, `\#`, `%`, `\%`, `{`, `\{`, `}`, `\}`)
+
+// LaTeXWriter writes a LaTeX project from the goldmark AST of each chapter:
+// summary levels map to chapters and sections, title-only top-level entries to
+// parts. Raw HTML is dropped.
+type LaTeXWriter struct {
+	Config   *book.Config
+	BookRoot string
+	Chapters []builder.RenderedChapter
+	parse    func(chapterPath string) (ast.Node, []byte, error)
+}
+
+// latexPreambleData is passed to the preamble template; text fields are escaped
+type latexPreambleData struct {
+	Title         string
+	Subtitle      string
+	Author        string
+	Date          string
+	Language      string
+	DocumentClass string
+	ClassOptions  string
+	CJK           bool // the book language is Chinese, Japanese or Korean
+}
+
+// latexChapter renders one chapter
+type latexChapter struct {
+	w         *LaTeXWriter
+	out       strings.Builder
+	source    []byte
+	dir       string
+	section   string // label of the chapter
+	offset    int    // sectioning level of the chapter's top heading
+	sections  map[string]string
+	images    map[string]string // source path -> project path
+	footnotes map[int]*east.Footnote
+}
+
+// NewLaTeXWriter prepares the chapters of a book for writing
+func NewLaTeXWriter(b *builder.Builder) (*LaTeXWriter, error) {
+	chapters, err := b.RenderChapters()
+	if err != nil {
+		return nil, err
+	}
+	return &LaTeXWriter{
+		Config:   b.Book.Config,
+		BookRoot: b.Book.Root,
+		Chapters: chapters,
+		parse:    b.ParseChapter,
+	}, nil
+}
+
+// Write writes main.tex, one file per chapter under chapters/ and the images
+// they include under images/ into dir
+func (w *LaTeXWriter) Write(dir string) error {
+	if err := os.MkdirAll(filepath.Join(dir, "chapters"), 0755); err != nil {
+		return err
+	}
+
+	preamble, err := w.preamble()
+	if err != nil {
+		return err
+	}
+
+	ids := builder.SectionIDs(w.Chapters)
+	sections := make(map[string]string)
+	for _, ch := range w.Chapters {
+		if ch.Path != "" {
+			sections[ch.Path] = ids[ch.Path]
+			sections[ch.HTMLPath] = ids[ch.Path]
+		}
+	}
+
+	var main strings.Builder
+	main.WriteString(preamble)
+	main.WriteString("\n\\begin{document}\n\n\\maketitle\n\\tableofcontents\n\n")
+
+	images := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, ch := range w.Chapters {
+		if ch.Part != "" {
+			fmt.Fprintf(&main, "\\part{%s}\n\n", latexEscape(ch.Part))
+		}
+		if ch.Path == "" {
+			// Title-only summary entries become parts, or headings when nested
+			cmd := "part"
+			if ch.Level > 1 {
+				cmd = latexSection(ch.Level - 1)
+			}
+			fmt.Fprintf(&main, "\\%s{%s}\n\n", cmd, latexEscape(ch.Title))
+			continue
+		}
+		if seen[ch.Path] {
+			continue
+		}
+		seen[ch.Path] = true
+
+		doc, source, err := w.parse(ch.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ch.Path, err)
+		}
+		c := &latexChapter{
+			w:        w,
+			source:   source,
+			dir:      path.Dir(ch.Path),
+			section:  ids[ch.Path],
+			offset:   ch.Level - 1,
+			sections: sections,
+			images:   images,
+		}
+		c.render(doc, ch.Title)
+
+		name := ids[ch.Path]
+		if err := os.WriteFile(filepath.Join(dir, "chapters", name+".tex"), []byte(c.out.String()), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(&main, "\\input{chapters/%s}\n", name)
+	}
+	main.WriteString("\n\\end{document}\n")
+
+	if err := os.WriteFile(filepath.Join(dir, "main.tex"), []byte(main.String()), 0644); err != nil {
+		return err
+	}
+
+	if len(images) > 0 {
+		if err := os.MkdirAll(filepath.Join(dir, "images"), 0755); err != nil {
+			return err
+		}
+	}
+	for src, dst := range images {
+		data, err := os.ReadFile(filepath.Join(w.BookRoot, filepath.FromSlash(src)))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(dst)), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preamble executes the configured or built-in preamble template
+func (w *LaTeXWriter) preamble() (string, error) {
+	cfg := w.Config
+	if cfg == nil {
+		cfg = &book.Config{}
+	}
+	opts := cfg.LaTeX
+	if opts == nil {
+		opts = &book.LaTeX{}
+	}
+
+	source := latexPreamble
+	if opts.Preamble != "" {
+		data, err := os.ReadFile(filepath.Join(w.BookRoot, filepath.FromSlash(opts.Preamble)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read latex preamble: %w", err)
+		}
+		source = string(data)
+	}
+	tmpl, err := template.New("preamble").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse latex preamble: %w", err)
+	}
+
+	data := latexPreambleData{
+		Title:         latexEscape(cfg.Title),
+		Subtitle:      latexEscape(cfg.Subtitle),
+		Author:        latexEscape(cfg.Author),
+		Date:          `\today`,
+		Language:      cfg.Language,
+		DocumentClass: opts.DocumentClass,
+		ClassOptions:  opts.ClassOptions,
+	}
+	if data.Title == "" {
+		data.Title = "GitBook"
+	}
+	if data.DocumentClass == "" {
+		data.DocumentClass = "book"
+	}
+	if data.ClassOptions == "" {
+		paper := "a4paper"
+		if cfg.PDF != nil {
+			if p, ok := latexPaperSizes[strings.ToLower(cfg.PDF.PaperSize)]; ok {
+				paper = p
+			}
+		}
+		data.ClassOptions = "11pt," + paper
+		if data.DocumentClass == "book" || data.DocumentClass == "report" {
+			data.ClassOptions += ",openany"
+		}
+	}
+	for _, lang := range []string{"zh", "ja", "ko"} {
+		if strings.HasPrefix(strings.ToLower(cfg.Language), lang) {
+			data.CJK = true
+		}
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render latex preamble: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// render writes a chapter; chapters not starting with a top-level heading get
+// one from their summary title so that they appear in the table of contents
+func (c *latexChapter) render(doc ast.Node, title string) {
+	c.footnotes = make(map[int]*east.Footnote)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fn, ok := n.(*east.Footnote); ok && entering {
+			c.footnotes[fn.Index] = fn
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if h, ok := doc.FirstChild().(*ast.Heading); !ok || h.Level != 1 {
+		fmt.Fprintf(&c.out, "\\%s{%s}\n\\label{%s}\n\n", latexSection(c.offset), latexEscape(title), latexLabel(c.section))
+	}
+	c.blocks(doc)
+}
+
+// latexSection returns the sectioning command of a level, 0 being a chapter
+func latexSection(level int) string {
+	if level >= len(latexSections) {
+		level = len(latexSections) - 1
+	}
+	return latexSections[level]
+}
+
+func (c *latexChapter) blocks(n ast.Node) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		c.block(child)
+	}
+}
+
+func (c *latexChapter) block(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.Heading:
+		fmt.Fprintf(&c.out, "\\%s{", latexSection(c.offset+n.Level-1))
+		c.inlines(n)
+		c.out.WriteString("}\n")
+		if n.Level == 1 && n.PreviousSibling() == nil {
+			fmt.Fprintf(&c.out, "\\label{%s}\n", latexLabel(c.section))
+		}
+		if id, ok := n.AttributeString("id"); ok {
+			if id, ok := id.([]byte); ok {
+				fmt.Fprintf(&c.out, "\\label{%s}\n", latexLabel(c.section+"--"+string(id)))
+			}
+		}
+		c.out.WriteString("\n")
+	case *ast.Paragraph:
+		if img, ok := soleImage(n); ok {
+			c.figure(img)
+			return
+		}
+		c.inlines(n)
+		c.out.WriteString("\n\n")
+	case *ast.TextBlock:
+		c.inlines(n)
+		c.out.WriteString("\n")
+	case *ast.Blockquote:
+		c.out.WriteString("\\begin{quote}\n")
+		c.blocks(n)
+		c.out.WriteString("\\end{quote}\n\n")
+	case *ast.FencedCodeBlock:
+		c.listing(n, latexLanguages[strings.ToLower(string(n.Language(c.source)))])
+	case *ast.CodeBlock:
+		c.listing(n, "")
+	case *ast.List:
+		c.list(n)
+	case *ast.ThematicBreak:
+		c.out.WriteString("\\par\\noindent\\rule{\\linewidth}{0.4pt}\\par\n\n")
+	case *east.Table:
+		c.table(n)
+	case *builder.MathBlock:
+		c.out.WriteString("\\[\n" + string(n.Value(c.source)) + "\n\\]\n\n")
+	case *east.FootnoteList:
+		// Footnotes are written where they are referenced
+	case *ast.HTMLBlock:
+		// Raw HTML has no LaTeX equivalent
+	default:
+		c.blocks(n)
+	}
+}
+
+func (c *latexChapter) listing(n ast.Node, language string) {
+	c.out.WriteString("\\begin{lstlisting}")
+	if language != "" {
+		c.out.WriteString("[language=" + language + "]")
+	}
+	c.out.WriteString("\n")
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		c.out.WriteString(strings.TrimRight(string(line.Value(c.source)), "\r\n") + "\n")
+	}
+	c.out.WriteString("\\end{lstlisting}\n\n")
+}
+
+func (c *latexChapter) list(l *ast.List) {
+	env := "itemize"
+	if l.IsOrdered() {
+		env = "enumerate"
+	}
+	c.out.WriteString("\\begin{" + env + "}")
+	if l.IsOrdered() && l.Start > 1 {
+		fmt.Fprintf(&c.out, "[start=%d]", l.Start)
+	}
+	c.out.WriteString("\n")
+	for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+		c.out.WriteString("\\item ")
+		c.blocks(item)
+	}
+	c.out.WriteString("\\end{" + env + "}\n\n")
+}
+
+// table writes a GFM table as a longtable, repeating the header on every page
+func (c *latexChapter) table(t *east.Table) {
+	var spec strings.Builder
+	for _, align := range t.Alignments {
+		switch align {
+		case east.AlignCenter:
+			spec.WriteString("c")
+		case east.AlignRight:
+			spec.WriteString("r")
+		default:
+			spec.WriteString("l")
+		}
+	}
+	c.out.WriteString("\\begin{longtable}[]{@{}" + spec.String() + "@{}}\n\\toprule\n")
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			if cell != row.FirstChild() {
+				c.out.WriteString(" & ")
+			}
+			c.inlines(cell)
+		}
+		c.out.WriteString(" \\\\\n")
+		if _, ok := row.(*east.TableHeader); ok {
+			c.out.WriteString("\\midrule\n\\endhead\n")
+		}
+	}
+	c.out.WriteString("\\bottomrule\n\\end{longtable}\n\n")
+}
+
+var htmlLineBreak = regexp.MustCompile(`(?i)^<br\s*/?>$`)
+
+func (c *latexChapter) inlines(n ast.Node) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			c.out.WriteString(latexEscape(string(child.Segment.Value(c.source))))
+			if child.HardLineBreak() {
+				c.out.WriteString("\\newline\n")
+			} else if child.SoftLineBreak() {
+				c.out.WriteString("\n")
+			}
+		case *ast.String:
+			c.out.WriteString(latexEscape(string(child.Value)))
+		case *ast.CodeSpan:
+			c.out.WriteString("\\texttt{" + latexEscape(plainText(child, c.source)) + "}")
+		case *ast.Emphasis:
+			cmd := "emph"
+			if child.Level >= 2 {
+				cmd = "textbf"
+			}
+			c.out.WriteString("\\" + cmd + "{")
+			c.inlines(child)
+			c.out.WriteString("}")
+		case *east.Strikethrough:
+			c.out.WriteString("\\sout{")
+			c.inlines(child)
+			c.out.WriteString("}")
+		case *ast.Link:
+			c.link(string(child.Destination), func() { c.inlines(child) })
+		case *ast.AutoLink:
+			url := string(child.URL(c.source))
+			if child.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(url, "mailto:") {
+				url = "mailto:" + url
+			}
+			label := string(child.Label(c.source))
+			c.link(url, func() { c.out.WriteString(latexEscape(label)) })
+		case *ast.Image:
+			if file, ok := c.imagePath(string(child.Destination)); ok {
+				c.out.WriteString("\\includegraphics[max width=\\linewidth]{" + file + "}")
+			} else {
+				c.out.WriteString(latexEscape(plainText(child, c.source)))
+			}
+		case *east.TaskCheckBox:
+			if child.IsChecked {
+				c.out.WriteString("$\\boxtimes$ ")
+			} else {
+				c.out.WriteString("$\\square$ ")
+			}
+		case *east.FootnoteLink:
+			c.footnote(child.Index)
+		case *east.FootnoteBacklink:
+			// Backlinks only make sense in HTML
+		case *builder.Math:
+			c.out.WriteString("$" + string(child.Value) + "$")
+		case *ast.RawHTML:
+			var raw strings.Builder
+			for i := 0; i < child.Segments.Len(); i++ {
+				seg := child.Segments.At(i)
+				raw.Write(seg.Value(c.source))
+			}
+			if htmlLineBreak.MatchString(strings.TrimSpace(raw.String())) {
+				c.out.WriteString("\\newline\n")
+			}
+		default:
+			c.inlines(child)
+		}
+	}
+}
+
+// footnote writes the footnote with the given index at its reference
+func (c *latexChapter) footnote(index int) {
+	fn, ok := c.footnotes[index]
+	if !ok {
+		return
+	}
+	outer := c.out
+	c.out = strings.Builder{}
+	c.blocks(fn)
+	body := strings.TrimSpace(c.out.String())
+	c.out = outer
+	c.out.WriteString("\\footnote{" + body + "}")
+}
+
+// link writes a hyperlink to an external URL, a heading or another chapter.
+// Links to other local files are written as plain text.
+func (c *latexChapter) link(dest string, content func()) {
+	label := ""
+	if strings.HasPrefix(dest, "#") && len(dest) > 1 {
+		label = c.section + "--" + dest[1:]
+	} else if target, fragment, ok := builder.ResolveRef(c.dir, dest); ok {
+		section, ok := c.sections[target]
+		if !ok {
+			content()
+			return
+		}
+		label = section
+		if len(fragment) > 1 {
+			label += "--" + fragment[1:]
+		}
+	}
+
+	switch {
+	case label != "":
+		c.out.WriteString("\\hyperref[" + latexLabel(label) + "]{")
+	case dest != "" && !strings.HasPrefix(dest, "#"):
+		c.out.WriteString("\\href{" + latexURLEscaper.Replace(dest) + "}{")
+	default:
+		content()
+		return
+	}
+	content()
+	c.out.WriteString("}")
+}
+
+// figure writes an image standing alone in its paragraph as a figure, with its
+// alternative text as caption
+func (c *latexChapter) figure(img *ast.Image) {
+	alt := plainText(img, c.source)
+	file, ok := c.imagePath(string(img.Destination))
+	if !ok {
+		c.out.WriteString(latexEscape(alt) + "\n\n")
+		return
+	}
+	c.out.WriteString("\\begin{figure}[htbp]\n\\centering\n")
+	c.out.WriteString("\\includegraphics[max width=\\linewidth]{" + file + "}\n")
+	if alt != "" {
+		c.out.WriteString("\\caption{" + latexEscape(alt) + "}\n")
+	}
+	c.out.WriteString("\\end{figure}\n\n")
+}
+
+var latexFileCleaner = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// imagePath returns the project path of a local image, registering it for copying
+func (c *latexChapter) imagePath(dest string) (string, bool) {
+	target, _, ok := builder.ResolveRef(c.dir, dest)
+	if !ok || !latexImageTypes[strings.ToLower(path.Ext(target))] {
+		return "", false
+	}
+	if file, ok := c.images[target]; ok {
+		return file, true
+	}
+	if _, err := os.Stat(filepath.Join(c.w.BookRoot, filepath.FromSlash(target))); err != nil {
+		return "", false
+	}
+
+	// Flatten the path into a name TeX is happy with, keeping names unique
+	name := latexFileCleaner.ReplaceAllString(strings.ReplaceAll(target, "/", "-"), "-")
+	file := "images/" + name
+	for i := 2; containsValue(c.images, file); i++ {
+		file = fmt.Sprintf("images/%d-%s", i, name)
+	}
+	c.images[target] = file
+	return file, true
+}
+
+func containsValue(m map[string]string, value string) bool {
+	for _, v := range m {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// soleImage returns the image of a paragraph containing nothing else
+func soleImage(p *ast.Paragraph) (*ast.Image, bool) {
+	img, ok := p.FirstChild().(*ast.Image)
+	if !ok || p.FirstChild() != p.LastChild() {
+		return nil, false
+	}
+	return img, true
+}
+
+func latexEscape(s string) string {
+	return latexEscaper.Replace(s)
+}
+
+var latexLabelCleaner = regexp.MustCompile(`[^A-Za-z0-9:.-]`)
+
+// latexLabel turns an anchor into a label made of characters safe in any engine
+func latexLabel(s string) string {
+	return latexLabelCleaner.ReplaceAllStringFunc(s, func(r string) string {
+		return fmt.Sprintf("+%x", []byte(r))
+	})
+}
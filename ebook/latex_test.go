@@ -0,0 +1,80 @@
+package ebook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+func TestLaTeXParts(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"README.md":  "# Intro\n",
+		"one.md":     "# One\n",
+		"two.md":     "# Two\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n\n## Basics & More\n\n* [One](one.md)\n* [Notes]()\n  * [Two](two.md)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := builder.NewBuilder(root, filepath.Join(root, "_book"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewLaTeXWriter(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	if err := w.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "main.tex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := string(data)
+	want := []string{"\\input{chapters/", "\\part{Basics \\& More}", "\\input{chapters/", "\\part{Notes}", "\\input{chapters/"}
+	rest := main[strings.Index(main, "\\tableofcontents"):]
+	for _, s := range want {
+		i := strings.Index(rest, s)
+		if i < 0 {
+			t.Fatalf("main.tex lacks %q in order:\n%s", s, main)
+		}
+		rest = rest[i+len(s):]
+	}
+}
+
+func TestLaTeXSectionCollisions(t *testing.T) {
+	b := newTestBuilder(t, map[string]string{
+		"README.md":  "# Intro\n",
+		"a/b.md":     "# Nested\n",
+		"a-b.md":     "# Flat\n",
+		"SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n* [Nested](a/b.md)\n* [Flat](a-b.md)\n",
+	})
+	w, err := NewLaTeXWriter(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	if err := w.Write(out); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each chapter keeps its own file
+	for name, title := range map[string]string{"ch-a-b": "Nested", "ch-a-b-2": "Flat"} {
+		data, err := os.ReadFile(filepath.Join(out, "chapters", name+".tex"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), title) {
+			t.Errorf("chapters/%s.tex does not hold %s:\n%s", name, title, data)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package ebook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// coverPath returns the cover image of a book relative to its root: the
+// configured ebook.cover, which must exist inside the book, or cover.jpg /
+// cover.png when present
+func coverPath(root string, cfg *book.Config) (string, error) {
+	if cfg != nil && cfg.Ebook != nil && cfg.Ebook.Cover != "" {
+		cover := filepath.ToSlash(filepath.Clean(cfg.Ebook.Cover))
+		file, err := book.ResolvePath(root, cover)
+		if err != nil {
+			return "", fmt.Errorf("ebook.cover: %w", err)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return "", fmt.Errorf("ebook.cover %s does not exist in %s", cover, root)
+		}
+		return cover, nil
+	}
+	for _, name := range []string{"cover.jpg", "cover.png"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// calibreOptions maps the book's cover and pdf settings onto ebook-convert arguments
+func calibreOptions(job *Job) []string {
+	var args []string
+	if job.Cover != "" {
+		args = append(args, "--cover", job.Cover)
+	}
+
+	pdf := job.Config().PDF
+	if pdf == nil {
+		return args
+	}
+
+	// Chapter separation applies to every output format
+	if pdf.ChapterMark != "" {
+		args = append(args, "--chapter-mark", pdf.ChapterMark)
+	}
+	if pdf.PageBreaksBefore != "" {
+		args = append(args, "--page-breaks-before", pdf.PageBreaksBefore)
+	}
+	if job.Format != "pdf" {
+		return args
+	}
+
+	if pdf.PaperSize != "" {
+		args = append(args, "--paper-size", strings.ToLower(pdf.PaperSize))
+	}
+	if m := pdf.Margin; m != nil {
+		for _, side := range []struct {
+			name  string
+			value float64
+		}{{"top", m.Top}, {"bottom", m.Bottom}, {"left", m.Left}, {"right", m.Right}} {
+			if side.value > 0 {
+				args = append(args, "--pdf-page-margin-"+side.name, formatPoints(side.value))
+			}
+		}
+	}
+	if pdf.FontFamily != "" {
+		args = append(args,
+			"--pdf-serif-family", pdf.FontFamily,
+			"--pdf-sans-family", pdf.FontFamily,
+		)
+	}
+	if pdf.FontSize > 0 {
+		args = append(args, "--pdf-default-font-size", strconv.Itoa(pdf.FontSize))
+	}
+	if pdf.PageNumbers != nil && *pdf.PageNumbers {
+		args = append(args, "--pdf-page-numbers")
+	}
+	if pdf.HeaderTemplate != "" {
+		args = append(args, "--pdf-header-template", pdf.HeaderTemplate)
+	}
+	if pdf.FooterTemplate != "" {
+		args = append(args, "--pdf-footer-template", pdf.FooterTemplate)
+	}
+	return args
+}
+
+// pandocOptions maps the book's cover and pdf settings onto pandoc arguments.
+// Header and footer templates are HTML snippets understood by calibre only.
+func pandocOptions(job *Job) []string {
+	var args []string
+	if job.Format == "epub" {
+		if job.Cover != "" {
+			args = append(args, "--epub-cover-image", job.Cover)
+		}
+	}
+
+	pdf := job.Config().PDF
+	if pdf == nil || job.Format != "pdf" {
+		return args
+	}
+
+	if pdf.PaperSize != "" {
+		args = append(args, "-V", "papersize="+strings.ToLower(pdf.PaperSize))
+	}
+	if m := pdf.Margin; m != nil {
+		var geometry []string
+		for _, side := range []struct {
+			name  string
+			value float64
+		}{{"top", m.Top}, {"bottom", m.Bottom}, {"left", m.Left}, {"right", m.Right}} {
+			if side.value > 0 {
+				geometry = append(geometry, side.name+"="+formatPoints(side.value)+"pt")
+			}
+		}
+		if len(geometry) > 0 {
+			args = append(args, "-V", "geometry:"+strings.Join(geometry, ","))
+		}
+	}
+	if pdf.FontFamily != "" {
+		args = append(args, "-V", "mainfont="+pdf.FontFamily)
+	}
+	if pdf.FontSize > 0 {
+		args = append(args, "-V", "fontsize="+strconv.Itoa(pdf.FontSize)+"pt")
+	}
+	if pdf.PageNumbers != nil && !*pdf.PageNumbers {
+		args = append(args, "-V", "pagestyle=empty")
+	}
+	if pdf.ChapterMark == "pagebreak" || pdf.ChapterMark == "both" {
+		args = append(args, "--top-level-division=chapter")
+	}
+	return args
+}
+
+func formatPoints(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
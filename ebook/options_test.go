@@ -0,0 +1,104 @@
+package ebook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+func newTestJob(format, cover string, pdf *book.PDF) *Job {
+	cfg := &book.Config{PDF: pdf}
+	return &Job{Format: format, Cover: cover, Builder: &builder.Builder{Book: &book.Book{Config: cfg}}}
+}
+
+func TestCalibreOptions(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		format string
+		cover  string
+		pdf    *book.PDF
+		want   []string
+	}{
+		{"unset", "pdf", "", nil, nil},
+		{"empty pdf", "pdf", "", &book.PDF{}, nil},
+		{"cover", "epub", "/b/cover.jpg", nil, []string{"--cover", "/b/cover.jpg"}},
+		{"paper size", "pdf", "", &book.PDF{PaperSize: "A4"}, []string{"--paper-size", "a4"}},
+		{"margins", "pdf", "", &book.PDF{Margin: &book.Margin{Top: 72, Bottom: 54.5, Left: 36, Right: 36}}, []string{
+			"--pdf-page-margin-top", "72",
+			"--pdf-page-margin-bottom", "54.5",
+			"--pdf-page-margin-left", "36",
+			"--pdf-page-margin-right", "36",
+		}},
+		{"zero margins", "pdf", "", &book.PDF{Margin: &book.Margin{Top: 0, Left: 10}}, []string{"--pdf-page-margin-left", "10"}},
+		{"fonts", "pdf", "", &book.PDF{FontFamily: "Noto Serif", FontSize: 12}, []string{
+			"--pdf-serif-family", "Noto Serif",
+			"--pdf-sans-family", "Noto Serif",
+			"--pdf-default-font-size", "12",
+		}},
+		{"page numbers", "pdf", "", &book.PDF{PageNumbers: &yes}, []string{"--pdf-page-numbers"}},
+		{"no page numbers", "pdf", "", &book.PDF{PageNumbers: &no}, nil},
+		{"header and footer", "pdf", "", &book.PDF{HeaderTemplate: "<p>_TITLE_</p>", FooterTemplate: "<p>_PAGENUM_</p>"}, []string{
+			"--pdf-header-template", "<p>_TITLE_</p>",
+			"--pdf-footer-template", "<p>_PAGENUM_</p>",
+		}},
+		{"chapter separation", "pdf", "", &book.PDF{ChapterMark: "pagebreak", PageBreaksBefore: "//h:h1"}, []string{
+			"--chapter-mark", "pagebreak",
+			"--page-breaks-before", "//h:h1",
+		}},
+		{"pdf settings outside pdf", "mobi", "/b/cover.jpg", &book.PDF{PaperSize: "a5", FontSize: 12, ChapterMark: "rule"}, []string{
+			"--cover", "/b/cover.jpg",
+			"--chapter-mark", "rule",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calibreOptions(newTestJob(tt.format, tt.cover, tt.pdf))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calibreOptions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPandocOptions(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		format string
+		cover  string
+		pdf    *book.PDF
+		want   []string
+	}{
+		{"unset", "pdf", "", nil, nil},
+		{"empty pdf", "pdf", "", &book.PDF{}, nil},
+		{"epub cover", "epub", "/b/cover.jpg", nil, []string{"--epub-cover-image", "/b/cover.jpg"}},
+		{"pdf cover", "pdf", "/b/cover.jpg", nil, nil},
+		{"paper size", "pdf", "", &book.PDF{PaperSize: "Letter"}, []string{"-V", "papersize=letter"}},
+		{"margins", "pdf", "", &book.PDF{Margin: &book.Margin{Top: 72, Bottom: 54.5, Left: 36, Right: 36}}, []string{
+			"-V", "geometry:top=72pt,bottom=54.5pt,left=36pt,right=36pt",
+		}},
+		{"zero margins", "pdf", "", &book.PDF{Margin: &book.Margin{Right: 20}}, []string{"-V", "geometry:right=20pt"}},
+		{"all margins zero", "pdf", "", &book.PDF{Margin: &book.Margin{}}, nil},
+		{"fonts", "pdf", "", &book.PDF{FontFamily: "Noto Serif", FontSize: 12}, []string{
+			"-V", "mainfont=Noto Serif",
+			"-V", "fontsize=12pt",
+		}},
+		{"page numbers", "pdf", "", &book.PDF{PageNumbers: &yes}, nil},
+		{"no page numbers", "pdf", "", &book.PDF{PageNumbers: &no}, []string{"-V", "pagestyle=empty"}},
+		{"header and footer", "pdf", "", &book.PDF{HeaderTemplate: "<p>_TITLE_</p>", FooterTemplate: "<p>_PAGENUM_</p>"}, nil},
+		{"chapter page breaks", "pdf", "", &book.PDF{ChapterMark: "both", PageBreaksBefore: "//h:h1"}, []string{"--top-level-division=chapter"}},
+		{"chapter rule", "pdf", "", &book.PDF{ChapterMark: "rule"}, nil},
+		{"pdf settings outside pdf", "docx", "", &book.PDF{PaperSize: "a5", ChapterMark: "pagebreak"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pandocOptions(newTestJob(tt.format, tt.cover, tt.pdf))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pandocOptions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,544 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	epubMimetype  = "application/epub+zip"
+	epubContainer = "META-INF/container.xml"
+)
+
+// Severity grades a validation finding
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "ERROR"
+	}
+	return "WARNING"
+}
+
+// Finding is a problem found in an EPUB, located by the entry path inside the
+// archive and, for XML errors, a line and column
+type Finding struct {
+	Severity Severity
+	Path     string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (f Finding) String() string {
+	location := f.Path
+	if f.Line > 0 {
+		location += fmt.Sprintf("(%d,%d)", f.Line, f.Column)
+	}
+	if location == "" {
+		return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, location, f.Message)
+}
+
+// opfPackage is the part of the package document the validator looks at
+type opfPackage struct {
+	Version          string `xml:"version,attr"`
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Identifiers []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+		Titles    []string `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Languages []string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Metas     []struct {
+			Property string `xml:"property,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []opfItem `xml:"manifest>item"`
+	Spine    struct {
+		Toc      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+	Fallback   string `xml:"fallback,attr"`
+}
+
+// epubValidator collects findings while checking one archive
+type epubValidator struct {
+	files    map[string]*zip.File
+	findings []Finding
+}
+
+// ValidateEPUB checks the structure of an EPUB file: the container, the
+// mimetype entry, the package document's metadata, manifest and spine, the
+// navigation document, the well-formedness of XHTML content documents and the
+// resources they reference. It returns an error only if the file cannot be
+// read as a ZIP archive.
+func ValidateEPUB(filename string) ([]Finding, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer zr.Close()
+
+	v := &epubValidator{files: make(map[string]*zip.File)}
+	for _, f := range zr.File {
+		if _, ok := v.files[f.Name]; ok {
+			v.errorf(f.Name, "duplicate entry in the ZIP archive")
+		}
+		v.files[f.Name] = f
+	}
+
+	v.checkMimetype(zr.File)
+	opfPath := v.checkContainer()
+	if opfPath != "" {
+		v.checkPackage(opfPath)
+	}
+	return v.findings, nil
+}
+
+func (v *epubValidator) errorf(name, format string, args ...any) {
+	v.findings = append(v.findings, Finding{Severity: SeverityError, Path: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *epubValidator) errorAt(name string, line, col int, format string, args ...any) {
+	v.findings = append(v.findings, Finding{Severity: SeverityError, Path: name, Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *epubValidator) warnf(name, format string, args ...any) {
+	v.findings = append(v.findings, Finding{Severity: SeverityWarning, Path: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *epubValidator) read(name string) ([]byte, error) {
+	f, ok := v.files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// checkMimetype checks that the archive starts with an uncompressed mimetype entry
+func (v *epubValidator) checkMimetype(files []*zip.File) {
+	if len(files) == 0 {
+		v.errorf("", "the archive is empty")
+		return
+	}
+	first := files[0]
+	if first.Name != "mimetype" {
+		if _, ok := v.files["mimetype"]; ok {
+			v.errorf("mimetype", "mimetype must be the first entry of the archive")
+		} else {
+			v.errorf("mimetype", "the mimetype entry is missing")
+			return
+		}
+	}
+	f := v.files["mimetype"]
+	if f.Method != zip.Store {
+		v.errorf("mimetype", "mimetype must be stored without compression")
+	}
+	if len(f.Extra) > 0 {
+		v.errorf("mimetype", "mimetype must not have an extra field")
+	}
+	data, err := v.read("mimetype")
+	if err != nil {
+		v.errorf("mimetype", "cannot be read: %v", err)
+		return
+	}
+	if string(data) != epubMimetype {
+		v.errorf("mimetype", "content must be %q without whitespace, found %q", epubMimetype, data)
+	}
+}
+
+// checkContainer checks META-INF/container.xml and returns the package document path
+func (v *epubValidator) checkContainer() string {
+	data, err := v.read(epubContainer)
+	if err != nil {
+		v.errorf(epubContainer, "the container file is missing")
+		return ""
+	}
+	if !v.wellFormed(epubContainer, data) {
+		return ""
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(data, &container); err != nil {
+		v.errorf(epubContainer, "cannot be parsed: %v", err)
+		return ""
+	}
+	for _, rf := range container.Rootfiles {
+		if rf.MediaType != "application/oebps-package+xml" {
+			continue
+		}
+		if _, ok := v.files[rf.FullPath]; !ok {
+			v.errorf(epubContainer, "the package document %s does not exist", rf.FullPath)
+			return ""
+		}
+		return rf.FullPath
+	}
+	v.errorf(epubContainer, "no rootfile with media type application/oebps-package+xml")
+	return ""
+}
+
+// checkPackage checks the package document and everything it declares
+func (v *epubValidator) checkPackage(opfPath string) {
+	data, err := v.read(opfPath)
+	if err != nil {
+		v.errorf(opfPath, "cannot be read: %v", err)
+		return
+	}
+	if !v.wellFormed(opfPath, data) {
+		return
+	}
+	var pkg opfPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		v.errorf(opfPath, "cannot be parsed: %v", err)
+		return
+	}
+	epub3 := strings.HasPrefix(pkg.Version, "3")
+	if pkg.Version == "" {
+		v.errorf(opfPath, "the package element has no version attribute")
+	}
+
+	v.checkMetadata(opfPath, &pkg, epub3)
+
+	// Manifest: unique ids and hrefs, every item present in the archive
+	baseDir := path.Dir(opfPath)
+	items := make(map[string]opfItem)
+	byPath := make(map[string]opfItem)
+	var nav []opfItem
+	for _, item := range pkg.Manifest {
+		if item.ID == "" {
+			v.errorf(opfPath, "manifest item %s has no id", item.Href)
+		} else if _, ok := items[item.ID]; ok {
+			v.errorf(opfPath, "duplicate manifest id %q", item.ID)
+		}
+		items[item.ID] = item
+		if item.MediaType == "" {
+			v.errorf(opfPath, "manifest item %q has no media-type", item.ID)
+		}
+
+		target, ok := resolveEntry(baseDir, item.Href)
+		if !ok {
+			if item.Href == "" {
+				v.errorf(opfPath, "manifest item %q has no href", item.ID)
+			}
+			continue // remote resources are allowed for some media types
+		}
+		if _, ok := byPath[target]; ok {
+			v.errorf(opfPath, "%s is declared more than once in the manifest", item.Href)
+		}
+		byPath[target] = item
+		if _, ok := v.files[target]; !ok {
+			v.errorf(opfPath, "manifest item %q refers to %s, which is missing from the archive", item.ID, target)
+		}
+		if hasProperty(item.Properties, "nav") {
+			nav = append(nav, item)
+		}
+		if hasProperty(item.Properties, "cover-image") && !strings.HasPrefix(item.MediaType, "image/") {
+			v.errorf(opfPath, "cover image %q has media type %s", item.ID, item.MediaType)
+		}
+		if item.Fallback != "" {
+			if _, ok := itemByID(pkg.Manifest, item.Fallback); !ok {
+				v.errorf(opfPath, "manifest item %q has an undeclared fallback %q", item.ID, item.Fallback)
+			}
+		}
+	}
+
+	// Archive entries that are not declared
+	names := make([]string, 0, len(v.files))
+	for name := range v.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "mimetype" || name == opfPath || strings.HasPrefix(name, "META-INF/") || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if _, ok := byPath[name]; !ok {
+			v.warnf(name, "the file is not declared in the manifest")
+		}
+	}
+
+	v.checkSpine(opfPath, &pkg, items, epub3)
+
+	if epub3 {
+		switch len(nav) {
+		case 0:
+			v.errorf(opfPath, "no manifest item has the nav property")
+		case 1:
+		default:
+			v.errorf(opfPath, "more than one manifest item has the nav property")
+		}
+	}
+
+	// Content documents: well-formed and referencing only packaged resources
+	for _, item := range pkg.Manifest {
+		target, ok := resolveEntry(baseDir, item.Href)
+		if !ok || item.MediaType != "application/xhtml+xml" {
+			if ok && item.MediaType == "text/css" {
+				v.checkStylesheet(target, byPath)
+			}
+			continue
+		}
+		data, err := v.read(target)
+		if err != nil {
+			continue // already reported as missing
+		}
+		if !v.wellFormed(target, data) {
+			continue
+		}
+		v.checkReferences(target, data, byPath)
+		if hasProperty(item.Properties, "nav") && !navHasTOC(data) {
+			v.errorf(target, `the navigation document has no nav element with epub:type="toc"`)
+		}
+	}
+}
+
+func (v *epubValidator) checkMetadata(opfPath string, pkg *opfPackage, epub3 bool) {
+	md := pkg.Metadata
+	if len(md.Titles) == 0 || strings.TrimSpace(strings.Join(md.Titles, "")) == "" {
+		v.errorf(opfPath, "the metadata has no dc:title")
+	}
+	if len(md.Languages) == 0 {
+		v.errorf(opfPath, "the metadata has no dc:language")
+	}
+	if len(md.Identifiers) == 0 {
+		v.errorf(opfPath, "the metadata has no dc:identifier")
+	}
+	if pkg.UniqueIdentifier == "" {
+		v.errorf(opfPath, "the package element has no unique-identifier attribute")
+	} else {
+		found := false
+		for _, id := range md.Identifiers {
+			if id.ID == pkg.UniqueIdentifier {
+				found = true
+				if strings.TrimSpace(id.Value) == "" {
+					v.errorf(opfPath, "the unique identifier %q is empty", id.ID)
+				}
+			}
+		}
+		if !found {
+			v.errorf(opfPath, "unique-identifier %q does not match any dc:identifier", pkg.UniqueIdentifier)
+		}
+	}
+
+	if !epub3 {
+		return
+	}
+	modified := ""
+	for _, meta := range md.Metas {
+		if meta.Property == "dcterms:modified" {
+			modified = strings.TrimSpace(meta.Value)
+		}
+	}
+	if modified == "" {
+		v.errorf(opfPath, "EPUB 3 metadata requires a dcterms:modified meta")
+	} else if !modifiedFormat.MatchString(modified) {
+		v.errorf(opfPath, "dcterms:modified %q must have the form CCYY-MM-DDThh:mm:ssZ", modified)
+	}
+}
+
+var modifiedFormat = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+
+func (v *epubValidator) checkSpine(opfPath string, pkg *opfPackage, items map[string]opfItem, epub3 bool) {
+	if len(pkg.Spine.ItemRefs) == 0 {
+		v.errorf(opfPath, "the spine is empty")
+	}
+	seen := make(map[string]bool)
+	linear := 0
+	for _, ref := range pkg.Spine.ItemRefs {
+		item, ok := items[ref.IDRef]
+		if !ok {
+			v.errorf(opfPath, "spine itemref %q is not declared in the manifest", ref.IDRef)
+			continue
+		}
+		if seen[ref.IDRef] {
+			v.errorf(opfPath, "spine itemref %q appears more than once", ref.IDRef)
+		}
+		seen[ref.IDRef] = true
+		if ref.Linear != "no" {
+			linear++
+		}
+		if item.MediaType != "application/xhtml+xml" && item.MediaType != "image/svg+xml" && item.Fallback == "" {
+			v.errorf(opfPath, "spine item %q has media type %s and no fallback", item.ID, item.MediaType)
+		}
+	}
+	if len(pkg.Spine.ItemRefs) > 0 && linear == 0 {
+		v.errorf(opfPath, "the spine has no linear item")
+	}
+
+	switch {
+	case pkg.Spine.Toc != "":
+		item, ok := items[pkg.Spine.Toc]
+		if !ok {
+			v.errorf(opfPath, "spine toc %q is not declared in the manifest", pkg.Spine.Toc)
+		} else if item.MediaType != "application/x-dtbncx+xml" {
+			v.errorf(opfPath, "spine toc %q is not an NCX document", pkg.Spine.Toc)
+		}
+	case !epub3:
+		v.errorf(opfPath, "EPUB 2 spine requires a toc attribute referring to the NCX")
+	}
+}
+
+// wellFormed reports whether data parses as XML, recording the first syntax error
+func (v *epubValidator) wellFormed(name string, data []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+	d.Entity = xml.HTMLEntity
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			line, col := d.InputPos()
+			msg := err.Error()
+			if se, ok := err.(*xml.SyntaxError); ok {
+				msg = se.Msg
+			}
+			v.errorAt(name, line, col, "malformed XML: %s", msg)
+			return false
+		}
+	}
+}
+
+// referenceAttrs are the attributes of content documents that point at resources
+var referenceAttrs = map[string]bool{"href": true, "src": true, "poster": true, "data": true}
+
+// checkReferences checks that the local resources referenced by a content
+// document exist in the archive and are declared in the manifest
+func (v *epubValidator) checkReferences(name string, data []byte, manifest map[string]opfItem) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if !referenceAttrs[attr.Name.Local] || (attr.Name.Space != "" && attr.Name.Space != "http://www.w3.org/1999/xlink") {
+				continue
+			}
+			target, ok := resolveEntry(path.Dir(name), attr.Value)
+			if !ok || target == name {
+				continue
+			}
+			line, col := d.InputPos()
+			if _, ok := v.files[target]; !ok {
+				v.errorAt(name, line, col, "referenced resource %s is missing from the archive", target)
+			} else if _, ok := manifest[target]; !ok {
+				v.errorAt(name, line, col, "referenced resource %s is not declared in the manifest", target)
+			}
+		}
+	}
+}
+
+var cssURL = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// checkStylesheet checks the url() references of a stylesheet
+func (v *epubValidator) checkStylesheet(name string, manifest map[string]opfItem) {
+	data, err := v.read(name)
+	if err != nil {
+		return
+	}
+	for _, m := range cssURL.FindAllSubmatch(data, -1) {
+		target, ok := resolveEntry(path.Dir(name), string(m[1]))
+		if !ok {
+			continue
+		}
+		if _, ok := v.files[target]; !ok {
+			v.errorf(name, "referenced resource %s is missing from the archive", target)
+		} else if _, ok := manifest[target]; !ok {
+			v.errorf(name, "referenced resource %s is not declared in the manifest", target)
+		}
+	}
+}
+
+// navHasTOC reports whether a navigation document contains a toc nav element
+func navHasTOC(data []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "nav" {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "type" && hasProperty(attr.Value, "toc") {
+					return true
+				}
+			}
+		}
+	}
+}
+
+// resolveEntry resolves a relative URL against a directory of the archive,
+// returning false for remote URLs and same-document fragments
+func resolveEntry(dir, ref string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		return strings.TrimPrefix(path.Clean(u.Path), "/"), true
+	}
+	return path.Join(dir, u.Path), true
+}
+
+func hasProperty(properties, name string) bool {
+	for _, p := range strings.Fields(properties) {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func itemByID(items []opfItem, id string) (opfItem, bool) {
+	for _, item := range items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return opfItem{}, false
+}
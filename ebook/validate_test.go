@@ -0,0 +1,156 @@
+package ebook
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestEPUB packages a small book with the native EPUB writer
+func writeTestEPUB(t *testing.T) string {
+	t.Helper()
+	b := newTestBuilder(t, map[string]string{
+		"book.json":    `{"title": "Guide", "author": "Ann"}`,
+		"README.md":    "# Intro\n\nSee [one](one.md).\n",
+		"one.md":       "# One\n\n![logo](img/logo.png)\n",
+		"img/logo.png": "\x89PNG\r\n\x1a\n",
+		"SUMMARY.md":   "# Summary\n\n* [Intro](README.md)\n* [One](one.md)\n",
+	})
+	w, err := NewEPUBWriter(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "book.epub")
+	if err := w.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// zipEntry is an archive entry as rewritten by rewriteZip
+type zipEntry struct {
+	Name   string
+	Method uint16
+	Data   string
+}
+
+// rewriteZip copies the entries of src to a new archive after passing them
+// through edit, which may reorder, change or drop them
+func rewriteZip(t *testing.T, src string, edit func([]zipEntry) []zipEntry) string {
+	t.Helper()
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	contents := readZip(t, src)
+	var entries []zipEntry
+	for _, f := range r.File {
+		entries = append(entries, zipEntry{Name: f.Name, Method: f.Method, Data: contents[f.Name]})
+	}
+
+	out := filepath.Join(t.TempDir(), "broken.epub")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range edit(entries) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.Name, Method: e.Method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.Data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func validationErrors(t *testing.T, file string) []string {
+	t.Helper()
+	findings, err := ValidateEPUB(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.String())
+		}
+	}
+	return errs
+}
+
+func TestValidateNativeEPUB(t *testing.T) {
+	if errs := validationErrors(t, writeTestEPUB(t)); len(errs) > 0 {
+		t.Errorf("the native EPUB has errors:\n%s", strings.Join(errs, "\n"))
+	}
+}
+
+func TestValidateBrokenEPUB(t *testing.T) {
+	good := writeTestEPUB(t)
+	tests := []struct {
+		name string
+		edit func([]zipEntry) []zipEntry
+		want string
+	}{
+		{
+			name: "missing mimetype",
+			edit: func(entries []zipEntry) []zipEntry { return entries[1:] },
+			want: "the mimetype entry is missing",
+		},
+		{
+			name: "mimetype not first",
+			edit: func(entries []zipEntry) []zipEntry { return append(entries[1:], entries[0]) },
+			want: "mimetype must be the first entry of the archive",
+		},
+		{
+			name: "compressed mimetype",
+			edit: func(entries []zipEntry) []zipEntry {
+				entries[0].Method = zip.Deflate
+				return entries
+			},
+			want: "mimetype must be stored without compression",
+		},
+		{
+			name: "broken nav link",
+			edit: func(entries []zipEntry) []zipEntry {
+				for i, e := range entries {
+					if e.Name == "OEBPS/nav.xhtml" {
+						entries[i].Data = strings.Replace(e.Data, `href="one.xhtml"`, `href="gone.xhtml"`, 1)
+					}
+				}
+				return entries
+			},
+			want: "referenced resource OEBPS/gone.xhtml is missing from the archive",
+		},
+		{
+			name: "missing manifest item",
+			edit: func(entries []zipEntry) []zipEntry {
+				var kept []zipEntry
+				for _, e := range entries {
+					if e.Name != "OEBPS/img/logo.png" {
+						kept = append(kept, e)
+					}
+				}
+				return kept
+			},
+			want: `refers to OEBPS/img/logo.png, which is missing from the archive`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validationErrors(t, rewriteZip(t, good, tt.edit))
+			if !strings.Contains(strings.Join(errs, "\n"), tt.want) {
+				t.Errorf("errors lack %q:\n%s", tt.want, strings.Join(errs, "\n"))
+			}
+		})
+	}
+}
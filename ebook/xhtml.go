@@ -0,0 +1,102 @@
+package ebook
+
+import (
+	stdhtml "html"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// voidElements are serialized as self-closing tags in XHTML
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "param": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// foreignNamespaces maps the namespaces of embedded SVG and MathML to their URIs
+var foreignNamespaces = map[string]string{
+	"svg":  "http://www.w3.org/2000/svg",
+	"math": "http://www.w3.org/1998/Math/MathML",
+}
+
+// toXHTML converts an HTML fragment, as rendered by goldmark with raw HTML
+// enabled, into well-formed XHTML. rewrite is called for every href and src
+// attribute and returns the value to write. Scripts and comments are dropped.
+func toXHTML(fragment string, rewrite func(attr, value string) string) (string, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		writeXHTMLNode(&sb, n, rewrite)
+	}
+	return sb.String(), nil
+}
+
+func writeXHTMLNode(sb *strings.Builder, n *html.Node, rewrite func(attr, value string) string) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(stdhtml.EscapeString(n.Data))
+	case html.ElementNode:
+		if n.Data == "script" || !isXMLName(n.Data) {
+			return
+		}
+		sb.WriteString("<" + n.Data)
+		// Foreign content needs its namespace declared at its root element
+		if ns := foreignNamespaces[n.Namespace]; ns != "" && (n.Parent == nil || n.Parent.Namespace != n.Namespace) {
+			sb.WriteString(` xmlns="` + ns + `"`)
+		}
+		for _, a := range n.Attr {
+			name := a.Key
+			if a.Namespace != "" {
+				name = a.Namespace + ":" + a.Key
+			}
+			// Namespace declarations are written by the serializer and the document root
+			if name == "xmlns" || a.Namespace == "xmlns" {
+				continue
+			}
+			if !isXMLName(name) || strings.HasPrefix(strings.ToLower(name), "on") {
+				continue
+			}
+			value := a.Val
+			if rewrite != nil && (name == "href" || name == "src") {
+				value = rewrite(name, value)
+			}
+			sb.WriteString(" " + name + `="` + stdhtml.EscapeString(value) + `"`)
+		}
+		if voidElements[n.Data] {
+			sb.WriteString("/>")
+			return
+		}
+		sb.WriteString(">")
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeXHTMLNode(sb, c, rewrite)
+		}
+		sb.WriteString("</" + n.Data + ">")
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeXHTMLNode(sb, c, rewrite)
+		}
+	}
+}
+
+// isXMLName reports whether s can be used as an XML element or attribute name
+func isXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case i > 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9')):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/yuin/goldmark/ast"
+)
+
+// docusaurusLayout writes the docs/ directory and sidebars.js of a Docusaurus
+// site; pages and assets keep their paths, so doc ids are the source paths
+// without extension
+type docusaurusLayout struct{}
+
+func (docusaurusLayout) contentDir() string { return "docs" }
+
+func (docusaurusLayout) assetDir() string { return "docs" }
+
+func (docusaurusLayout) targetPath(p *Page) string { return p.Source }
+
+func (docusaurusLayout) frontMatter(p *Page, fm book.FrontMatter) (book.FrontMatter, []string) {
+	return fm, nil
+}
+
+func (docusaurusLayout) chapterLink(from, to *Page, fragment string) string {
+	return escapeHref(relativeRef(from.Target, to.Target)) + fragment
+}
+
+func (docusaurusLayout) assetLink(from *Page, asset string) string {
+	return relativeRef(from.Target, asset)
+}
+
+var (
+	htmlVoidTag   = regexp.MustCompile(`(?i)<(br|hr|img|input|meta|link|source|col|wbr)\b([^>]*[^/])?>`)
+	htmlClassAttr = regexp.MustCompile(`\sclass\s*=`)
+	htmlStyleAttr = regexp.MustCompile(`\sstyle\s*=\s*["']`)
+)
+
+// unsupported reports raw HTML that MDX, which parses HTML as JSX, rejects
+func (docusaurusLayout) unsupported(n ast.Node, source []byte) string {
+	switch n.(type) {
+	case *builder.Math, *builder.MathBlock:
+		return "math needs the remark-math and rehype-katex plugins in docusaurus.config.js"
+	case *ast.RawHTML, *ast.HTMLBlock:
+		html := nodeText(n, source)
+		switch {
+		case strings.Contains(html, "<!--"):
+			return "HTML comments are not valid MDX; use {/* */}"
+		case htmlVoidTag.MatchString(html):
+			return "void HTML elements must be self-closed in MDX, e.g. <br />"
+		case htmlClassAttr.MatchString(html):
+			return "the class attribute must be written className in MDX"
+		case htmlStyleAttr.MatchString(html):
+			return "the style attribute must be a JSX object in MDX"
+		}
+	}
+	return ""
+}
+
+// docusaurusItem is an entry of sidebars.js
+type docusaurusItem struct {
+	Type  string           `json:"type"`
+	ID    string           `json:"id,omitempty"`
+	Label string           `json:"label"`
+	Link  *docusaurusLink  `json:"link,omitempty"`
+	Items []docusaurusItem `json:"items,omitempty"`
+}
+
+type docusaurusLink struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func (docusaurusLayout) finish(e *Exporter) error {
+	items := docusaurusParts(e.Pages)
+	data, err := json.MarshalIndent(items, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Generated from SUMMARY.md by gitbook export\n\n")
+	sb.WriteString("/** @type {import('@docusaurus/plugin-content-docs').SidebarsConfig} */\n")
+	sb.WriteString("const sidebars = {\n")
+	fmt.Fprintf(&sb, "  bookSidebar: %s,\n", data)
+	sb.WriteString("};\n\nmodule.exports = sidebars;\n")
+	return os.WriteFile(filepath.Join(e.OutputDir, "sidebars.js"), []byte(sb.String()), 0644)
+}
+
+// docusaurusParts groups the top-level pages of each part of the summary
+// into a category labelled with the part title
+func docusaurusParts(pages []*Page) []docusaurusItem {
+	var items []docusaurusItem
+	part := -1 // index of the category of the current part in items
+	for i, p := range pages {
+		if p.Part != "" {
+			items = append(items, docusaurusItem{Type: "category", Label: p.Part, Items: []docusaurusItem{}})
+			part = len(items) - 1
+		}
+		page := docusaurusItems(pages[i : i+1])
+		if part < 0 {
+			items = append(items, page...)
+		} else {
+			items[part].Items = append(items[part].Items, page...)
+		}
+	}
+	// Docusaurus rejects categories without items
+	kept := make([]docusaurusItem, 0, len(items))
+	for _, item := range items {
+		if item.Type != "category" || len(item.Items) > 0 {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// docusaurusItems maps pages to docs, and pages with articles to categories
+// linking to their page
+func docusaurusItems(pages []*Page) []docusaurusItem {
+	items := make([]docusaurusItem, 0, len(pages))
+	for _, p := range pages {
+		id := docusaurusID(p)
+		if len(p.Children) == 0 {
+			if id == "" {
+				continue // nothing to link to
+			}
+			items = append(items, docusaurusItem{Type: "doc", ID: id, Label: p.Title})
+			continue
+		}
+		item := docusaurusItem{Type: "category", Label: p.Title, Items: docusaurusItems(p.Children)}
+		if id != "" {
+			item.Link = &docusaurusLink{Type: "doc", ID: id}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func docusaurusID(p *Page) string {
+	if p.Target == "" {
+		return ""
+	}
+	return strings.TrimSuffix(p.Target, path.Ext(p.Target))
+}
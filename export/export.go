@@ -0,0 +1,463 @@
+// Package export converts a book into the source layout of other documentation
+// tools, so that it can be moved to them without hand-editing.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/yuin/goldmark/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// Targets lists the supported export targets
+var Targets = []string{"mdbook", "docusaurus", "hugo"}
+
+// Issue is a construct of the book that could not be translated to the target
+type Issue struct {
+	Path    string // file relative to the book root, empty for the whole book
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	switch {
+	case i.Path == "":
+		return i.Message
+	case i.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", i.Path, i.Line, i.Message)
+	default:
+		return fmt.Sprintf("%s: %s", i.Path, i.Message)
+	}
+}
+
+// Page is an entry of the summary as laid out in the target
+type Page struct {
+	Title    string
+	Source   string // markdown source relative to the book root, empty for title-only entries
+	Target   string // path relative to the content directory of the target
+	Weight   int    // position in reading order, starting at 1
+	Part     string // title of the part ("## Part" in SUMMARY.md) opened by this top-level page
+	Children []*Page
+}
+
+// layout is implemented by each export target
+type layout interface {
+	// contentDir is the directory receiving pages, relative to the output directory
+	contentDir() string
+	// assetDir is the directory receiving other files, relative to the output directory
+	assetDir() string
+	// targetPath maps a page to its path in the content directory
+	targetPath(p *Page) string
+	// frontMatter returns the front matter to write for a page, nil for none,
+	// and the keys that were dropped
+	frontMatter(p *Page, fm book.FrontMatter) (book.FrontMatter, []string)
+	// chapterLink returns the destination of a link between two pages
+	chapterLink(from, to *Page, fragment string) string
+	// assetLink returns the destination of a reference to a file relative to the book root
+	assetLink(from *Page, asset string) string
+	// unsupported describes why a node cannot be kept as is, or returns ""
+	unsupported(n ast.Node, source []byte) string
+	// finish writes the navigation and configuration files
+	finish(e *Exporter) error
+}
+
+// Exporter converts a book into the layout of another documentation tool
+type Exporter struct {
+	Book      *book.Book
+	Target    string
+	OutputDir string
+	Pages     []*Page // top-level summary entries
+	Issues    []Issue
+
+	layout   layout
+	builder  *builder.Builder
+	bySource map[string]*Page
+	reported map[string]bool
+}
+
+// NewExporter prepares the export of the book at bookRoot into outputDir
+func NewExporter(bookRoot, target, outputDir string, opts ...book.LoadOption) (*Exporter, error) {
+	var l layout
+	switch target {
+	case "mdbook":
+		l = mdbookLayout{}
+	case "docusaurus":
+		l = docusaurusLayout{}
+	case "hugo":
+		l = &hugoLayout{}
+	default:
+		return nil, fmt.Errorf("unknown export target %q (supported: %s)", target, strings.Join(Targets, ", "))
+	}
+
+	b, err := builder.NewBuilder(bookRoot, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{
+		Book:      b.Book,
+		Target:    target,
+		OutputDir: absOutput,
+		layout:    l,
+		builder:   b,
+		bySource:  make(map[string]*Page),
+		reported:  make(map[string]bool),
+	}, nil
+}
+
+// Export writes the pages, assets, navigation and configuration of the target.
+// Constructs that cannot be translated are kept as they are and recorded in Issues.
+func (e *Exporter) Export() error {
+	if e.Book.Summary == nil {
+		return fmt.Errorf("SUMMARY.md not found in %s", e.Book.Root)
+	}
+	weight := 0
+	e.Pages = e.pages(e.Book.Summary.Chapters, &weight)
+	e.assignTargets(e.Pages)
+
+	if err := os.MkdirAll(filepath.Join(e.OutputDir, e.layout.contentDir()), 0755); err != nil {
+		return err
+	}
+	if err := e.copyAssets(); err != nil {
+		return fmt.Errorf("failed to copy assets: %w", err)
+	}
+
+	sources := make([]string, 0, len(e.bySource))
+	for source := range e.bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		if err := e.writePage(e.bySource[source]); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+
+	if err := e.layout.finish(e); err != nil {
+		return err
+	}
+
+	sort.SliceStable(e.Issues, func(i, j int) bool {
+		if e.Issues[i].Path != e.Issues[j].Path {
+			return e.Issues[i].Path < e.Issues[j].Path
+		}
+		return e.Issues[i].Line < e.Issues[j].Line
+	})
+	return nil
+}
+
+// pages converts summary entries into pages; missing files become title-only
+// entries so that their articles are kept
+func (e *Exporter) pages(chapters []book.Chapter, weight *int) []*Page {
+	var pages []*Page
+	for _, ch := range chapters {
+		*weight++
+		p := &Page{Title: ch.Title, Weight: *weight, Part: ch.Part}
+		if ch.Path != "" {
+			source := path.Clean(filepath.ToSlash(ch.Path))
+			file, err := book.ResolvePath(e.Book.Root, source)
+			if err != nil {
+				e.report(source, 0, "listed in SUMMARY.md but outside the book; exported as a title-only entry")
+			} else if _, err := os.Stat(file); err != nil {
+				e.report(source, 0, "listed in SUMMARY.md but missing; exported as a title-only entry")
+			} else {
+				p.Source = source
+				if _, ok := e.bySource[source]; !ok {
+					e.bySource[source] = p // pages listed twice are written once
+				}
+			}
+		}
+		p.Children = append(p.Children, e.pages(ch.Articles, weight)...)
+		pages = append(pages, p)
+	}
+	return pages
+}
+
+func (e *Exporter) assignTargets(pages []*Page) {
+	for _, p := range pages {
+		e.assignTargets(p.Children)
+		if p.Source != "" && p.Target == "" {
+			p.Target = e.layout.targetPath(p)
+		}
+	}
+}
+
+// report records an issue once per file and message
+func (e *Exporter) report(path string, line int, message string) {
+	key := path + "\x00" + message
+	if e.reported[key] {
+		return
+	}
+	e.reported[key] = true
+	e.Issues = append(e.Issues, Issue{Path: path, Line: line, Message: message})
+}
+
+// copyAssets copies the files of the book that are not markdown into the
+// asset directory, and reports markdown files missing from the summary
+func (e *Exporter) copyAssets() error {
+	root := e.Book.Root
+	summary := "SUMMARY.md"
+	if e.Book.Config != nil && e.Book.Config.Structure != nil && e.Book.Config.Structure.Summary != "" {
+		summary = e.Book.Config.Structure.Summary
+	}
+
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			base := filepath.Base(p)
+			if p != root && (base == "_book" || base == "node_modules" || strings.HasPrefix(base, ".") || p == e.OutputDir) {
+				return filepath.SkipDir
+			}
+			if rel, err := filepath.Rel(root, p); err == nil && e.Book.Ignore.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if e.Book.Ignore.Match(rel, false) {
+			return nil
+		}
+		ext := strings.ToLower(path.Ext(rel))
+		if ext == ".md" || ext == ".markdown" {
+			if _, ok := e.bySource[rel]; !ok && rel != summary {
+				e.report(rel, 0, "not listed in SUMMARY.md; not exported")
+			}
+			return nil
+		}
+		if book.IsConfigFile(rel) {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(e.OutputDir, e.layout.assetDir(), filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, info.Mode())
+	})
+}
+
+var (
+	variableTag = regexp.MustCompile(`\{\{\s*book\.([A-Za-z0-9_.-]+)\s*\}\}`)
+	templateTag = regexp.MustCompile(`\{%-?\s*([A-Za-z_]+)[^%]*-?%\}|\{\{[^}]*\}\}`)
+	markdownRef = regexp.MustCompile(`(\]\()(<[^>]*>|[^)\s]+)`)
+)
+
+// writePage writes a page with variables substituted, links rewritten for the
+// target and its front matter translated
+func (e *Exporter) writePage(p *Page) error {
+	contentDir := filepath.Join(e.OutputDir, e.layout.contentDir())
+	dst := filepath.Join(contentDir, filepath.FromSlash(p.Target))
+	if !book.Within(contentDir, dst) {
+		e.report(p.Source, 0, fmt.Sprintf("would be written to %s, outside the %s content directory; not exported", p.Target, e.Target))
+		return nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(e.Book.Root, filepath.FromSlash(p.Source)))
+	if err != nil {
+		return err
+	}
+	fm, body, err := book.ParseFrontMatter(content)
+	if err != nil {
+		return err
+	}
+	offset := bytes.Count(content[:len(content)-len(body)], []byte("\n"))
+
+	e.checkNodes(p, offset)
+
+	lines := strings.Split(string(body), "\n")
+	fence := ""
+	for i, line := range lines {
+		lineNo := offset + i + 1
+		line = e.substituteVariables(p.Source, lineNo, line)
+
+		// Template tags and links inside fenced code are left alone
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			fence = ""
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+		case fence == "":
+			if m := templateTag.FindString(variableTag.ReplaceAllString(line, "")); m != "" {
+				e.report(p.Source, lineNo, fmt.Sprintf("template tag %s has no equivalent in %s; left as is", m, e.Target))
+			}
+			line = e.rewriteRefs(p, line)
+		}
+		lines[i] = line
+	}
+
+	out, dropped := e.layout.frontMatter(p, fm)
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		e.report(p.Source, 1, fmt.Sprintf("front matter keys not supported by %s were dropped: %s", e.Target, strings.Join(dropped, ", ")))
+	}
+	var buf bytes.Buffer
+	if len(out) > 0 {
+		data, err := yaml.Marshal(map[string]interface{}(out))
+		if err != nil {
+			return err
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+		buf.WriteString("---\n")
+	}
+	buf.WriteString(strings.Join(lines, "\n"))
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, buf.Bytes(), 0644)
+}
+
+// substituteVariables replaces legacy {{ book.name }} tags with the values of
+// the variables in book.json
+func (e *Exporter) substituteVariables(source string, lineNo int, line string) string {
+	var variables map[string]interface{}
+	if e.Book.Config != nil {
+		variables = e.Book.Config.Variables
+	}
+	return variableTag.ReplaceAllStringFunc(line, func(tag string) string {
+		name := variableTag.FindStringSubmatch(tag)[1]
+		var value interface{} = variables
+		for _, key := range strings.Split(name, ".") {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = m[key]
+		}
+		if value == nil {
+			e.report(source, lineNo, fmt.Sprintf("variable book.%s is not defined in book.json; left as is", name))
+			return tag
+		}
+		return fmt.Sprint(value)
+	})
+}
+
+// rewriteRefs rewrites the destinations of markdown links and images on a line
+func (e *Exporter) rewriteRefs(p *Page, line string) string {
+	return markdownRef.ReplaceAllStringFunc(line, func(m string) string {
+		parts := markdownRef.FindStringSubmatch(m)
+		ref := strings.TrimSuffix(strings.TrimPrefix(parts[2], "<"), ">")
+		target, fragment, ok := builder.ResolveRef(path.Dir(p.Source), ref)
+		if !ok {
+			return m
+		}
+		if to, ok := e.bySource[target]; ok {
+			return parts[1] + e.layout.chapterLink(p, to, fragment)
+		}
+		ext := strings.ToLower(path.Ext(target))
+		if ext == ".md" || ext == ".markdown" {
+			return m
+		}
+		return parts[1] + escapeHref(e.layout.assetLink(p, target)) + fragment
+	})
+}
+
+// checkNodes reports the nodes of a page the target cannot render as they are
+func (e *Exporter) checkNodes(p *Page, offset int) {
+	doc, source, err := e.builder.ParseChapter(p.Source)
+	if err != nil {
+		return
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if msg := e.layout.unsupported(n, source); msg != "" {
+			e.report(p.Source, offset+nodeLine(n, source), msg)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// nodeLine returns the 1-based line of a node in source, or of its closest
+// block ancestor for inline nodes without segments
+func nodeLine(n ast.Node, source []byte) int {
+	for ; n != nil; n = n.Parent() {
+		start := -1
+		switch n := n.(type) {
+		case *ast.RawHTML:
+			if n.Segments.Len() > 0 {
+				start = n.Segments.At(0).Start
+			}
+		case *ast.Text:
+			start = n.Segment.Start
+		default:
+			if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+				start = n.Lines().At(0).Start
+			}
+		}
+		if start >= 0 {
+			return bytes.Count(source[:start], []byte("\n")) + 1
+		}
+	}
+	return 0
+}
+
+// nodeText returns the raw source of an HTML node
+func nodeText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	switch n := n.(type) {
+	case *ast.RawHTML:
+		for i := 0; i < n.Segments.Len(); i++ {
+			seg := n.Segments.At(i)
+			sb.Write(seg.Value(source))
+		}
+	case *ast.HTMLBlock:
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			sb.Write(line.Value(source))
+		}
+	}
+	return sb.String()
+}
+
+// relativeRef returns the path of target relative to the directory of a page
+func relativeRef(from, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// escapeHref percent-encodes a path for use as a link destination
+func escapeHref(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// copyFrontMatter returns a shallow copy of fm
+func copyFrontMatter(fm book.FrontMatter) book.FrontMatter {
+	out := make(book.FrontMatter, len(fm))
+	for k, v := range fm {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,391 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exportBook covers parts, nesting, a title-only entry, links with anchors,
+// assets, front matter, variables, math and raw HTML
+var exportBook = map[string]string{
+	"README.md": `# Introduction
+
+Read [the basics](guide/basics.md#setup) for version {{ book.version }}.
+`,
+	"SUMMARY.md": `# Summary
+
+* [Introduction](README.md)
+
+## Part One
+
+* [Basics](guide/basics.md)
+  * [Advanced](guide/advanced.md)
+* [Appendix]()
+  * [FAQ](guide/faq.md)
+`,
+	"book.json": `{
+  "title": "The \"Go\" Guide",
+  "author": "Ann",
+  "description": "A guide\nin two lines",
+  "language": "en",
+  "siteUrl": "https://example.com/guide/",
+  "variables": {"version": "1.0", "count": 2, "ratio": 0.5, "skip": null}
+}
+`,
+	"guide/advanced.md": `# Advanced
+
+Math: $x^2$
+
+<div>raw</div>
+`,
+	"guide/basics.md": `---
+title: Basics
+description: First steps
+---
+
+# Basics
+
+## Setup
+
+![logo](../images/logo.png)
+
+See [advanced](advanced.md).
+`,
+	"guide/faq.md": `# FAQ
+
+Nothing yet.
+`,
+	"images/logo.png": `PNG`,
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree returns the files under dir by slash-separated relative path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(file string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, file)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestExportGolden(t *testing.T) {
+	tests := []struct {
+		target string
+		want   map[string]string
+		issues []string
+	}{
+		{
+			target: "mdbook",
+			want: map[string]string{
+				"book.toml": `[book]
+title = 'The "Go" Guide'
+authors = ['Ann']
+description = "A guide\nin two lines"
+language = 'en'
+src = 'src'
+
+[output]
+[output.html]
+site-url = 'https://example.com/guide/'
+`,
+				"src/README.md": `# Introduction
+
+Read [the basics](guide/basics.md#setup) for version 1.0.
+`,
+				"src/SUMMARY.md": `# Summary
+
+- [Introduction](README.md)
+
+# Part One
+
+- [Basics](guide/basics.md)
+    - [Advanced](guide/advanced.md)
+
+# Appendix
+
+- [FAQ](guide/faq.md)
+`,
+				"src/guide/advanced.md": `# Advanced
+
+Math: $x^2$
+
+<div>raw</div>
+`,
+				"src/guide/basics.md": `
+# Basics
+
+## Setup
+
+![logo](../images/logo.png)
+
+See [advanced](advanced.md).
+`,
+				"src/guide/faq.md": `# FAQ
+
+Nothing yet.
+`,
+				"src/images/logo.png": `PNG`,
+			},
+			issues: []string{
+				`guide/advanced.md:3: $ math is not recognized by mdBook's MathJax support, which expects \\( \\) and \\[ \\]`,
+				`guide/basics.md:1: front matter keys not supported by mdbook were dropped: description, title`,
+			},
+		},
+		{
+			target: "docusaurus",
+			want: map[string]string{
+				"docs/README.md": `# Introduction
+
+Read [the basics](guide/basics.md#setup) for version 1.0.
+`,
+				"docs/guide/advanced.md": `# Advanced
+
+Math: $x^2$
+
+<div>raw</div>
+`,
+				"docs/guide/basics.md": `---
+description: First steps
+title: Basics
+---
+
+# Basics
+
+## Setup
+
+![logo](../images/logo.png)
+
+See [advanced](advanced.md).
+`,
+				"docs/guide/faq.md": `# FAQ
+
+Nothing yet.
+`,
+				"docs/images/logo.png": `PNG`,
+				"sidebars.js": `// Generated from SUMMARY.md by gitbook export
+
+/** @type {import('@docusaurus/plugin-content-docs').SidebarsConfig} */
+const sidebars = {
+  bookSidebar: [
+    {
+      "type": "doc",
+      "id": "README",
+      "label": "Introduction"
+    },
+    {
+      "type": "category",
+      "label": "Part One",
+      "items": [
+        {
+          "type": "category",
+          "label": "Basics",
+          "link": {
+            "type": "doc",
+            "id": "guide/basics"
+          },
+          "items": [
+            {
+              "type": "doc",
+              "id": "guide/advanced",
+              "label": "Advanced"
+            }
+          ]
+        },
+        {
+          "type": "category",
+          "label": "Appendix",
+          "items": [
+            {
+              "type": "doc",
+              "id": "guide/faq",
+              "label": "FAQ"
+            }
+          ]
+        }
+      ]
+    }
+  ],
+};
+
+module.exports = sidebars;
+`,
+			},
+			issues: []string{
+				`guide/advanced.md:3: math needs the remark-math and rehype-katex plugins in docusaurus.config.js`,
+			},
+		},
+		{
+			target: "hugo",
+			want: map[string]string{
+				"content/_index.md": `---
+title: Introduction
+weight: 1
+---
+# Introduction
+
+Read [the basics]({{< relref "/guide/basics.md#setup" >}}) for version 1.0.
+`,
+				"content/guide/advanced.md": `---
+title: Advanced
+weight: 3
+---
+# Advanced
+
+Math: $x^2$
+
+<div>raw</div>
+`,
+				"content/guide/basics.md": `---
+description: First steps
+title: Basics
+weight: 2
+---
+
+# Basics
+
+## Setup
+
+![logo](/images/logo.png)
+
+See [advanced]({{< relref "/guide/advanced.md" >}}).
+`,
+				"content/guide/faq.md": `---
+title: FAQ
+weight: 5
+---
+# FAQ
+
+Nothing yet.
+`,
+				"hugo.toml": `baseURL = 'https://example.com/guide/'
+title = 'The "Go" Guide'
+languageCode = 'en'
+
+[params]
+author = 'Ann'
+count = 2
+description = "A guide\nin two lines"
+math = true
+ratio = 0.5
+version = '1.0'
+
+[markup]
+[markup.goldmark]
+[markup.goldmark.extensions]
+[markup.goldmark.extensions.passthrough]
+enable = true
+
+[markup.goldmark.extensions.passthrough.delimiters]
+block = [['$$', '$$']]
+inline = [['$', '$']]
+
+[markup.goldmark.renderer]
+unsafe = true
+`,
+				"static/images/logo.png": `PNG`,
+			},
+			issues: []string{
+				`SUMMARY.md: part "Part One" has no equivalent in Hugo; dropped`,
+				`SUMMARY.md: title-only entry "Appendix" has no page in Hugo; its articles keep their own sections`,
+				`book.json: variable skip cannot be written to hugo.toml; skipped`,
+				`guide/advanced.md: nested under "Basics" in SUMMARY.md, but Hugo derives sections from directories`,
+				`guide/advanced.md:3: math is passed through to the theme, which must load MathJax or KaTeX`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, exportBook)
+			out := filepath.Join(t.TempDir(), "out")
+			exp, err := NewExporter(root, tt.target, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := exp.Export(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := readTree(t, out)
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s =\n%s\nwant\n%s", name, got[name], want)
+				}
+			}
+			for name := range got {
+				if _, ok := tt.want[name]; !ok {
+					t.Errorf("unexpected file %s", name)
+				}
+			}
+
+			var issues []string
+			for _, issue := range exp.Issues {
+				issues = append(issues, issue.String())
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues =\n%s\nwant\n%s", strings.Join(issues, "\n"), strings.Join(tt.issues, "\n"))
+			}
+		})
+	}
+}
+
+func TestExportOutsideBook(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "book")
+	writeFiles(t, dir, map[string]string{
+		"outside.md":      "# Outside\n",
+		"book/README.md":  "# Intro\n",
+		"book/SUMMARY.md": "# Summary\n\n* [Intro](README.md)\n* [Out](../outside.md)\n  * [Deep](../../outside.md)\n",
+	})
+	out := filepath.Join(dir, "out", "deep")
+	exp, err := NewExporter(root, "mdbook", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := readTree(t, filepath.Join(dir, "out")); len(files) != 3 {
+		t.Errorf("files written: %v, want book.toml, src/README.md and src/SUMMARY.md", files)
+	}
+	var issues []string
+	for _, issue := range exp.Issues {
+		issues = append(issues, issue.String())
+	}
+	want := []string{
+		"../../outside.md: listed in SUMMARY.md but outside the book; exported as a title-only entry",
+		"../outside.md: listed in SUMMARY.md but outside the book; exported as a title-only entry",
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(issues, "\n"), strings.Join(want, "\n"))
+	}
+}
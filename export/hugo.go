@@ -0,0 +1,203 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/yuin/goldmark/ast"
+)
+
+// hugoLayout writes the content/ and static/ directories and hugo.toml of a
+// Hugo site. README.md pages become section indexes (_index.md) and the
+// summary order is kept with weights; links between pages use relref.
+type hugoLayout struct {
+	math bool // some page uses $ math
+	html bool // some page uses raw HTML
+}
+
+func (*hugoLayout) contentDir() string { return "content" }
+
+func (*hugoLayout) assetDir() string { return "static" }
+
+func (*hugoLayout) targetPath(p *Page) string {
+	if path.Base(p.Source) == "README.md" {
+		return path.Join(path.Dir(p.Source), "_index.md")
+	}
+	return p.Source
+}
+
+func (*hugoLayout) frontMatter(p *Page, fm book.FrontMatter) (book.FrontMatter, []string) {
+	out := copyFrontMatter(fm)
+	if out.String("title") == "" {
+		out["title"] = p.Title
+	}
+	out["weight"] = p.Weight
+	return out, nil
+}
+
+func (*hugoLayout) chapterLink(from, to *Page, fragment string) string {
+	return fmt.Sprintf(`{{< relref "/%s%s" >}}`, to.Target, fragment)
+}
+
+// assetLink makes references absolute: pages are published as directories, so
+// paths relative to the markdown file no longer resolve
+func (*hugoLayout) assetLink(from *Page, asset string) string {
+	return "/" + asset
+}
+
+func (l *hugoLayout) unsupported(n ast.Node, source []byte) string {
+	switch n.(type) {
+	case *builder.Math, *builder.MathBlock:
+		l.math = true
+		return "math is passed through to the theme, which must load MathJax or KaTeX"
+	case *ast.RawHTML, *ast.HTMLBlock:
+		l.html = true
+	}
+	return ""
+}
+
+func (l *hugoLayout) finish(e *Exporter) error {
+	l.checkSections(e, e.Pages, nil)
+
+	cfg := e.Book.Config
+	if cfg == nil {
+		cfg = &book.Config{}
+	}
+	config := hugoConfig{
+		BaseURL:      cfg.SiteURL,
+		Title:        cfg.Title,
+		LanguageCode: cfg.Language,
+		Params:       make(map[string]interface{}),
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "/"
+	}
+	for k, v := range cfg.Variables {
+		value, ok := tomlParam(v)
+		if !ok {
+			e.report("book.json", 0, fmt.Sprintf("variable %s cannot be written to hugo.toml; skipped", k))
+			continue
+		}
+		config.Params[k] = value
+	}
+	if cfg.Author != "" {
+		config.Params["author"] = cfg.Author
+	}
+	if cfg.Description != "" {
+		config.Params["description"] = cfg.Description
+	}
+	if l.math {
+		config.Params["math"] = true
+	}
+
+	goldmark := make(map[string]interface{})
+	if l.html {
+		goldmark["renderer"] = map[string]interface{}{"unsafe": true}
+	}
+	if l.math {
+		goldmark["extensions"] = map[string]interface{}{
+			"passthrough": map[string]interface{}{
+				"enable": true,
+				"delimiters": map[string]interface{}{
+					"block":  [][]string{{"$$", "$$"}},
+					"inline": [][]string{{"$", "$"}},
+				},
+			},
+		}
+	}
+	if len(goldmark) > 0 {
+		config.Markup = map[string]interface{}{"goldmark": goldmark}
+	}
+
+	data, err := toml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.OutputDir, "hugo.toml"), data, 0644)
+}
+
+// hugoConfig is the hugo.toml of a Hugo site
+type hugoConfig struct {
+	BaseURL      string                 `toml:"baseURL"`
+	Title        string                 `toml:"title"`
+	LanguageCode string                 `toml:"languageCode,omitempty"`
+	Params       map[string]interface{} `toml:"params,omitempty"`
+	Markup       map[string]interface{} `toml:"markup,omitempty"`
+}
+
+// checkSections reports summary nesting that Hugo, which derives sections
+// from directories, cannot reproduce
+func (l *hugoLayout) checkSections(e *Exporter, pages []*Page, parent *Page) {
+	for _, p := range pages {
+		if p.Part != "" {
+			e.report("SUMMARY.md", 0, fmt.Sprintf("part %q has no equivalent in Hugo; dropped", p.Part))
+		}
+		switch {
+		case p.Source == "":
+			e.report("SUMMARY.md", 0, fmt.Sprintf("title-only entry %q has no page in Hugo; its articles keep their own sections", p.Title))
+		case parent != nil && parent.Source != "" && hugoParentSection(p) != hugoSection(parent):
+			e.report(p.Source, 0, fmt.Sprintf("nested under %q in SUMMARY.md, but Hugo derives sections from directories", parent.Title))
+		}
+		l.checkSections(e, p.Children, p)
+	}
+}
+
+// hugoSection returns the directory of the section a page opens, or the page
+// itself for leaf pages, which cannot contain others
+func hugoSection(p *Page) string {
+	if path.Base(p.Target) == "_index.md" {
+		return path.Dir(p.Target)
+	}
+	return strings.TrimSuffix(p.Target, path.Ext(p.Target))
+}
+
+// hugoParentSection returns the directory of the section containing a page
+func hugoParentSection(p *Page) string {
+	dir := path.Dir(p.Target)
+	if path.Base(p.Target) == "_index.md" {
+		dir = path.Dir(dir)
+	}
+	return dir
+}
+
+// tomlParam converts a JSON value for the TOML encoder, reporting false for
+// null, which TOML cannot hold. Whole numbers become integers, as written in
+// book.json.
+func tomlParam(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), true
+		}
+	case map[string]interface{}:
+		table := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			value, ok := tomlParam(item)
+			if !ok {
+				return nil, false
+			}
+			table[k] = value
+		}
+		return table, true
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			value, ok := tomlParam(item)
+			if !ok {
+				return nil, false
+			}
+			items[i] = value
+		}
+		return items, true
+	}
+	return v, true
+}
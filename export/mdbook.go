@@ -0,0 +1,128 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/yuin/goldmark/ast"
+)
+
+// mdbookLayout writes an mdBook project: book.toml and src/ holding SUMMARY.md,
+// the pages and the assets at their original paths
+type mdbookLayout struct{}
+
+func (mdbookLayout) contentDir() string { return "src" }
+
+func (mdbookLayout) assetDir() string { return "src" }
+
+func (mdbookLayout) targetPath(p *Page) string { return p.Source }
+
+// frontMatter drops front matter, which mdBook would render as text
+func (mdbookLayout) frontMatter(p *Page, fm book.FrontMatter) (book.FrontMatter, []string) {
+	var dropped []string
+	for key := range fm {
+		dropped = append(dropped, key)
+	}
+	return nil, dropped
+}
+
+func (mdbookLayout) chapterLink(from, to *Page, fragment string) string {
+	return escapeHref(relativeRef(from.Target, to.Target)) + fragment
+}
+
+func (mdbookLayout) assetLink(from *Page, asset string) string {
+	return relativeRef(from.Target, asset)
+}
+
+func (mdbookLayout) unsupported(n ast.Node, source []byte) string {
+	switch n.(type) {
+	case *builder.Math, *builder.MathBlock:
+		return `$ math is not recognized by mdBook's MathJax support, which expects \\( \\) and \\[ \\]`
+	}
+	return ""
+}
+
+func (l mdbookLayout) finish(e *Exporter) error {
+	var summary strings.Builder
+	summary.WriteString("# Summary\n\n")
+	for _, p := range e.Pages {
+		if p.Part != "" {
+			fmt.Fprintf(&summary, "\n# %s\n\n", p.Part)
+		}
+		if p.Source == "" {
+			// Top-level title-only entries become part titles
+			fmt.Fprintf(&summary, "\n# %s\n\n", p.Title)
+			writeMdbookSummary(&summary, p.Children, 0)
+			continue
+		}
+		writeMdbookSummary(&summary, []*Page{p}, 0)
+	}
+	if err := os.WriteFile(filepath.Join(e.OutputDir, "src", "SUMMARY.md"), []byte(summary.String()), 0644); err != nil {
+		return err
+	}
+
+	cfg := e.Book.Config
+	if cfg == nil {
+		cfg = &book.Config{}
+	}
+	var config mdbookConfig
+	config.Book.Title = cfg.Title
+	if cfg.Author != "" {
+		config.Book.Authors = []string{cfg.Author}
+	}
+	config.Book.Description = cfg.Description
+	config.Book.Language = cfg.Language
+	config.Book.Src = "src"
+	if cfg.SiteURL != "" {
+		config.Output = &mdbookOutput{}
+		config.Output.HTML.SiteURL = cfg.SiteURL
+	}
+	data, err := toml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.OutputDir, "book.toml"), data, 0644)
+}
+
+// mdbookConfig is the book.toml of an mdBook project
+type mdbookConfig struct {
+	Book struct {
+		Title       string   `toml:"title"`
+		Authors     []string `toml:"authors,omitempty"`
+		Description string   `toml:"description,omitempty"`
+		Language    string   `toml:"language,omitempty"`
+		Src         string   `toml:"src"`
+	} `toml:"book"`
+	Output *mdbookOutput `toml:"output,omitempty"`
+}
+
+type mdbookOutput struct {
+	HTML struct {
+		SiteURL string `toml:"site-url"`
+	} `toml:"html"`
+}
+
+// writeMdbookSummary writes numbered chapters; nested title-only entries
+// become draft chapters
+func writeMdbookSummary(sb *strings.Builder, pages []*Page, depth int) {
+	for _, p := range pages {
+		target := ""
+		if p.Source != "" {
+			target = escapeHref(p.Target)
+		}
+		fmt.Fprintf(sb, "%s- [%s](%s)\n", strings.Repeat("    ", depth), escapeLinkText(p.Title), target)
+		writeMdbookSummary(sb, p.Children, depth+1)
+	}
+}
+
+var linkTextEscaper = regexp.MustCompile(`[\[\]]`)
+
+func escapeLinkText(s string) string {
+	return linkTextEscaper.ReplaceAllString(s, `\$0`)
+}
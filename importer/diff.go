@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// unifiedDiff returns the unified diff turning old into new for the file name,
+// or "" when they are equal. A missing file diffs as /dev/null.
+func unifiedDiff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := splitDiffLines(old), splitDiffLines(new)
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Edit script: ' ', '-' or '+' followed by the line
+	type edit struct {
+		op   byte
+		line string
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		default:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		}
+	}
+
+	var sb strings.Builder
+	from, to := "a/"+name, "b/"+name
+	if old == "" {
+		from = "/dev/null"
+	}
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+
+	// Group edits into hunks with context
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		first := max(start-diffContext, 0)
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k
+			} else if k-end > 2*diffContext {
+				break
+			}
+		}
+		last := min(end+diffContext+1, len(edits))
+
+		// Line numbers of the hunk in both files
+		oldStart, newStart := 1, 1
+		for _, e := range edits[:first] {
+			if e.op != '+' {
+				oldStart++
+			}
+			if e.op != '-' {
+				newStart++
+			}
+		}
+		oldLines, newLines := 0, 0
+		for _, e := range edits[first:last] {
+			if e.op != '+' {
+				oldLines++
+			}
+			if e.op != '-' {
+				newLines++
+			}
+		}
+		if oldLines == 0 {
+			oldStart--
+		}
+		if newLines == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLines, newStart, newLines)
+		for _, e := range edits[first:last] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.line)
+			sb.WriteByte('\n')
+		}
+		start = last
+	}
+	return sb.String()
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// planDir imports a bare tree of Markdown files in place: directories become
+// chapters opened by their README.md or index.md, files are ordered by name
+func (p *Plan) planDir() error {
+	p.Config.Title = book.FileTitle(filepath.Base(p.Source))
+
+	index, chapters, err := p.dirChapters(p.Source, "")
+	if err != nil {
+		return err
+	}
+	if index != nil {
+		chapters = append([]book.Chapter{*index}, chapters...)
+	} else {
+		p.note("the tree has no README.md, which GitBook uses as the home page")
+	}
+	p.Summary = &book.Summary{Chapters: chapters}
+
+	return p.addTree(p.Source, "", func(rel string) bool {
+		return rel == "SUMMARY.md" || book.IsConfigFile(rel)
+	})
+}
+
+// dirChapters returns the index page of a directory and the chapters of its
+// other pages and subdirectories; rel is the directory relative to the book root
+func (p *Plan) dirChapters(dir, rel string) (*book.Chapter, []book.Chapter, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var index *book.Chapter
+	var pages, sections []book.Chapter
+	for _, entry := range entries {
+		name := entry.Name()
+		full := filepath.Join(dir, name)
+		if strings.HasPrefix(name, ".") || name == "_book" || name == "node_modules" || full == p.Root && full != p.Source {
+			continue
+		}
+
+		if entry.IsDir() {
+			sub, articles, err := p.dirChapters(full, path.Join(rel, name))
+			if err != nil {
+				return nil, nil, err
+			}
+			switch {
+			case sub != nil:
+				sub.Articles = articles
+				sections = append(sections, *sub)
+			case len(articles) > 0:
+				sections = append(sections, book.Chapter{Title: book.FileTitle(name), Articles: articles})
+			}
+			continue
+		}
+
+		if !isMarkdown(name) || (rel == "" && name == "SUMMARY.md") {
+			continue
+		}
+		ch := book.Chapter{Title: book.PageTitle(full), Path: bookPath(path.Join(rel, name))}
+		base := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+		if (base == "readme" || base == "index") && (index == nil || base == "readme") {
+			if ch.Title == "" {
+				ch.Title = book.FileTitle(path.Base(rel))
+				if rel == "" {
+					ch.Title = "Introduction"
+				}
+			}
+			if index != nil {
+				pages = append(pages, *index) // README wins over index
+			}
+			index = &ch
+			continue
+		}
+		if ch.Title == "" {
+			ch.Title = book.FileTitle(name)
+		}
+		pages = append(pages, ch)
+	}
+	return index, append(pages, sections...), nil
+}
+
+// bookPath returns the path of a page in the book; MDX pages become Markdown
+func bookPath(rel string) string {
+	if strings.EqualFold(path.Ext(rel), ".mdx") {
+		return strings.TrimSuffix(rel, path.Ext(rel)) + ".md"
+	}
+	return rel
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+func TestImportDirInPlace(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "field-notes")
+	writeFiles(t, root, map[string]string{
+		"README.md":         "# Notes\n",
+		"b.md":              "# Beta\n",
+		"a.md":              "No heading here.\n",
+		"setup/index.md":    "# Setup\n",
+		"setup/README.md":   "# Getting set up\n",
+		"setup/install.mdx": "# Install\n",
+		"misc/deep/x.md":    "# X\n",
+		"images/logo.png":   "PNG",
+		".git/HEAD":         "ref: refs/heads/main\n",
+	})
+
+	if got := Detect(root); got != "dir" {
+		t.Fatalf("Detect = %q, want dir", got)
+	}
+	plan, err := NewPlan(root, root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages come before subdirectories; README.md wins over index.md
+	want := "# Summary\n\n" +
+		"* [Notes](README.md)\n" +
+		"* [A](a.md)\n" +
+		"* [Beta](b.md)\n" +
+		"* [Misc]()\n" +
+		"  * [Deep]()\n" +
+		"    * [X](misc/deep/x.md)\n" +
+		"* [Getting set up](setup/README.md)\n" +
+		"  * [Setup](setup/index.md)\n" +
+		"  * [Install](setup/install.md)\n"
+	if got := plan.Summary.Render(); got != want {
+		t.Errorf("SUMMARY.md =\n%s\nwant\n%s", got, want)
+	}
+	if plan.Config.Title != "Field notes" {
+		t.Errorf("title = %q, want Field notes", plan.Config.Title)
+	}
+
+	if err := plan.Apply("move", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"SUMMARY.md", "book.json", "setup/install.md", "images/logo.png"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	b, err := book.LoadBook(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Summary.Render(); got != want {
+		t.Errorf("loaded SUMMARY.md =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestImportDirWithoutReadme(t *testing.T) {
+	source := t.TempDir()
+	writeFiles(t, source, map[string]string{"one.md": "# One\n"})
+	plan, err := NewPlan(source, t.TempDir(), "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(plan.Notes, "the tree has no README.md, which GitBook uses as the home page") {
+		t.Errorf("notes = %s", strings.Join(plan.Notes, "\n"))
+	}
+}
@@ -0,0 +1,439 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// docusaurusSidebars are the sidebar files looked for at the site root
+var docusaurusSidebars = []string{"sidebars.js", "sidebars.ts", "sidebars.mjs", "sidebars.cjs", "sidebars.json"}
+
+// numberPrefix matches the ordering prefixes Docusaurus strips from doc ids
+var numberPrefix = regexp.MustCompile(`^\d+\s*[-_.]+\s*`)
+
+// planDocusaurus imports a Docusaurus site: the first sidebar gives the
+// structure, docs/ and static/ move to the book root
+func (p *Plan) planDocusaurus() error {
+	docsDir := filepath.Join(p.Source, "docs")
+	ids, err := docusaurusIDs(docsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read docs: %w", err)
+	}
+
+	var sidebarFile string
+	for _, name := range docusaurusSidebars {
+		if _, err := os.Stat(filepath.Join(p.Source, name)); err == nil {
+			sidebarFile = name
+			break
+		}
+	}
+	if sidebarFile == "" {
+		return fmt.Errorf("no sidebars file found in %s (looked for %s)", p.Source, strings.Join(docusaurusSidebars, ", "))
+	}
+	data, err := os.ReadFile(filepath.Join(p.Source, sidebarFile))
+	if err != nil {
+		return err
+	}
+	sidebars, err := parseJSObject(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sidebarFile, err)
+	}
+	obj, ok := sidebars.(*jsObject)
+	if !ok || len(obj.keys) == 0 {
+		return fmt.Errorf("%s: no sidebar found", sidebarFile)
+	}
+	for _, name := range obj.keys[1:] {
+		p.note("%s: only the first sidebar is imported; %q is skipped", sidebarFile, name)
+	}
+
+	used := make(map[string]bool)
+	p.Summary = &book.Summary{Chapters: p.docusaurusItems(obj.values[obj.keys[0]], docsDir, ids, used)}
+	var unused []string
+	for id, rel := range ids {
+		if !used[id] {
+			unused = append(unused, bookPath(rel))
+		}
+	}
+	sort.Strings(unused)
+	for _, rel := range unused {
+		p.note("%s is not in the sidebar; copied without a SUMMARY.md entry", rel)
+	}
+	if _, err := os.Stat(filepath.Join(docsDir, "README.md")); err != nil {
+		p.note("the docs have no README.md, which GitBook uses as the home page")
+	}
+
+	p.Config.Title, p.Config.Description = docusaurusSiteInfo(p.Source)
+	if err := p.addTree(docsDir, "", nil); err != nil {
+		return err
+	}
+	// Static files are served from the site root, as absolute paths resolve in books
+	if _, err := os.Stat(filepath.Join(p.Source, "static")); err == nil {
+		if err := p.addTree(filepath.Join(p.Source, "static"), "", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docusaurusIDs maps doc ids to paths relative to docs/. Ids are paths without
+// extension and number prefixes; an id in front matter replaces the file name.
+func docusaurusIDs(docsDir string) (map[string]string, error) {
+	ids := make(map[string]string)
+	err := filepath.Walk(docsDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if file != docsDir && (strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdown(info.Name()) || strings.HasPrefix(info.Name(), "_") {
+			return nil
+		}
+		rel, err := filepath.Rel(docsDir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		var parts []string
+		for _, dir := range strings.Split(path.Dir(rel), "/") {
+			if dir != "." {
+				parts = append(parts, numberPrefix.ReplaceAllString(dir, ""))
+			}
+		}
+		base := numberPrefix.ReplaceAllString(strings.TrimSuffix(path.Base(rel), path.Ext(rel)), "")
+		if content, err := os.ReadFile(file); err == nil {
+			if fm, _, err := book.ParseFrontMatter(content); err == nil && fm.String("id") != "" {
+				base = fm.String("id")
+			}
+		}
+		ids[strings.Join(append(parts, base), "/")] = rel
+		return nil
+	})
+	return ids, err
+}
+
+// docusaurusItems converts sidebar items: docs become pages, categories
+// chapters opened by their linked doc, autogenerated items the files of their
+// directory
+func (p *Plan) docusaurusItems(value interface{}, docsDir string, ids map[string]string, used map[string]bool) []book.Chapter {
+	var chapters []book.Chapter
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case *jsObject:
+		// Shorthand: {"Category label": [items]}
+		for _, label := range v.keys {
+			chapters = append(chapters, book.Chapter{Title: label, Articles: p.docusaurusItems(v.values[label], docsDir, ids, used)})
+		}
+		return chapters
+	}
+
+	for _, item := range items {
+		if id, ok := item.(string); ok {
+			if ch, ok := p.docusaurusDoc(id, "", docsDir, ids, used); ok {
+				chapters = append(chapters, ch)
+			}
+			continue
+		}
+		obj, ok := item.(*jsObject)
+		if !ok {
+			continue
+		}
+		label := obj.text("label")
+		switch typ := obj.text("type"); typ {
+		case "doc", "ref":
+			if ch, ok := p.docusaurusDoc(obj.text("id"), label, docsDir, ids, used); ok {
+				chapters = append(chapters, ch)
+			}
+		case "category":
+			ch := book.Chapter{Title: label}
+			if link, ok := obj.values["link"].(*jsObject); ok && link.text("type") == "doc" {
+				if doc, ok := p.docusaurusDoc(link.text("id"), label, docsDir, ids, used); ok {
+					ch.Path = doc.Path
+				}
+			}
+			ch.Articles = p.docusaurusItems(obj.values["items"], docsDir, ids, used)
+			chapters = append(chapters, ch)
+		case "autogenerated":
+			dirName := obj.text("dirName")
+			index, articles, err := p.dirChapters(filepath.Join(docsDir, filepath.FromSlash(dirName)), path.Clean(dirName))
+			if err != nil {
+				p.note("autogenerated sidebar of %s: %v", dirName, err)
+				continue
+			}
+			if index != nil {
+				articles = append([]book.Chapter{*index}, articles...)
+			}
+			markUsed(articles, ids, used)
+			chapters = append(chapters, articles...)
+		case "link":
+			p.note("external sidebar link %q (%s) has no SUMMARY.md equivalent; skipped", label, obj.text("href"))
+		default:
+			if items, ok := obj.values["items"]; ok && typ == "" {
+				chapters = append(chapters, book.Chapter{Title: label, Articles: p.docusaurusItems(items, docsDir, ids, used)})
+			} else {
+				p.note("sidebar item of type %q is not supported; skipped", typ)
+			}
+		}
+	}
+	return chapters
+}
+
+// docusaurusDoc returns the chapter of a doc id, titled by the sidebar label,
+// the sidebar_label or title front matter or the first heading
+func (p *Plan) docusaurusDoc(id, label, docsDir string, ids map[string]string, used map[string]bool) (book.Chapter, bool) {
+	rel, ok := ids[id]
+	if !ok {
+		p.note("sidebar doc %q does not exist; skipped", id)
+		return book.Chapter{}, false
+	}
+	used[id] = true
+
+	file := filepath.Join(docsDir, filepath.FromSlash(rel))
+	title := label
+	if title == "" {
+		if content, err := os.ReadFile(file); err == nil {
+			if fm, _, err := book.ParseFrontMatter(content); err == nil {
+				title = fm.String("sidebar_label")
+			}
+		}
+	}
+	if title == "" {
+		title = book.PageTitle(file)
+	}
+	if title == "" {
+		title = book.FileTitle(path.Base(rel))
+	}
+	return book.Chapter{Title: title, Path: bookPath(rel)}, true
+}
+
+// markUsed marks the docs of chapters found by walking a directory as used
+func markUsed(chapters []book.Chapter, ids map[string]string, used map[string]bool) {
+	for _, ch := range chapters {
+		for id, rel := range ids {
+			if bookPath(rel) == ch.Path {
+				used[id] = true
+			}
+		}
+		markUsed(ch.Articles, ids, used)
+	}
+}
+
+var (
+	docusaurusTitle   = regexp.MustCompile(`(?m)^\s*title:\s*['"]([^'"]*)['"]`)
+	docusaurusTagline = regexp.MustCompile(`(?m)^\s*tagline:\s*['"]([^'"]*)['"]`)
+)
+
+// docusaurusSiteInfo reads the title and tagline of the site configuration
+func docusaurusSiteInfo(root string) (title, tagline string) {
+	for _, name := range []string{"docusaurus.config.js", "docusaurus.config.ts", "docusaurus.config.mjs"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		if m := docusaurusTitle.FindSubmatch(data); m != nil {
+			title = string(m[1])
+		}
+		if m := docusaurusTagline.FindSubmatch(data); m != nil {
+			tagline = string(m[1])
+		}
+		return title, tagline
+	}
+	return book.FileTitle(filepath.Base(root)), ""
+}
+
+// jsObject is a JavaScript object literal with its key order
+type jsObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *jsObject) text(key string) string {
+	s, _ := o.values[key].(string)
+	return s
+}
+
+var jsAssignment = regexp.MustCompile(`(?:=|export\s+default)\s*\{`)
+
+// parseJSObject reads the object literal assigned or exported by a sidebar
+// file. Only literals are supported: strings, numbers, booleans, arrays and
+// objects, with comments, unquoted keys and trailing commas.
+func parseJSObject(src []byte) (interface{}, error) {
+	src = stripJSComments(src)
+	start := 0
+	if loc := jsAssignment.FindIndex(src); loc != nil {
+		start = loc[1] - 1
+	} else if i := bytes.IndexAny(src, "{["); i >= 0 {
+		start = i
+	}
+
+	var out bytes.Buffer
+	depth := 0
+	for i := start; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			var sb strings.Builder
+			for ; end < len(src) && src[end] != c; end++ {
+				if src[end] == '\\' && end+1 < len(src) {
+					end++
+					switch src[end] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[end])
+					}
+					continue
+				}
+				sb.WriteByte(src[end])
+			}
+			quoted, _ := json.Marshal(sb.String())
+			out.Write(quoted)
+			i = end
+		case c == '{' || c == '[':
+			depth++
+			out.WriteByte(c)
+		case c == '}' || c == ']':
+			trimmed := bytes.TrimRight(out.Bytes(), " \t\r\n")
+			out.Truncate(len(bytes.TrimSuffix(trimmed, []byte(","))))
+			out.WriteByte(c)
+			depth--
+		case c == ':' || c == ',' || c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			out.WriteByte(c)
+		case c == '-' || c >= '0' && c <= '9':
+			end := i
+			for end < len(src) && (src[end] == '-' || src[end] == '.' || src[end] >= '0' && src[end] <= '9') {
+				end++
+			}
+			out.Write(src[i:end])
+			i = end - 1
+		case isJSIdent(c):
+			end := i
+			for end < len(src) && (isJSIdent(src[end]) || src[end] >= '0' && src[end] <= '9') {
+				end++
+			}
+			word := string(src[i:end])
+			next := bytes.TrimLeft(src[end:], " \t\r\n")
+			switch {
+			case len(next) > 0 && next[0] == ':':
+				quoted, _ := json.Marshal(word)
+				out.Write(quoted)
+			case word == "true" || word == "false" || word == "null":
+				out.WriteString(word)
+			default:
+				return nil, fmt.Errorf("unsupported expression %q; only literal sidebars can be imported", word)
+			}
+			i = end - 1
+		default:
+			return nil, fmt.Errorf("unsupported syntax %q; only literal sidebars can be imported", c)
+		}
+		if depth == 0 {
+			break
+		}
+	}
+
+	d := json.NewDecoder(&out)
+	return decodeOrdered(d)
+}
+
+func isJSIdent(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// stripJSComments removes // and /* */ comments outside strings
+func stripJSComments(src []byte) []byte {
+	var out bytes.Buffer
+	quote := byte(0)
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out.WriteByte(src[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				return out.Bytes()
+			}
+			i += end + 3
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// decodeOrdered decodes JSON keeping the key order of objects
+func decodeOrdered(d *json.Decoder) (interface{}, error) {
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &jsObject{values: make(map[string]interface{})}
+		for d.More() {
+			keyTok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			value, err := decodeOrdered(d)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.values[key]; !ok {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := d.Token()
+		return obj, err
+	default:
+		var list []interface{}
+		for d.More() {
+			value, err := decodeOrdered(d)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := d.Token()
+		return list, err
+	}
+}
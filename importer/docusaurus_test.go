@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// testSidebars uses the JavaScript that JSON lacks: comments, quotes other
+// than double quotes, unquoted keys and trailing commas
+const testSidebars = `// @ts-check
+/** @type {import('@docusaurus/plugin-content-docs').SidebarsConfig} */
+const sidebars = {
+  // The book follows the first sidebar
+  docs: [
+    'intro', /* the home page */
+    {
+      type: 'category',
+      label: "Guide // not a comment",
+      link: {type: 'doc', id: 'guide/index'},
+      items: ['guide/setup', ` + "`guide/usage`" + `,],
+    },
+    {type: 'category', label: 'API', items: [{type: 'autogenerated', dirName: 'api'}]},
+    {type: "link", label: 'Site', href: 'https://example.com/',},
+  ],
+  more: {'Extra': ['extra']},
+};
+
+module.exports = sidebars;
+`
+
+// jsString renders a parsed object literal as compact JSON in key order
+func jsString(v interface{}) string {
+	switch v := v.(type) {
+	case *jsObject:
+		var members []string
+		for _, key := range v.keys {
+			members = append(members, fmt.Sprintf("%q:%s", key, jsString(v.values[key])))
+		}
+		return "{" + strings.Join(members, ",") + "}"
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, jsString(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func TestParseJSObject(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{
+			name: "sidebars.js",
+			src:  testSidebars,
+			want: `{"docs":["intro",{"type":"category","label":"Guide // not a comment","link":{"type":"doc","id":"guide/index"},"items":["guide/setup","guide/usage"]},{"type":"category","label":"API","items":[{"type":"autogenerated","dirName":"api"}]},{"type":"link","label":"Site","href":"https://example.com/"}],"more":{"Extra":["extra"]}}`,
+		},
+		{
+			name: "export default",
+			src:  "export default {\n  docs: [{type: 'doc', id: 'a', customProps: {weight: -1.5, draft: false, note: null}}],\n} satisfies SidebarsConfig;\n",
+			want: `{"docs":[{"type":"doc","id":"a","customProps":{"weight":-1.5,"draft":false,"note":<nil>}}]}`,
+		},
+		{
+			name: "escapes",
+			src:  `module.exports = {docs: ['it\'s', "tab\there", 'a /* b */ c']};`,
+			want: `{"docs":["it's","tab\there","a /* b */ c"]}`,
+		},
+		{
+			name: "json",
+			src:  `{"docs": ["a", "b"]}`,
+			want: `{"docs":["a","b"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := parseJSObject([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := jsString(v); got != tt.want {
+				t.Errorf("parseJSObject =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	// Sidebars computed by code cannot be imported
+	for _, src := range []string{
+		"module.exports = {docs: require('./docs.json')};",
+		"module.exports = {docs: [...shared]};",
+	} {
+		if _, err := parseJSObject([]byte(src)); err == nil || !strings.Contains(err.Error(), "only literal sidebars can be imported") {
+			t.Errorf("parseJSObject(%s) error = %v", src, err)
+		}
+	}
+}
+
+func TestImportDocusaurus(t *testing.T) {
+	source, root := t.TempDir(), t.TempDir()
+	writeFiles(t, source, map[string]string{
+		"sidebars.js":            testSidebars,
+		"docusaurus.config.js":   "module.exports = {\n  title: 'My Site',\n  tagline: 'Docs for all',\n};\n",
+		"docs/intro.md":          "# Intro\n",
+		"docs/guide/index.md":    "# Guide\n",
+		"docs/guide/01-setup.md": "---\nsidebar_label: Set up\n---\n# Setup\n",
+		"docs/guide/usage.mdx":   "# Usage\n",
+		"docs/api/users.md":      "# Users\n",
+		"docs/extra.md":          "# Extra\n",
+		"static/img/logo.png":    "PNG",
+	})
+
+	if got := Detect(source); got != "docusaurus" {
+		t.Fatalf("Detect = %q, want docusaurus", got)
+	}
+	plan, err := NewPlan(source, root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Number prefixes are dropped from ids, categories open on their linked doc
+	want := "# Summary\n\n" +
+		"* [Intro](intro.md)\n" +
+		"* [Guide // not a comment](guide/index.md)\n" +
+		"  * [Set up](guide/01-setup.md)\n" +
+		"  * [Usage](guide/usage.md)\n" +
+		"* [API]()\n" +
+		"  * [Users](api/users.md)\n"
+	if got := plan.Summary.Render(); got != want {
+		t.Errorf("SUMMARY.md =\n%s\nwant\n%s", got, want)
+	}
+	if plan.Config.Title != "My Site" || plan.Config.Description != "Docs for all" {
+		t.Errorf("config = %q, %q; want My Site, Docs for all", plan.Config.Title, plan.Config.Description)
+	}
+	for _, note := range []string{
+		`sidebars.js: only the first sidebar is imported; "more" is skipped`,
+		`external sidebar link "Site" (https://example.com/) has no SUMMARY.md equivalent; skipped`,
+		"extra.md is not in the sidebar; copied without a SUMMARY.md entry",
+		"the docs have no README.md, which GitBook uses as the home page",
+	} {
+		if !contains(plan.Notes, note) {
+			t.Errorf("notes lack %q:\n%s", note, strings.Join(plan.Notes, "\n"))
+		}
+	}
+
+	var placed []string
+	for _, op := range plan.Files {
+		placed = append(placed, op.To)
+	}
+	wantPlaced := []string{"api/users.md", "extra.md", "guide/01-setup.md", "guide/index.md", "guide/usage.md", "img/logo.png", "intro.md"}
+	if !reflect.DeepEqual(placed, wantPlaced) {
+		t.Errorf("files = %q, want %q", placed, wantPlaced)
+	}
+
+	if err := plan.Apply("copy", false); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := book.LoadSummary(filepath.Join(root, "SUMMARY.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary.Render(); got != want {
+		t.Errorf("written SUMMARY.md =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestImportDocusaurusWithoutSidebars(t *testing.T) {
+	source := t.TempDir()
+	writeFiles(t, source, map[string]string{"docs/intro.md": "# Intro\n"})
+	_, err := NewPlan(source, t.TempDir(), "docusaurus")
+	if err == nil || !strings.Contains(err.Error(), "no sidebars file found") {
+		t.Errorf("error = %v, want no sidebars file found", err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
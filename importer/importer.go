@@ -0,0 +1,339 @@
+// Package importer turns mdBook projects, Docusaurus sites and bare trees of
+// Markdown files into GitBook books.
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// Formats lists the source layouts that can be imported
+var Formats = []string{"mdbook", "docusaurus", "dir"}
+
+// Modes lists how files are placed into the book
+var Modes = []string{"move", "copy", "link"}
+
+// Plan describes how a source tree becomes a book; nothing is changed on disk
+// until Apply
+type Plan struct {
+	Source  string // absolute source directory
+	Root    string // absolute book root
+	Format  string
+	Config  *book.Config
+	Summary *book.Summary
+	Files   []FileOp
+	// Consumed lists source files converted into book.json or SUMMARY.md;
+	// moving removes them
+	Consumed []string
+	Notes    []string // constructs that need manual attention
+}
+
+// FileOp places a file of the source tree into the book
+type FileOp struct {
+	From string // absolute source path
+	To   string // slash-separated path relative to the book root
+}
+
+// Detect guesses the layout of a source directory
+func Detect(source string) string {
+	if book.IsMdbookConfig(filepath.Join(source, "book.toml")) {
+		return "mdbook"
+	}
+	for _, name := range docusaurusSidebars {
+		if _, err := os.Stat(filepath.Join(source, name)); err == nil {
+			return "docusaurus"
+		}
+	}
+	return "dir"
+}
+
+// NewPlan reads the source tree and plans its import into the book at root.
+// An empty format is detected from the files of the source.
+func NewPlan(source, root, format string) (*Plan, error) {
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(absSource); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("source %s is not a directory", source)
+	}
+	if format == "" {
+		format = Detect(absSource)
+	}
+
+	p := &Plan{Source: absSource, Root: absRoot, Format: format, Config: &book.Config{}}
+	switch format {
+	case "mdbook":
+		err = p.planMdbook()
+	case "docusaurus":
+		err = p.planDocusaurus()
+	case "dir":
+		err = p.planDir()
+	default:
+		return nil, fmt.Errorf("unknown import format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Settings of an existing configuration file win over imported ones
+	configPath, err := book.ConfigPath(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := book.LoadConfig(configPath); err == nil {
+		if existing.Title == "" {
+			existing.Title = p.Config.Title
+		}
+		if existing.Author == "" {
+			existing.Author = p.Config.Author
+		}
+		if existing.Description == "" {
+			existing.Description = p.Config.Description
+		}
+		if existing.Language == "" {
+			existing.Language = p.Config.Language
+		}
+		p.Config = existing
+	}
+
+	sort.SliceStable(p.Files, func(i, j int) bool { return p.Files[i].To < p.Files[j].To })
+	return p, nil
+}
+
+// Apply places the files and writes book.json and SUMMARY.md. Existing files,
+// including SUMMARY.md, are only replaced with force.
+func (p *Plan) Apply(mode string, force bool) error {
+	if !validMode(mode) {
+		return fmt.Errorf("unknown import mode %q (supported: %s)", mode, strings.Join(Modes, ", "))
+	}
+	summaryPath := filepath.Join(p.Root, "SUMMARY.md")
+	if _, err := os.Stat(summaryPath); err == nil && !force {
+		return fmt.Errorf("SUMMARY.md already exists in %s (use --force to replace it)", p.Root)
+	}
+	if !force {
+		var conflicts []string
+		for _, op := range p.Files {
+			if p.conflicts(op) {
+				conflicts = append(conflicts, op.To)
+			}
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("files already exist in the book (use --force to replace them): %s", strings.Join(conflicts, ", "))
+		}
+	}
+
+	if err := os.MkdirAll(p.Root, 0755); err != nil {
+		return err
+	}
+	for _, op := range p.Files {
+		if err := p.place(op, mode); err != nil {
+			return fmt.Errorf("failed to %s %s: %w", mode, op.To, err)
+		}
+		if mode == "move" {
+			p.removeEmptyDirs(filepath.Dir(op.From))
+		}
+	}
+
+	configPath, err := book.ConfigPath(p.Root)
+	if err != nil {
+		return err
+	}
+	if err := book.SaveConfig(configPath, p.Config); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(configPath), err)
+	}
+	if err := book.SaveSummary(summaryPath, p.Summary); err != nil {
+		return fmt.Errorf("failed to write SUMMARY.md: %w", err)
+	}
+
+	if mode == "move" {
+		for _, file := range p.Consumed {
+			if file == summaryPath || file == configPath {
+				continue
+			}
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			p.removeEmptyDirs(filepath.Dir(file))
+		}
+	}
+	return nil
+}
+
+// Diff describes what Apply would do: the file operations followed by unified
+// diffs of book.json and SUMMARY.md against the files in the book
+func (p *Plan) Diff(mode string) (string, error) {
+	if !validMode(mode) {
+		return "", fmt.Errorf("unknown import mode %q (supported: %s)", mode, strings.Join(Modes, ", "))
+	}
+	var sb strings.Builder
+	for _, op := range p.Files {
+		if p.inPlace(op) {
+			continue
+		}
+		from, err := filepath.Rel(p.Source, op.From)
+		if err != nil {
+			from = op.From
+		}
+		status := ""
+		if p.conflicts(op) {
+			status = " (exists)"
+		}
+		fmt.Fprintf(&sb, "%s %s -> %s%s\n", mode, filepath.ToSlash(from), op.To, status)
+	}
+	if mode == "move" {
+		for _, file := range p.Consumed {
+			if rel, err := filepath.Rel(p.Source, file); err == nil && file != filepath.Join(p.Root, "SUMMARY.md") {
+				fmt.Fprintf(&sb, "remove %s\n", filepath.ToSlash(rel))
+			}
+		}
+	}
+
+	configPath, err := book.ConfigPath(p.Root)
+	if err != nil {
+		return "", err
+	}
+	config, err := book.EncodeConfig(p.Config, configPath)
+	if err != nil {
+		return "", err
+	}
+	var summary bytes.Buffer
+	if err := book.WriteSummary(&summary, p.Summary); err != nil {
+		return "", err
+	}
+	for _, f := range []struct {
+		name    string
+		content []byte
+	}{
+		{filepath.Base(configPath), config},
+		{"SUMMARY.md", summary.Bytes()},
+	} {
+		old, _ := os.ReadFile(filepath.Join(p.Root, f.name))
+		sb.WriteString(unifiedDiff(f.name, string(old), string(f.content)))
+	}
+	return sb.String(), nil
+}
+
+func validMode(mode string) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// inPlace reports whether a file is already where the book expects it
+func (p *Plan) inPlace(op FileOp) bool {
+	return op.From == filepath.Join(p.Root, filepath.FromSlash(op.To))
+}
+
+// conflicts reports whether placing a file would replace another one
+func (p *Plan) conflicts(op FileOp) bool {
+	if p.inPlace(op) {
+		return false
+	}
+	_, err := os.Lstat(filepath.Join(p.Root, filepath.FromSlash(op.To)))
+	return err == nil
+}
+
+func (p *Plan) place(op FileOp, mode string) error {
+	if p.inPlace(op) {
+		return nil
+	}
+	dst := filepath.Join(p.Root, filepath.FromSlash(op.To))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+
+	switch mode {
+	case "move":
+		return os.Rename(op.From, dst)
+	case "link":
+		return os.Symlink(op.From, dst)
+	default:
+		data, err := os.ReadFile(op.From)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(op.From)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, info.Mode())
+	}
+}
+
+// removeEmptyDirs removes dir and its parents below the source once moving
+// files has emptied them
+func (p *Plan) removeEmptyDirs(dir string) {
+	for dir != p.Source && strings.HasPrefix(dir, p.Source+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// addTree plans every file under dir, placed under prefix in the book, except
+// those for which skip returns true
+func (p *Plan) addTree(dir, prefix string, skip func(rel string) bool) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			base := info.Name()
+			if file != dir && (strings.HasPrefix(base, ".") || base == "_book" || base == "node_modules") {
+				return filepath.SkipDir
+			}
+			if file == p.Root && file != p.Source {
+				return filepath.SkipDir // importing into a directory inside the source
+			}
+			return nil
+		}
+		if skip != nil && skip(rel) {
+			return nil
+		}
+		to := bookPath(path.Join(prefix, rel))
+		if to != path.Join(prefix, rel) {
+			p.note("%s: MDX is imported as Markdown; JSX and imports need manual conversion", to)
+		}
+		p.Files = append(p.Files, FileOp{From: file, To: to})
+		return nil
+	})
+}
+
+// note records a construct that needs manual attention
+func (p *Plan) note(format string, args ...interface{}) {
+	p.Notes = append(p.Notes, fmt.Sprintf(format, args...))
+}
+
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	}
+	return false
+}
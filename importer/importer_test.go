@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+	"github.com/hitzhangjie/gitbook/builder"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestImportMdbookInPlace(t *testing.T) {
+	root := t.TempDir()
+	mdbookConfig := "[book]\ntitle = \"Guide\"\nauthors = [\"Ann\", \"Bob\"] # editors\n\n[output.html]\nsite-url = \"/guide/\"\n"
+	writeFiles(t, root, map[string]string{
+		"book.toml":      mdbookConfig,
+		"src/SUMMARY.md": "# Summary\n\n[Intro](README.md)\n\n- [Chapter](ch/one.md)\n",
+		"src/README.md":  "# Intro\n",
+		"src/ch/one.md":  "# Chapter\n",
+	})
+
+	if got := Detect(root); got != "mdbook" {
+		t.Fatalf("Detect = %q, want mdbook", got)
+	}
+	plan, err := NewPlan(root, root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Apply("move", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// mdBook's own configuration is left alone and book.json is written
+	data, err := os.ReadFile(filepath.Join(root, "book.toml"))
+	if err != nil || string(data) != mdbookConfig {
+		t.Errorf("book.toml = %q, %v; want it unchanged", data, err)
+	}
+	config, err := book.LoadConfig(filepath.Join(root, "book.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "Guide" || config.Author != "Ann, Bob" {
+		t.Errorf("config = %q by %q, want Guide by Ann, Bob", config.Title, config.Author)
+	}
+	if path, err := book.FindConfig(root); err != nil || path != filepath.Join(root, "book.json") {
+		t.Errorf("FindConfig = %q, %v; want book.json", path, err)
+	}
+
+	// Moving consumes the whole src directory, including its SUMMARY.md
+	for _, name := range []string{"README.md", "ch/one.md", "SUMMARY.md"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "src")); !os.IsNotExist(err) {
+		t.Errorf("src still exists after moving: %v", err)
+	}
+}
+
+func TestImportMdbookPartsAndBuild(t *testing.T) {
+	source, root := t.TempDir(), t.TempDir()
+	writeFiles(t, source, map[string]string{
+		"book.toml":      "[book]\ntitle = \"Guide\"\n",
+		"src/SUMMARY.md": "# Summary\n\n[Intro](README.md)\n\n# Basics\n\n- [One](one.md)\n    - [Two](two.md)\n\n# Empty\n",
+		"src/README.md":  "# Intro\n",
+		"src/one.md":     "# One\n",
+		"src/two.md":     "# Two\n",
+	})
+
+	plan, err := NewPlan(source, root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapters := plan.Summary.Chapters
+	if len(chapters) != 2 || chapters[1].Part != "Basics" || chapters[1].Path != "one.md" || len(chapters[1].Articles) != 1 {
+		t.Fatalf("chapters = %+v, want the Basics part opened by one.md", chapters)
+	}
+	if err := plan.Apply("copy", false); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := builder.NewBuilder(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Build(); err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []string{"index.html", "README.html", "one.html", "two.html"} {
+		if _, err := os.Stat(filepath.Join(b.OutputDir, page)); err != nil {
+			t.Errorf("%s was not built: %v", page, err)
+		}
+	}
+}
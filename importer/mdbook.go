@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// planMdbook imports an mdBook project: book.toml gives the settings and
+// src/SUMMARY.md the structure; the files of src/ move to the book root
+func (p *Plan) planMdbook() error {
+	values, err := book.ReadConfigValues(filepath.Join(p.Source, "book.toml"))
+	if err != nil {
+		return fmt.Errorf("failed to read %w", err)
+	}
+	settings, _ := values["book"].(map[string]interface{})
+	p.Config.Title, _ = settings["title"].(string)
+	p.Config.Description, _ = settings["description"].(string)
+	p.Config.Language, _ = settings["language"].(string)
+	authors, _ := settings["authors"].([]interface{})
+	var names []string
+	for _, a := range authors {
+		if name, ok := a.(string); ok {
+			names = append(names, name)
+		}
+	}
+	p.Config.Author = strings.Join(names, ", ")
+
+	src, _ := settings["src"].(string)
+	if src == "" {
+		src = "src"
+	}
+	srcDir := filepath.Join(p.Source, filepath.FromSlash(src))
+	summaryPath := filepath.Join(srcDir, "SUMMARY.md")
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the mdBook summary: %w", err)
+	}
+	p.Summary = parseMdbookSummary(string(data))
+	p.Consumed = append(p.Consumed, summaryPath)
+
+	if err := p.addTree(srcDir, "", func(rel string) bool { return rel == "SUMMARY.md" }); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "README.md")); err != nil {
+		p.note("the book has no README.md, which GitBook uses as the home page")
+	}
+	return nil
+}
+
+var (
+	mdbookLink = regexp.MustCompile(`^\[(.*)\]\((.*)\)$`)
+	mdbookItem = regexp.MustCompile(`^(\s*)[-*]\s+(.*)$`)
+)
+
+// parseMdbookSummary converts an mdBook summary. Part titles open a part at
+// the first chapter that follows them, draft chapters become title-only
+// entries and separators are dropped.
+func parseMdbookSummary(content string) *book.Summary {
+	summary := &book.Summary{}
+	part := ""        // part title waiting for its first chapter
+	var indents []int // indentation of each open nesting level
+	var stack []*[]book.Chapter
+	seenTitle := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "<!--"):
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			title := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			if !seenTitle && len(summary.Chapters) == 0 {
+				seenTitle = true // the summary's own title
+				continue
+			}
+			part = title // replaces a part without chapters, as in SUMMARY.md
+			indents, stack = nil, nil
+			continue
+		case strings.Trim(trimmed, "-") == "":
+			continue // separator
+		}
+
+		m := mdbookItem.FindStringSubmatch(line)
+		if m == nil {
+			// Prefix and suffix chapters are plain links outside the list
+			if ch, ok := mdbookChapter(trimmed); ok {
+				ch.Part, part = part, ""
+				summary.Chapters = append(summary.Chapters, ch)
+				indents, stack = nil, nil
+			}
+			continue
+		}
+		ch, ok := mdbookChapter(m[2])
+		if !ok {
+			continue
+		}
+
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		for len(indents) > 0 && indent < indents[len(indents)-1] {
+			indents, stack = indents[:len(indents)-1], stack[:len(stack)-1]
+		}
+		switch {
+		case len(indents) == 0:
+			indents, stack = []int{indent}, []*[]book.Chapter{&summary.Chapters}
+		case indent > indents[len(indents)-1]:
+			parent := stack[len(stack)-1]
+			if len(*parent) > 0 {
+				last := &(*parent)[len(*parent)-1]
+				indents, stack = append(indents, indent), append(stack, &last.Articles)
+			}
+		}
+		list := stack[len(stack)-1]
+		if len(stack) == 1 {
+			ch.Part, part = part, ""
+		}
+		*list = append(*list, ch)
+	}
+	if part != "" {
+		summary.Trailer = append(summary.Trailer, "## "+part) // a part without chapters
+	}
+	return summary
+}
+
+// mdbookChapter parses a "[Title](path)" entry, paths being relative to src/
+func mdbookChapter(s string) (book.Chapter, bool) {
+	m := mdbookLink.FindStringSubmatch(s)
+	if m == nil {
+		return book.Chapter{}, false
+	}
+	title := strings.NewReplacer(`\[`, "[", `\]`, "]").Replace(m[1])
+	target := strings.TrimSpace(m[2])
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if target != "" {
+		target = path.Clean(strings.TrimPrefix(target, "./"))
+	}
+	return book.Chapter{Title: title, Path: target}, true
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+// maxFileSize limits the size of a markdown file saved through the editor API
+const maxFileSize = 10 << 20
+
+// FileContent is the payload of the /api/files endpoint
+type FileContent struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// ModTime is the file's modification time in Unix nanoseconds. On save it must
+	// match the file on disk, otherwise the save is rejected as a conflicting edit.
+	ModTime int64 `json:"mtime,string"`
+}
+
+// generateEditToken returns a random token authenticating editor API requests
+func generateEditToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// handleFiles reads (GET) and saves (PUT) markdown files under the book root.
+// Saved files are picked up by the watcher, which triggers the usual rebuild.
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Gitbook-Token")
+	if s.EditToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.EditToken)) != 1 {
+		writeJSONError(w, http.StatusUnauthorized, "invalid or missing edit token")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.readFile(w, r)
+	case http.MethodPut, http.MethodPost:
+		s.saveFile(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) readFile(w http.ResponseWriter, r *http.Request) {
+	relPath := r.URL.Query().Get("path")
+	fp, err := s.resolveSourcePath(relPath)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	info, err := os.Stat(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeJSONError(w, http.StatusNotFound, "file not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, FileContent{
+		Path:    relPath,
+		Content: string(data),
+		ModTime: info.ModTime().UnixNano(),
+	})
+}
+
+func (s *Server) saveFile(w http.ResponseWriter, r *http.Request) {
+	var req FileContent
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxFileSize)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	fp, err := s.resolveSourcePath(req.Path)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Reject the save if the file changed since the client loaded it
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	info, err := os.Stat(fp)
+	switch {
+	case err == nil:
+		if info.ModTime().UnixNano() != req.ModTime {
+			current, _ := os.ReadFile(fp)
+			writeJSON(w, http.StatusConflict, FileContent{
+				Path:    req.Path,
+				Content: string(current),
+				ModTime: info.ModTime().UnixNano(),
+			})
+			return
+		}
+	case os.IsNotExist(err):
+		if req.ModTime != 0 {
+			writeJSONError(w, http.StatusConflict, "file was deleted")
+			return
+		}
+	default:
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := os.WriteFile(fp, []byte(req.Content), 0644); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	info, err = os.Stat(fp)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, FileContent{
+		Path:    req.Path,
+		Content: req.Content,
+		ModTime: info.ModTime().UnixNano(),
+	})
+}
+
+// resolveSourcePath maps a slash-separated path relative to the book root to an
+// absolute path, refusing anything outside the root, hidden or not markdown
+func (s *Server) resolveSourcePath(relPath string) (string, error) {
+	if relPath == "" {
+		return "", errors.New("path is required")
+	}
+
+	ext := strings.ToLower(filepath.Ext(relPath))
+	if ext != ".md" && ext != ".markdown" {
+		return "", errors.New("only markdown files can be edited")
+	}
+
+	absRoot, err := filepath.Abs(s.Book.Root)
+	if err != nil {
+		return "", err
+	}
+	absFile, err := filepath.Abs(filepath.Join(absRoot, filepath.FromSlash(relPath)))
+	if err != nil {
+		return "", err
+	}
+
+	// Security check: ensure path is within the book root
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("path is outside the book root")
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part == "_book" || strings.HasPrefix(part, ".") {
+			return "", errors.New("path is not editable")
+		}
+	}
+
+	// Refuse to follow symlinks out of the book root, including through the
+	// directory of a file that doesn't exist yet
+	if !book.Within(absRoot, absFile) {
+		return "", errors.New("path is outside the book root")
+	}
+
+	return absFile, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hitzhangjie/gitbook/book"
+)
+
+func TestSaveFileStaysInBookRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Book: &book.Book{Root: root}, EditToken: "token"}
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		created string
+	}{
+		{"new page", "page.md", http.StatusOK, filepath.Join(root, "page.md")},
+		{"symlink to outside", "link/pwn.md", http.StatusBadRequest, filepath.Join(outside, "pwn.md")},
+		{"parent directory", "../pwn.md", http.StatusBadRequest, filepath.Join(filepath.Dir(root), "pwn.md")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(`{"path":"` + tt.path + `","content":"# Pwn\n","mtime":"0"}`)
+			req := httptest.NewRequest(http.MethodPut, "/api/files", body)
+			req.Header.Set("X-Gitbook-Token", "token")
+			rec := httptest.NewRecorder()
+			s.handleFiles(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			_, err := os.Stat(tt.created)
+			if exists := err == nil; exists != (tt.status == http.StatusOK) {
+				t.Errorf("%s exists = %v after status %d", tt.created, exists, rec.Code)
+			}
+		})
+	}
+}
+
+func TestReadFileRejectsEscapes(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.md"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Book: &book.Book{Root: root}, EditToken: "token"}
+
+	for _, path := range []string{"link/secret.md", "../secret.md"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/files?path="+path, nil)
+		req.Header.Set("X-Gitbook-Token", "token")
+		rec := httptest.NewRecorder()
+		s.handleFiles(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want %d", path, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// saveRequest sends a save of the editor API to s and returns the response
+func saveRequest(t *testing.T, s *Server, token string, content FileContent) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/files", bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("X-Gitbook-Token", token)
+	}
+	rec := httptest.NewRecorder()
+	s.handleFiles(rec, req)
+	return rec
+}
+
+func TestSaveFileConflicts(t *testing.T) {
+	root := t.TempDir()
+	page := filepath.Join(root, "page.md")
+	if err := os.WriteFile(page, []byte("# On disk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := info.ModTime().UnixNano()
+	s := &Server{Book: &book.Book{Root: root}, EditToken: "token"}
+
+	// A stale save gets the current content back
+	rec := saveRequest(t, s, "token", FileContent{Path: "page.md", Content: "# Stale\n", ModTime: mtime - 1})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("stale save: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	var current FileContent
+	if err := json.NewDecoder(rec.Body).Decode(&current); err != nil {
+		t.Fatal(err)
+	}
+	if current.Content != "# On disk\n" || current.ModTime != mtime {
+		t.Errorf("stale save returned %+v, want the file on disk", current)
+	}
+
+	// A save of a file deleted since it was loaded
+	rec = saveRequest(t, s, "token", FileContent{Path: "gone.md", Content: "# Gone\n", ModTime: mtime})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("save of a deleted file: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	if _, err := os.Stat(filepath.Join(root, "gone.md")); !os.IsNotExist(err) {
+		t.Errorf("the deleted file was written again: %v", err)
+	}
+
+	// A save of the version on disk goes through
+	rec = saveRequest(t, s, "token", FileContent{Path: "page.md", Content: "# Saved\n", ModTime: mtime})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("save: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := readTestFile(t, page); got != "# Saved\n" {
+		t.Errorf("page.md = %q after saving", got)
+	}
+	var saved FileContent
+	if err := json.NewDecoder(rec.Body).Decode(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(page); err != nil || saved.ModTime != info.ModTime().UnixNano() {
+		t.Errorf("save returned mtime %d, want the one of the saved file", saved.ModTime)
+	}
+}
+
+func TestFilesRequireToken(t *testing.T) {
+	root := t.TempDir()
+	page := filepath.Join(root, "page.md")
+	if err := os.WriteFile(page, []byte("# On disk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		serverToken string
+		token       string
+	}{
+		{"missing token", "token", ""},
+		{"wrong token", "token", "guess"},
+		{"editing disabled", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Book: &book.Book{Root: root}, EditToken: tt.serverToken}
+			rec := saveRequest(t, s, tt.token, FileContent{Path: "page.md", Content: "# Pwn\n"})
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("save: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if got := readTestFile(t, page); got != "# On disk\n" {
+				t.Errorf("page.md = %q after an unauthorized save", got)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/files?path=page.md", nil)
+			if tt.token != "" {
+				req.Header.Set("X-Gitbook-Token", tt.token)
+			}
+			rec = httptest.NewRecorder()
+			s.handleFiles(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("read: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func readTestFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
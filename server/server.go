@@ -27,6 +27,7 @@ type Server struct {
 	Port             int
 	Host             string
 	OutputDir        string
+	EditToken        string // token required by the /api/files editor endpoint, generated if empty
 	httpServer       *http.Server
 	watcher          *fsnotify.Watcher
 	builder          *builder.Builder
@@ -34,6 +35,8 @@ type Server struct {
 	clientsMutex     sync.RWMutex
 	rebuildDebouncer *time.Timer
 	rebuildMutex     sync.Mutex
+	saveMutex        sync.Mutex
+	loadOptions      []book.LoadOption
 }
 
 // UpdateMessage represents a message sent to clients
@@ -47,7 +50,7 @@ const defaultHTTPAddr = "localhost:4000"
 
 // NewServer creates a new development server. httpAddr is the listen address (e.g. "localhost:4000" or "0.0.0.0:8080");
 // if empty, default "localhost:4000" is used.
-func NewServer(bookRoot string, httpAddr string) (*Server, error) {
+func NewServer(bookRoot string, httpAddr string, opts ...book.LoadOption) (*Server, error) {
 	if httpAddr == "" {
 		httpAddr = defaultHTTPAddr
 	}
@@ -60,12 +63,12 @@ func NewServer(bookRoot string, httpAddr string) (*Server, error) {
 		return nil, fmt.Errorf("invalid port in http address %q", httpAddr)
 	}
 
-	b, err := book.LoadBook(bookRoot)
+	b, err := book.LoadBook(bookRoot, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	outputDir := filepath.Join(bookRoot, "_book")
+	outputDir := filepath.Join(b.ConfigRoot, "_book")
 
 	// Create file watcher
 	watcher, err := fsnotify.NewWatcher()
@@ -74,22 +77,24 @@ func NewServer(bookRoot string, httpAddr string) (*Server, error) {
 	}
 
 	return &Server{
-		Book:      b,
-		Port:      port,
-		Host:      host,
-		OutputDir: outputDir,
-		watcher:   watcher,
-		clients:   make(map[*websocket.Conn]bool),
+		Book:        b,
+		Port:        port,
+		Host:        host,
+		OutputDir:   outputDir,
+		watcher:     watcher,
+		clients:     make(map[*websocket.Conn]bool),
+		loadOptions: opts,
 	}, nil
 }
 
 // Start starts the development server
 func (s *Server) Start() error {
 	// Build the book first
-	b, err := builder.NewBuilder(s.Book.Root, s.OutputDir)
+	b, err := builder.NewBuilder(s.Book.ConfigRoot, s.OutputDir, s.loadOptions...)
 	if err != nil {
 		return fmt.Errorf("failed to create builder: %w", err)
 	}
+	b.LocalEdit = true
 	s.builder = b
 
 	if err := s.builder.Build(); err != nil {
@@ -107,6 +112,16 @@ func (s *Server) Start() error {
 	// WebSocket endpoint
 	mux.HandleFunc("/ws", s.handleWebSocket)
 
+	// Markdown source endpoint for the in-browser editor
+	if s.EditToken == "" {
+		token, err := generateEditToken()
+		if err != nil {
+			return fmt.Errorf("failed to generate edit token: %w", err)
+		}
+		s.EditToken = token
+	}
+	mux.HandleFunc("/api/files", s.handleFiles)
+
 	// Serve static files
 	mux.HandleFunc("/", s.handleRequest)
 
@@ -120,6 +135,7 @@ func (s *Server) Start() error {
 
 	fmt.Printf("Serving book on http://%s:%d\n", s.Host, s.Port)
 	fmt.Println("Live reload enabled - watching for file changes...")
+	fmt.Printf("In-browser editor token: %s\n", s.EditToken)
 	fmt.Println("Press Ctrl+C to stop the server")
 
 	go func() {
@@ -214,7 +230,7 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 // startWatcher starts watching for file changes
 func (s *Server) startWatcher() error {
-	// Watch the book root directory recursively
+	// Watch the content root recursively
 	err := filepath.Walk(s.Book.Root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -222,13 +238,18 @@ func (s *Server) startWatcher() error {
 
 		// Skip output directory and hidden directories
 		base := filepath.Base(path)
-		if base == "_book" || base == ".git" || strings.HasPrefix(base, ".") {
+		if path == s.OutputDir || base == "_book" || base == ".git" || strings.HasPrefix(base, ".") {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
+		// Skip directories matched by .bookignore
+		if rel, err := filepath.Rel(s.Book.Root, path); err == nil && info.IsDir() && s.Book.Ignore.Match(rel, true) {
+			return filepath.SkipDir
+		}
+
 		// Only watch directories (files will be watched through their parent directory)
 		if info.IsDir() {
 			return s.watcher.Add(path)
@@ -241,6 +262,13 @@ func (s *Server) startWatcher() error {
 		return err
 	}
 
+	// The configuration file may sit above the content root
+	if s.Book.ConfigRoot != s.Book.Root {
+		if err := s.watcher.Add(s.Book.ConfigRoot); err != nil {
+			return err
+		}
+	}
+
 	// Start watching for events in a goroutine
 	go s.watchEvents()
 
@@ -289,13 +317,35 @@ func (s *Server) shouldRebuild(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	base := filepath.Base(path)
 
+	// Changed ignore files apply from now on
+	inRoot := book.Within(s.Book.Root, path)
+	if (base == ".bookignore" || base == ".gitignore") && (inRoot || filepath.Dir(path) == s.Book.ConfigRoot) {
+		if err := s.Book.ReadIgnore(); err != nil {
+			log.Printf("Failed to read ignore files: %v", err)
+		}
+		return true
+	}
+
+	// Outside the content root, only the configuration file counts
+	if !inRoot {
+		return filepath.Dir(path) == s.Book.ConfigRoot && book.IsConfigFile(base)
+	}
+
+	// Skip paths matched by .bookignore
+	if rel, err := filepath.Rel(s.Book.Root, path); err == nil {
+		info, err := os.Stat(path)
+		if s.Book.Ignore.Match(rel, err == nil && info.IsDir()) {
+			return false
+		}
+	}
+
 	// Watch markdown files
 	if ext == ".md" || ext == ".markdown" {
 		return true
 	}
 
 	// Watch configuration files
-	if base == "book.json" || base == "SUMMARY.md" || base == "README.md" {
+	if book.IsConfigFile(base) || base == "SUMMARY.md" || base == "README.md" {
 		return true
 	}
 